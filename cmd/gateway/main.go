@@ -1,16 +1,26 @@
 package main
 
 import (
+	"KamaitachiGo/internal/cache/snapshot"
+	"KamaitachiGo/internal/gateway"
+	"KamaitachiGo/internal/model"
 	"KamaitachiGo/pkg/config"
 	"KamaitachiGo/pkg/etcd"
 	"KamaitachiGo/pkg/hash"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -20,10 +30,19 @@ import (
 )
 
 var (
-	// consistentHash 用于实现请求到后端Slave节点的一致性哈希路由
-	consistentHash *hash.ConsistentHash
+	// consistentHash 请求到后端Slave节点的路由，默认为一致性哈希环，可配置为 rendezvous/maglev
+	consistentHash hash.Router
 	etcdClient     *etcd.Client
 	httpClient     *http.Client
+	// readinessGate 新注册的节点通过就绪检查后才加入 consistentHash
+	readinessGate *gateway.ReadinessGate
+	// readAttempts 读请求最多尝试的节点数，readTimeout 为有副本可重试时单次尝试的超时
+	readAttempts = 2
+	readTimeout  = 10 * time.Second
+	// loadTracker 统计各节点在途请求数，启用有界负载时热点节点的超额读请求溢出到后继节点
+	loadTracker = gateway.NewLoadTracker(0)
+	// handoffer 成员变化时的缓存交接，未启用时为 nil
+	handoffer *gateway.Handoffer
 )
 
 func main() {
@@ -65,29 +84,71 @@ func main() {
 	}
 	defer etcdClient.Close()
 
-	// 初始化一致性哈希环。虚拟节点数量150，可调整。
+	// 初始化一致性哈希环。虚拟节点数量150，可调整（Slave预热时按同样的哈希环计算归属）。
 	// consistentHash 用于将请求Key（如股票ID）映射到后端Slave节点。
-	consistentHash = hash.NewConsistentHash(150, nil)
+	consistentHash, err = hash.NewRouter(cfg.Routing.Strategy, hash.DefaultReplicas)
+	if err != nil {
+		logrus.Fatalf("Failed to create router: %v", err)
+	}
+	logrus.Infof("Routing strategy: %s", cfg.Routing.Strategy)
+
+	// 节点准入：注册的节点通过就绪检查（数据库可用、快照已加载、预热完成）后才加入一致性哈希环
+	readinessGate = gateway.NewReadinessGate(consistentHash, httpClient, time.Second)
+	// 已加入的节点：主动探测 + 转发失败统计，异常时临时摘除，恢复后重新加入
+	readinessGate.SetOutlierConfig(gateway.OutlierConfig{
+		ProbeInterval:      time.Duration(cfg.Gateway.ProbeInterval) * time.Second,
+		ProbeFailures:      cfg.Gateway.ProbeFailures,
+		ConsecutiveErrors:  cfg.Gateway.ConsecutiveErrors,
+		BaseEjectionTime:   time.Duration(cfg.Gateway.BaseEjectionTime) * time.Second,
+		MaxEjectionTime:    time.Duration(cfg.Gateway.MaxEjectionTime) * time.Second,
+		MaxEjectionPercent: cfg.Gateway.MaxEjectionPercent,
+	})
+	// 成员变化时先交接缓存再切换路由，避免迁移的subject在新节点上冷启动
+	if wait := cfg.Gateway.HandoffWait(); wait > 0 {
+		if cfg.Server.InternalToken == "" {
+			logrus.Warn("internal_token is not configured, slaves will reject cache handoff requests")
+		}
+		handoffer = gateway.NewHandoffer(httpClient, cfg.Routing.Strategy, hash.DefaultReplicas, wait, cfg.Server.InternalToken)
+		readinessGate.SetHandoff(handoffer)
+	}
+	readinessGate.Start()
+	readAttempts = cfg.Gateway.Attempts()
+	readTimeout = cfg.Gateway.AttemptTimeout()
+	loadTracker = gateway.NewLoadTracker(cfg.Gateway.BoundedLoadFactor)
+	if loadTracker.Bounded() {
+		logrus.Infof("Bounded-load consistent hashing enabled with load factor %.2f", cfg.Gateway.BoundedLoadFactor)
+	}
+	defer readinessGate.Stop()
 
-	// 发现服务节点并添加到一致性哈希环
+	// 发现服务节点，就绪后添加到一致性哈希环
 	// 这里发现的是所有服务名为 "kamaitachi-slave" 的节点
-	nodes, err := etcdClient.Discover("kamaitachi-slave")
+	nodes, err := etcdClient.DiscoverNodes("kamaitachi-slave")
 	if err != nil {
 		logrus.Errorf("Failed to discover services: %v", err)
 	} else {
-		consistentHash.Add(nodes...)
-		logrus.Infof("Discovered %d slave nodes and added to consistent hash ring", len(nodes))
+		for _, node := range nodes {
+			readinessGate.Offer(node)
+		}
+		logrus.Infof("Discovered %d slave nodes, waiting for readiness before adding to consistent hash ring", len(nodes))
 	}
 
 	// 监听etcd中服务节点的变化
 	// 当Slave节点上线/下线时，动态更新一致性哈希环
 	etcdClient.WatchPrefix("/services/kamaitachi-slave/", func(eventType, key, value string) {
 		if eventType == "PUT" {
-			consistentHash.Add(value)
-			logrus.Infof("Node added: %s, consistent hash ring updated", value)
+			// 注册值为节点描述信息（地址、权重、可用区、版本、容量），权重变化时实时调整哈希环
+			node, err := etcd.ParseNodeInfo(value)
+			if err != nil {
+				logrus.Warnf("Ignoring invalid registration %s: %v", key, err)
+				return
+			}
+			readinessGate.Offer(node)
 		} else if eventType == "DELETE" {
-			consistentHash.Remove(value)
-			logrus.Infof("Node removed: %s, consistent hash ring updated", value)
+			// DELETE 事件没有值，节点地址取自Key
+			node := strings.TrimPrefix(key, "/services/kamaitachi-slave/")
+			// 交接与移出哈希环在后台执行，不阻塞监听
+			readinessGate.Remove(node)
+			logrus.Infof("Node deregistered: %s, removing from consistent hash ring after handoff", node)
 		}
 	})
 
@@ -123,13 +184,25 @@ func setupGatewayRouter() *gin.Engine {
 	// 统一重置接口
 	r.POST("/kamaitachi/api/data/v1/cache/reset", resetHandler)
 
+	// 协调备份：所有节点以同一编号保存一代缓存快照
+	r.POST("/kamaitachi/api/data/v1/cache/snapshots", snapshotHandler)
+
+	// 节点管理：查看健康与摘除状态，手动摘除/恢复节点
+	adminGroup := r.Group("/kamaitachi/api/gateway/v1")
+	{
+		adminGroup.GET("/nodes", nodesHandler)
+		adminGroup.POST("/nodes/:node/eject", ejectHandler)
+		adminGroup.POST("/nodes/:node/reinstate", reinstateHandler)
+	}
+
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		nodes := consistentHash.GetNodes()
 		c.JSON(200, gin.H{
-			"status": "ok",
-			"nodes":  nodes,
-			"count":  len(nodes),
+			"status":  "ok",
+			"nodes":   nodes,
+			"count":   len(nodes),
+			"pending": readinessGate.Pending(),
 		})
 	})
 
@@ -147,34 +220,7 @@ func proxyHandler(c *gin.Context) {
 
 	stdjson.Unmarshal(bodyBytes, &requestBody)
 
-	// 确定路由key：
-	// 优先级1: 请求体中 'subjects' 字段的第一个元素（例如股票ID）
-	// 优先级2: 客户端IP
-	routeKey := ""
-	if subjects, ok := requestBody["subjects"].(string); ok && subjects != "" {
-		// 使用第一个subject作为路由key，保证相同subject的请求落在同一个节点
-		parts := strings.Split(subjects, ",")
-		if len(parts) > 0 {
-			routeKey = strings.TrimSpace(parts[0])
-		}
-	}
-	if routeKey == "" {
-		// 如果subjects为空，则回退使用客户端IP作为路由key
-		// 注意：这在单机压测时可能导致所有请求路由到同一节点
-		routeKey = c.ClientIP()
-	}
-
-	// 通过一致性哈希选择目标Slave节点
-	targetNode := consistentHash.Get(routeKey)
-	if targetNode == "" {
-		logrus.Errorf("No available slave nodes found for routeKey: %s", routeKey)
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "no available nodes",
-		})
-		return
-	}
-
-	// 构建目标URL
+	// 构建目标路径
 	// 如果请求路径以 /data/ 开头，去掉该前缀再转发给后端（后端期望 /kamaitachi/...）
 	forwardPath := c.Request.URL.Path
 	if strings.HasPrefix(forwardPath, "/data/") {
@@ -186,51 +232,278 @@ func proxyHandler(c *gin.Context) {
 		forwardPath = "/"
 	}
 
-	targetURL := "http://" + targetNode + forwardPath
-	if c.Request.URL.RawQuery != "" {
-		targetURL += "?" + c.Request.URL.RawQuery
+	// 缓存管理与节点间内部接口不对外转发，由网关自身的接口协调
+	if adminPath(forwardPath) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "cache administration endpoints are not exposed through the gateway",
+		})
+		return
 	}
 
-	logrus.Infof("Routing request to: %s (key: %s)", targetURL, routeKey)
+	// 确定路由key（与节点预热、缓存交接使用同一规则，见 model.RouteKey）：
+	// 优先级1: 主题池请求的主题名（节点按主题整体缓存结果）
+	// 优先级2: 请求体中 'subjects' 字段的第一个元素（例如股票ID），保证相同subject的请求落在同一个节点
+	// 优先级3: 客户端IP
+	var subjects []string
+	if raw, ok := requestBody["subjects"].(string); ok && raw != "" {
+		subjects = model.SplitList(raw)
+	}
+	topic, _ := requestBody["topic"].(string)
+	routeKey := model.RouteKey(topic, subjects)
+
+	// 多subject的快照/区间请求：按归属节点拆分，并行分发后合并
+	if topic == "" && len(subjects) > 1 && c.Request.Method == http.MethodPost {
+		if kind := queryKind(forwardPath); kind != "" {
+			groups := gateway.GroupSubjects(subjects, consistentHash.Get)
+			if len(groups) > 1 {
+				scatterHandler(c, kind, forwardPath, bodyBytes, groups)
+				return
+			}
+		}
+	}
 
-	// 创建代理请求
-	proxyReq, err := http.NewRequest(c.Request.Method, targetURL, bytes.NewBuffer(bodyBytes))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to create proxy request",
+	if routeKey == "" {
+		// 既没有topic也没有subjects，则回退使用客户端IP作为路由key
+		// 注意：这在单机压测时可能导致所有请求路由到同一节点
+		routeKey = c.ClientIP()
+	}
+
+	// 通过一致性哈希选择目标Slave节点；读请求同时取哈希环上的后继节点作为副本，主节点失败或超时后依次重试
+	candidates := routeCandidates(routeKey, isIdempotentRead(c.Request.Method, forwardPath))
+	if len(candidates) == 0 {
+		logrus.Errorf("No available slave nodes found for routeKey: %s", routeKey)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "no available nodes",
 		})
 		return
 	}
 
-	// 复制请求头
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			proxyReq.Header.Add(key, value)
-		}
+	// 构建目标路径（含查询参数）
+	target := forwardPath
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
 	}
 
+	logrus.Infof("Routing request to: %s%s (key: %s)", candidates[0], target, routeKey)
+
 	// 发送请求
 	// 使用全局的httpClient，带有连接池优化
-	resp, err := httpClient.Do(proxyReq)
+	result, err := forward(c.Request.Context(), c.Request.Method, target, bodyBytes, c.Request.Header, candidates)
+	if result.retries > 0 {
+		c.Header(headerRetries, strconv.Itoa(result.retries))
+	}
 	if err != nil {
-		logrus.Errorf("Failed to proxy request to %s: %v", targetURL, err)
+		logrus.Errorf("Failed to proxy request to %s%s: %v", result.node, target, err)
 		c.JSON(http.StatusBadGateway, gin.H{
 			"error": "failed to proxy request: " + err.Error(),
 		})
 		return
 	}
-	defer resp.Body.Close()
 
 	// 复制响应头
-	for key, values := range resp.Header {
+	for key, values := range result.header {
 		for _, value := range values {
 			c.Header(key, value)
 		}
 	}
+	c.Header(headerNode, result.node)
+	c.Header(headerRetries, strconv.Itoa(result.retries))
 
 	// 复制响应体
-	respBody, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+	c.Data(result.status, result.header.Get("Content-Type"), result.body)
+}
+
+// 转发结果响应头：实际处理请求的节点与换副本重试的次数
+const (
+	headerNode    = "X-Kamaitachi-Node"
+	headerRetries = "X-Kamaitachi-Retries"
+)
+
+// routeCandidates 返回请求依次尝试的节点：写请求只发往主节点；读请求为主节点及哈希环后继副本，
+// 启用有界负载时从第一个负载未超限的节点开始
+func routeCandidates(key string, read bool) []string {
+	if !read {
+		return consistentHash.GetN(key, 1)
+	}
+	if !loadTracker.Bounded() {
+		return consistentHash.GetN(key, readAttempts)
+	}
+
+	successors := consistentHash.GetN(key, len(consistentHash.GetNodes()))
+	start := loadTracker.Choose(successors, consistentHash.Weight)
+	candidates := make([]string, 0, min(readAttempts, len(successors)))
+	for i := 0; i < len(successors) && len(candidates) < readAttempts; i++ {
+		candidates = append(candidates, successors[(start+i)%len(successors)])
+	}
+	return candidates
+}
+
+// forwardResult 一次转发（含重试）的结果
+type forwardResult struct {
+	node    string
+	retries int
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// forward 依次向候选节点转发请求，连接错误、超时或 5xx 时换下一个节点重试；
+// 每次尝试的 context 派生自客户端请求的 ctx，客户端断开后不再转发和重试；
+// 多个候选节点时每次尝试使用 readTimeout 超时。所有节点都失败时，最后一个节点的 5xx 响应原样返回
+func forward(parent context.Context, method, target string, body []byte, header http.Header, candidates []string) (forwardResult, error) {
+	var result forwardResult
+	var lastErr error
+	for i, node := range candidates {
+		if err := parent.Err(); err != nil {
+			return result, err
+		}
+		result = forwardResult{node: node, retries: i}
+		err := func() error {
+			ctx := parent
+			if len(candidates) > 1 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, readTimeout)
+				defer cancel()
+			}
+			req, err := http.NewRequestWithContext(ctx, method, "http://"+node+target, bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			// 复制请求头
+			for key, values := range header {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+			}
+
+			loadTracker.Begin(node)
+			defer loadTracker.End(node)
+			resp, err := httpClient.Do(req)
+			// 客户端断开导致的失败不计入节点的异常检测
+			if parent.Err() == nil {
+				reportResult(node, resp, err)
+			}
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			result.status = resp.StatusCode
+			result.header = resp.Header
+			result.body, err = io.ReadAll(resp.Body)
+			return err
+		}()
+		switch {
+		case err != nil:
+			lastErr = err
+		case result.status >= http.StatusInternalServerError && i < len(candidates)-1:
+			lastErr = fmt.Errorf("status %d", result.status)
+		default:
+			return result, nil
+		}
+		if i < len(candidates)-1 {
+			logrus.Warnf("Request %s to %s failed (%v), retrying on replica %s", target, node, lastErr, candidates[i+1])
+		}
+	}
+	return result, lastErr
+}
+
+// adminPath 是否为不允许经网关转发的路径：节点间内部接口（/kamaitachi/internal/）与缓存管理接口（/cache/）
+// 先规范化路径，避免通过 "..", 重复斜杠等绕过
+func adminPath(p string) bool {
+	p = path.Clean("/" + p)
+	return strings.HasPrefix(p, "/kamaitachi/internal/") || strings.Contains(p+"/", "/cache/")
+}
+
+// isIdempotentRead 是否为可在其他副本上重试的读请求：GET/HEAD 以及快照/区间查询
+func isIdempotentRead(method, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return queryKind(path) != ""
+	}
+	return false
+}
+
+// reportResult 将转发结果反馈给异常检测：连接错误、超时和 5xx 计为失败
+func reportResult(node string, resp *http.Response, err error) {
+	switch {
+	case err != nil:
+		readinessGate.ReportFailure(node, err)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		readinessGate.ReportFailure(node, fmt.Errorf("status %d", resp.StatusCode))
+	default:
+		readinessGate.ReportSuccess(node)
+	}
+}
+
+// queryKind 判断转发路径是否为可拆分的快照/区间查询
+func queryKind(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	switch {
+	case strings.HasSuffix(path, "/api/data/v1/snapshot"):
+		return "snapshot"
+	case strings.HasSuffix(path, "/api/data/v1/period"):
+		return "period"
+	}
+	return ""
+}
+
+// scatterHandler 将请求按subject归属节点拆分，并行转发并合并结果
+func scatterHandler(c *gin.Context, kind, forwardPath string, bodyBytes []byte, groups []gateway.Group) {
+	// 每组按组内第一个subject取副本，组的主节点失败时换副本重试
+	replicas := make(map[string][]string, len(groups))
+	for _, group := range groups {
+		replicas[group.Node] = routeCandidates(group.Subjects[0], true)
+	}
+	header := c.Request.Header.Clone()
+	header.Del("Content-Length")
+
+	var mu sync.Mutex
+	retries := 0
+	served := make([]string, 0, len(groups))
+	send := func(node string, body []byte) ([]byte, error) {
+		candidates := replicas[node]
+		if len(candidates) == 0 {
+			candidates = []string{node}
+		}
+		result, err := forward(c.Request.Context(), http.MethodPost, forwardPath, body, header, candidates)
+		mu.Lock()
+		retries += result.retries
+		if err == nil {
+			served = append(served, result.node)
+		}
+		mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if result.status != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d", result.status)
+		}
+		return result.body, nil
+	}
+
+	logrus.Infof("Scattering %s request to %d nodes", kind, len(groups))
+
+	var result interface{}
+	var err error
+	if kind == "snapshot" {
+		result, err = gateway.ScatterSnapshot(bodyBytes, groups, send)
+	} else {
+		result, err = gateway.ScatterPeriod(bodyBytes, groups, send)
+	}
+	sort.Strings(served)
+	c.Header(headerNode, strings.Join(served, ","))
+	c.Header(headerRetries, strconv.Itoa(retries))
+	if err != nil {
+		logrus.Errorf("Failed to scatter %s request: %v", kind, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "failed to proxy request: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
 // statsHandler 从所有后端节点拉取统计并做简单聚合
@@ -369,4 +642,130 @@ func resetHandler(c *gin.Context) {
 	})
 }
 
+// snapshotHandler 向所有后端节点并行发送保存快照的请求，各节点使用同一个快照代编号，
+// 之后可以在每个节点上按该编号加载，得到同一时刻的缓存状态
+func snapshotHandler(c *gin.Context) {
+	nodes := consistentHash.GetNodes()
+	if len(nodes) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"status_code": 500,
+			"status_msg":  "No active slave nodes found to snapshot.",
+		})
+		return
+	}
+
+	id := c.Query("id")
+	if id == "" {
+		id = snapshot.NewGenerationID(time.Now())
+	}
+
+	results := make([]map[string]interface{}, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node string) {
+			defer wg.Done()
+			result := map[string]interface{}{"node": node}
+			results[i] = result
+
+			url := "http://" + node + "/kamaitachi/api/data/v1/cache/snapshots?id=" + url.QueryEscape(id)
+			resp, err := httpClient.Post(url, "application/json", nil)
+			if err != nil {
+				logrus.Errorf("Failed to send snapshot request to node %s: %v", node, err)
+				result["status_code"] = 502
+				result["status_msg"] = err.Error()
+				return
+			}
+			defer resp.Body.Close()
+
+			var parsed map[string]interface{}
+			body, _ := io.ReadAll(resp.Body)
+			if err := stdjson.Unmarshal(body, &parsed); err != nil || resp.StatusCode != http.StatusOK {
+				logrus.Errorf("Unexpected snapshot response from node %s: status %d", node, resp.StatusCode)
+				result["status_code"] = 502
+				result["status_msg"] = fmt.Sprintf("unexpected response status %d", resp.StatusCode)
+				return
+			}
+			for key, value := range parsed {
+				result[key] = value
+			}
+		}(i, node)
+	}
+	wg.Wait()
+
+	saved := 0
+	for _, result := range results {
+		if code, ok := result["status_code"].(float64); ok && code == 0 {
+			saved++
+		}
+	}
+
+	statusCode := 0
+	if saved < len(nodes) {
+		statusCode = 500
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": statusCode,
+		"status_msg":  fmt.Sprintf("Saved snapshot %s on %d of %d nodes.", id, saved, len(nodes)),
+		"data": gin.H{
+			"id":    id,
+			"nodes": results,
+		},
+	})
+}
+
+// nodesHandler 返回所有节点的路由状态：在环中的节点、被摘除的节点及原因、等待就绪的节点
+func nodesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "success",
+		"data": gin.H{
+			"routing": consistentHash.GetNodes(),
+			"members": readinessGate.Nodes(),
+			"pending": readinessGate.Pending(),
+			"load":    loadTracker.Stats(),
+			"handoff": lastHandoff(),
+		},
+	})
+}
+
+// lastHandoff 最近一次缓存交接的结果
+func lastHandoff() map[string]interface{} {
+	if handoffer == nil {
+		return nil
+	}
+	return handoffer.Last()
+}
+
+// ejectHandler 手动摘除节点（如维护前），直到调用恢复接口
+func ejectHandler(c *gin.Context) {
+	node := c.Param("node")
+	if err := readinessGate.Eject(node, c.Query("reason")); err != nil {
+		nodeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status_code": 0, "status_msg": "success", "data": gin.H{"node": node}})
+}
+
+// reinstateHandler 手动恢复被摘除的节点
+func reinstateHandler(c *gin.Context) {
+	node := c.Param("node")
+	if err := readinessGate.Reinstate(node); err != nil {
+		nodeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status_code": 0, "status_msg": "success", "data": gin.H{"node": node}})
+}
 
+// nodeError 将节点管理错误转换为响应中的状态码
+func nodeError(c *gin.Context, err error) {
+	code, msg := 500, err.Error()
+	var kerr *model.KamaitachiError
+	if errors.As(err, &kerr) {
+		code, msg = kerr.Code, kerr.Message
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": code,
+		"status_msg":  msg,
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -19,18 +20,37 @@ import (
 
 // FinanceRecord 财报记录
 type FinanceRecord struct {
-	StockCode             string
-	MarketCode            string
-	SubjectKey            string
-	StockName             string
-	ReportDate            int64
-	EndDate               string
-	Year                  string
-	Period                string
-	OperatingIncome       float64
-	ParentHolderNetProfit float64
-	Category              string
-	Topic                 string
+	StockCode   string
+	MarketCode  string
+	SubjectKey  string
+	StockName   string
+	ReportDate  int64
+	EndDate     string
+	DeclareDate int64 // 公告日期（秒级时间戳），0 表示未知
+	Year        string
+	Period      string
+	Indicators  map[string]float64 // 指标ID -> 值，缺失的指标不写入（存为NULL）
+	Category    string
+	Topic       string
+}
+
+// indicatorColumn 源数据时间序列数组中的指标位置与存储列的对应关系
+type indicatorColumn struct {
+	Column string // finance_data 中的列名
+	Index  int    // 时间序列数组中的默认下标，-1 表示没有默认位置，需通过 -indicators 指定
+}
+
+// indicatorColumns 宽表指标列及其默认位置
+// 源数据数组布局：[0]公告时间 [1]截止日期 [2]年份 [3]报告期 [4..]指标值。
+// 源数据没有附带字段说明，只有 [4] 营业收入是原导入程序直接读取的位置；其余指标的下标未经确认，
+// 没有默认值，需按导出方提供的字段顺序写入 -indicators 映射文件，未映射的列存为 NULL
+var indicatorColumns = []indicatorColumn{
+	{Column: "operating_income", Index: 4},
+	{Column: "total_operating_cost", Index: -1},
+	{Column: "operating_profit", Index: -1},
+	{Column: "total_profit", Index: -1},
+	{Column: "net_profit", Index: -1},
+	{Column: "parent_holder_net_profit", Index: -1},
 }
 
 var (
@@ -38,18 +58,66 @@ var (
 	sqlDir     = flag.String("dir", "../f10sql", "SQL文件目录")
 	batchSize  = flag.Int("batch", 1000, "批量插入大小")
 	maxRecords = flag.Int("max", 0, "最大导入记录数（0=全部）")
+	layout     = flag.String("layout", "wide", "存储布局：wide=宽表列，narrow=窄表(finance_indicator)")
+	mapping    = flag.String("indicators", "", "指标映射文件（JSON，{\"指标ID\": 数组下标}），覆盖默认映射")
+	codeColumn = flag.String("code-column", "stock_code,code", "源INSERT语句中股票代码列的列名（逗号分隔的候选）")
+	dataColumn = flag.String("data-column", "data,json_data", "源INSERT语句中时间序列JSON列的列名（逗号分隔的候选）")
+)
+
+// firstIndicatorIndex 源数据数组中第一个指标值的下标
+const firstIndicatorIndex = 4
+
+// indicatorNames 数组下标 -> 指标ID，未映射的下标在窄表中以 col_<下标> 存储
+var indicatorNames = map[int]string{}
+
+// layoutError 源 INSERT 语句的列与导入配置不符（找不到股票代码或时间序列JSON列、值个数与列数不一致），
+// 继续导入会把其他列当作数据写入，需中止
+type layoutError struct {
+	file string
+	err  error
+}
+
+func (e *layoutError) Error() string {
+	return fmt.Sprintf("%s: 源数据布局不符: %v", e.file, e.err)
+}
+
+// sourceLayout 源 INSERT 语句的列及股票代码、时间序列JSON所在位置
+type sourceLayout struct {
+	columns []string
+	code    int
+	data    int
+}
+
+// defaultSourceLayout 不带列名的 INSERT 语句：VALUES ('股票代码', '{JSON}', '更新时间', 版本)
+var defaultSourceLayout = &sourceLayout{
+	columns: []string{"stock_code", "data", "update_time", "version"},
+	code:    0,
+	data:    1,
+}
+
+var (
+	// insertColumnsPattern INSERT INTO `表` (`列1`, `列2`, ...) VALUES
+	insertColumnsPattern = regexp.MustCompile(`(?i)INSERT\s+INTO\s+[^\s(]+\s*\(([^)]*)\)\s*VALUES\s*`)
+	valuesPattern        = regexp.MustCompile(`(?i)\bVALUES\s*`)
 )
 
 func main() {
 	flag.Parse()
 
+	if *layout != "narrow" && *layout != "wide" {
+		log.Fatalf("未知的存储布局: %s", *layout)
+	}
+	if err := loadIndicatorMapping(*mapping); err != nil {
+		log.Fatal("加载指标映射失败:", err)
+	}
+
 	fmt.Println("╔══════════════════════════════════════╗")
 	fmt.Println("║   财报数据导入工具 - SQLite版本      ║")
 	fmt.Println("╚══════════════════════════════════════╝")
 	fmt.Println()
 
 	// 1. 连接SQLite
-	fmt.Printf("📂 数据库: %s\n", *dbPath)
+	fmt.Printf("📂 数据库: %s (布局: %s)\n", *dbPath, *layout)
 	db, err := initDatabase(*dbPath)
 	if err != nil {
 		log.Fatal("数据库初始化失败:", err)
@@ -71,20 +139,30 @@ func main() {
 
 	// 3. 导入数据
 	totalRecords := 0
+	totalSkipped := 0
 	startTime := time.Now()
 
 	for i, sqlFile := range sqlFiles {
 		fileName := filepath.Base(sqlFile)
 		fmt.Printf("[%d/%d] 处理: %s\n", i+1, len(sqlFiles), fileName)
 
-		count, err := importSQLFile(db, sqlFile, *batchSize, *maxRecords-totalRecords)
+		count, skipped, err := importSQLFile(db, sqlFile, *batchSize, *maxRecords-totalRecords)
+		totalSkipped += skipped
+		var le *layoutError
+		if errors.As(err, &le) {
+			log.Fatalf("  ❌ %v（检查 -code-column/-data-column）", le)
+		}
 		if err != nil {
 			log.Printf("  ⚠️  警告: %v\n", err)
 			continue
 		}
 
 		totalRecords += count
-		fmt.Printf("  ✅ 导入 %d 条记录\n", count)
+		if skipped > 0 {
+			fmt.Printf("  ✅ 导入 %d 条记录，跳过 %d 条\n", count, skipped)
+		} else {
+			fmt.Printf("  ✅ 导入 %d 条记录\n", count)
+		}
 
 		if *maxRecords > 0 && totalRecords >= *maxRecords {
 			fmt.Printf("\n⚠️  已达到最大记录数限制: %d\n", *maxRecords)
@@ -100,6 +178,9 @@ func main() {
 	fmt.Println("║           导入完成统计               ║")
 	fmt.Println("╚══════════════════════════════════════╝")
 	fmt.Printf("✅ 总记录数: %d\n", totalRecords)
+	if totalSkipped > 0 {
+		fmt.Printf("⚠️  跳过记录数: %d（数组长度不足或指标位置不是数值，详见上方日志）\n", totalSkipped)
+	}
 	fmt.Printf("⏱️  总耗时: %s\n", elapsed)
 	fmt.Printf("🚀 速度: %.0f 条/秒\n", float64(totalRecords)/elapsed.Seconds())
 	fmt.Println()
@@ -131,10 +212,15 @@ func initDatabase(dbPath string) (*sql.DB, error) {
 			subject_key TEXT NOT NULL,
 			stock_name TEXT,
 			report_date INTEGER NOT NULL,
+			declare_date INTEGER,
 			end_date TEXT,
 			year TEXT,
 			period TEXT,
 			operating_income REAL,
+			total_operating_cost REAL,
+			operating_profit REAL,
+			total_profit REAL,
+			net_profit REAL,
 			parent_holder_net_profit REAL,
 			category TEXT DEFAULT 'stock',
 			topic TEXT DEFAULT 'stock_a_listing_pool'
@@ -143,11 +229,29 @@ func initDatabase(dbPath string) (*sql.DB, error) {
 		CREATE INDEX IF NOT EXISTS idx_subject_date ON finance_data(subject_key, report_date DESC);
 		CREATE INDEX IF NOT EXISTS idx_topic ON finance_data(topic);
 		CREATE INDEX IF NOT EXISTS idx_stock_code ON finance_data(stock_code);
+
+		CREATE TABLE IF NOT EXISTS finance_indicator (
+			subject_key TEXT NOT NULL,
+			report_date INTEGER NOT NULL,
+			indicator_id TEXT NOT NULL,
+			value REAL,
+			PRIMARY KEY (subject_key, report_date, indicator_id)
+		) WITHOUT ROWID;
+
+		CREATE TABLE IF NOT EXISTS indicator_catalog (
+			indicator_id TEXT PRIMARY KEY,
+			source_index INTEGER
+		);
 	`)
 	if err != nil {
 		return nil, err
 	}
 
+	// 旧版本数据库补齐公告日期与指标列
+	if err := ensureColumns(db); err != nil {
+		return nil, err
+	}
+
 	// 性能优化
 	db.Exec("PRAGMA journal_mode=WAL")
 	db.Exec("PRAGMA synchronous=NORMAL")
@@ -158,6 +262,93 @@ func initDatabase(dbPath string) (*sql.DB, error) {
 	return db, nil
 }
 
+// ensureColumns 为缺少公告日期列或指标列的旧表补齐列
+func ensureColumns(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(finance_data)")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	if !existing["declare_date"] {
+		if _, err := db.Exec("ALTER TABLE finance_data ADD COLUMN declare_date INTEGER"); err != nil {
+			return fmt.Errorf("添加列 declare_date 失败: %v", err)
+		}
+		fmt.Println("  ➕ 添加公告日期列: declare_date")
+	}
+
+	for _, ic := range indicatorColumns {
+		if existing[ic.Column] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE finance_data ADD COLUMN %s REAL", ic.Column)); err != nil {
+			return fmt.Errorf("添加列 %s 失败: %v", ic.Column, err)
+		}
+		fmt.Printf("  ➕ 添加指标列: %s\n", ic.Column)
+	}
+	return nil
+}
+
+// loadIndicatorMapping 构建数组下标到指标ID的映射：默认宽表列映射 + 映射文件
+func loadIndicatorMapping(path string) error {
+	for _, ic := range indicatorColumns {
+		if ic.Index >= 0 {
+			indicatorNames[ic.Index] = ic.Column
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var custom map[string]int
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return err
+		}
+		for id, index := range custom {
+			if index < firstIndicatorIndex {
+				return fmt.Errorf("指标 %s 的下标 %d 不是指标值位置", id, index)
+			}
+			indicatorNames[index] = id
+		}
+		fmt.Printf("📑 加载指标映射 %d 项: %s\n", len(custom), path)
+	}
+
+	mapped := make(map[string]bool, len(indicatorNames))
+	for _, id := range indicatorNames {
+		mapped[id] = true
+	}
+	var unmapped []string
+	for _, ic := range indicatorColumns {
+		if !mapped[ic.Column] {
+			unmapped = append(unmapped, ic.Column)
+		}
+	}
+	if len(unmapped) > 0 {
+		fmt.Printf("⚠️  以下指标没有映射位置，导入后为空（需在 -indicators 中指定下标）: %s\n", strings.Join(unmapped, ", "))
+	}
+	return nil
+}
+
+// indicatorName 返回数组下标对应的指标ID
+func indicatorName(index int) string {
+	if name, ok := indicatorNames[index]; ok {
+		return name
+	}
+	return fmt.Sprintf("col_%d", index)
+}
+
 // findSQLFiles 查找SQL文件
 func findSQLFiles(dir string) ([]string, error) {
 	var files []string
@@ -175,112 +366,302 @@ func findSQLFiles(dir string) ([]string, error) {
 	return files, err
 }
 
-// importSQLFile 导入SQL文件
-func importSQLFile(db *sql.DB, sqlFile string, batchSize int, maxRecords int) (int, error) {
+// importSQLFile 导入SQL文件，返回导入的记录数和因布局不符跳过的记录数
+func importSQLFile(db *sql.DB, sqlFile string, batchSize int, maxRecords int) (int, int, error) {
 	file, err := os.Open(sqlFile)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	defer file.Close()
 
 	// 准备插入语句
-	stmt, err := db.Prepare(`
+	columns := make([]string, len(indicatorColumns))
+	for i, ic := range indicatorColumns {
+		columns[i] = ic.Column
+	}
+	stmt, err := db.Prepare(fmt.Sprintf(`
 		INSERT INTO finance_data 
 		(stock_code, market_code, subject_key, stock_name, report_date, 
-		 end_date, year, period, operating_income, parent_holder_net_profit, category, topic)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`)
+		 declare_date, end_date, year, period, %s, category, topic)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, %s?, ?)
+	`, strings.Join(columns, ", "), strings.Repeat("?, ", len(columns))))
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	defer stmt.Close()
 
-	// 正则表达式匹配INSERT语句
-	// VALUES ('stock_code', '{json}', 'update_time', version)
-	insertPattern := regexp.MustCompile(`VALUES\s*\('([^']+)',\s*'(\{[^}]+\}[^']*)',\s*'[^']*',\s*\d+\)`)
+	// 窄表插入语句
+	indicatorStmt, err := db.Prepare(`
+		INSERT OR REPLACE INTO finance_indicator (subject_key, report_date, indicator_id, value)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer indicatorStmt.Close()
+	narrow := *layout == "narrow"
+	catalog := make(map[string]bool)
 
 	scanner := bufio.NewScanner(file)
 	buf := make([]byte, 10*1024*1024)  // 10MB buffer
 	scanner.Buffer(buf, 100*1024*1024) // 最大100MB
 
 	totalCount := 0
+	skipped := 0
 	batchCount := 0
 	tx, _ := db.Begin()
 
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		// 查找INSERT语句
-		matches := insertPattern.FindStringSubmatch(line)
-		if len(matches) < 3 {
-			continue
-		}
-
-		stockCode := matches[1]
-		jsonData := matches[2]
-
-		// 解析JSON时间序列数据
-		records, err := parseTimeSeriesData(stockCode, jsonData)
+		// 查找INSERT语句，按列名定位股票代码与时间序列JSON
+		layout, tuples, err := parseInsert(line)
 		if err != nil {
-			log.Printf("  ⚠️  解析失败 [%s]: %v\n", stockCode, err)
-			continue
+			tx.Rollback()
+			return totalCount, skipped, &layoutError{file: sqlFile, err: err}
 		}
 
-		// 批量插入
-		for _, record := range records {
-			_, err := tx.Stmt(stmt).Exec(
-				record.StockCode,
-				record.MarketCode,
-				record.SubjectKey,
-				record.StockName,
-				record.ReportDate,
-				record.EndDate,
-				record.Year,
-				record.Period,
-				record.OperatingIncome,
-				record.ParentHolderNetProfit,
-				record.Category,
-				record.Topic,
-			)
+		for _, tuple := range tuples {
+			stockCode := tuple[layout.code]
+
+			// 解析JSON时间序列数据，布局不符的报告期已记录日志并跳过
+			records, bad, err := parseTimeSeriesData(stockCode, tuple[layout.data])
+			skipped += bad
 			if err != nil {
-				log.Printf("  ⚠️  插入失败: %v\n", err)
+				log.Printf("  ⚠️  解析失败 [%s]: %v\n", stockCode, err)
+				skipped++
 				continue
 			}
 
-			totalCount++
-			batchCount++
+			// 批量插入
+			for _, record := range records {
+				args := []interface{}{
+					record.StockCode,
+					record.MarketCode,
+					record.SubjectKey,
+					record.StockName,
+					record.ReportDate,
+					nullableTimestamp(record.DeclareDate),
+					record.EndDate,
+					record.Year,
+					record.Period,
+				}
+				for _, ic := range indicatorColumns {
+					if v, ok := record.Indicators[ic.Column]; ok && !narrow {
+						args = append(args, v)
+					} else {
+						args = append(args, nil)
+					}
+				}
+				args = append(args, record.Category, record.Topic)
 
-			// 批量提交
-			if batchCount >= batchSize {
-				if err := tx.Commit(); err != nil {
-					return totalCount, err
+				_, err := tx.Stmt(stmt).Exec(args...)
+				if err != nil {
+					log.Printf("  ⚠️  插入失败: %v\n", err)
+					continue
 				}
-				tx, _ = db.Begin()
-				batchCount = 0
-			}
 
-			// 检查最大记录数
-			if maxRecords > 0 && totalCount >= maxRecords {
-				tx.Commit()
-				return totalCount, nil
+				if narrow {
+					for id, v := range record.Indicators {
+						if _, err := tx.Stmt(indicatorStmt).Exec(record.SubjectKey, record.ReportDate, id, v); err != nil {
+							log.Printf("  ⚠️  插入指标失败 [%s]: %v\n", id, err)
+							continue
+						}
+						catalog[id] = true
+					}
+				}
+
+				totalCount++
+				batchCount++
+
+				// 批量提交
+				if batchCount >= batchSize {
+					if err := tx.Commit(); err != nil {
+						return totalCount, skipped, err
+					}
+					tx, _ = db.Begin()
+					batchCount = 0
+				}
+
+				// 检查最大记录数
+				if maxRecords > 0 && totalCount >= maxRecords {
+					tx.Commit()
+					return totalCount, skipped, registerCatalog(db, catalog)
+				}
 			}
 		}
 	}
 
 	// 提交剩余的
 	if err := tx.Commit(); err != nil {
-		return totalCount, err
+		return totalCount, skipped, err
 	}
 
 	if err := scanner.Err(); err != nil {
-		return totalCount, err
+		return totalCount, skipped, err
+	}
+
+	return totalCount, skipped, registerCatalog(db, catalog)
+}
+
+// registerCatalog 将导入过的窄表指标登记到 indicator_catalog，服务启动时据此识别可查询的指标
+func registerCatalog(db *sql.DB, catalog map[string]bool) error {
+	for id := range catalog {
+		index := -1
+		for i, name := range indicatorNames {
+			if name == id {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			fmt.Sscanf(id, "col_%d", &index)
+		}
+		if _, err := db.Exec("INSERT OR IGNORE INTO indicator_catalog (indicator_id, source_index) VALUES (?, ?)", id, index); err != nil {
+			return fmt.Errorf("登记指标 %s 失败: %v", id, err)
+		}
 	}
+	return nil
+}
+
+// parseInsert 解析一行中的 INSERT 语句，返回源列布局和所有值元组；不含 VALUES 的行返回 nil。
+// 带列名的语句按列名定位股票代码与时间序列JSON，列名不匹配或元组的值个数与列数不一致时返回错误
+func parseInsert(line string) (*sourceLayout, [][]string, error) {
+	layout := defaultSourceLayout
+	var rest string
+	if m := insertColumnsPattern.FindStringSubmatchIndex(line); m != nil {
+		var err error
+		if layout, err = parseSourceColumns(line[m[2]:m[3]]); err != nil {
+			return nil, nil, err
+		}
+		rest = line[m[1]:]
+	} else if loc := valuesPattern.FindStringIndex(line); loc != nil {
+		rest = line[loc[1]:]
+	} else {
+		return nil, nil, nil
+	}
+
+	tuples, err := parseTuples(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tuple := range tuples {
+		if len(tuple) != len(layout.columns) {
+			return nil, nil, fmt.Errorf("VALUES 元组有 %d 个值，但列为 %v", len(tuple), layout.columns)
+		}
+	}
+	return layout, tuples, nil
+}
+
+// parseSourceColumns 解析 INSERT 列名列表，按 -code-column / -data-column 的候选列名定位
+func parseSourceColumns(list string) (*sourceLayout, error) {
+	layout := &sourceLayout{code: -1, data: -1}
+	for i, column := range strings.Split(list, ",") {
+		column = strings.ToLower(strings.Trim(strings.TrimSpace(column), "`\"[]"))
+		layout.columns = append(layout.columns, column)
+		if layout.code < 0 && isCandidate(column, *codeColumn) {
+			layout.code = i
+		}
+		if layout.data < 0 && isCandidate(column, *dataColumn) {
+			layout.data = i
+		}
+	}
+	if layout.code < 0 {
+		return nil, fmt.Errorf("INSERT 列 %v 中没有股票代码列（候选: %s，可用 -code-column 指定）", layout.columns, *codeColumn)
+	}
+	if layout.data < 0 {
+		return nil, fmt.Errorf("INSERT 列 %v 中没有时间序列JSON列（候选: %s，可用 -data-column 指定）", layout.columns, *dataColumn)
+	}
+	return layout, nil
+}
+
+// isCandidate 列名是否在逗号分隔的候选列表中
+func isCandidate(column, candidates string) bool {
+	for _, candidate := range strings.Split(candidates, ",") {
+		if strings.EqualFold(strings.TrimSpace(candidate), column) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTuples 解析 VALUES 之后的一个或多个元组：单引号字符串（保留反斜杠转义原样，连续两个单引号表示一个单引号）或裸值
+func parseTuples(s string) ([][]string, error) {
+	var tuples [][]string
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == ',' || s[i] == '\r') {
+			i++
+		}
+		if i >= len(s) || s[i] == ';' {
+			return tuples, nil
+		}
+		if s[i] != '(' {
+			return nil, fmt.Errorf("VALUES 第 %d 个字符处应为 '('", i)
+		}
+		i++
+
+		var tuple []string
+		for {
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("VALUES 元组未结束")
+			}
+			var value strings.Builder
+			if s[i] == '\'' {
+				i++
+				closed := false
+				for i < len(s) && !closed {
+					switch {
+					case s[i] == '\\' && i+1 < len(s):
+						value.WriteString(s[i : i+2])
+						i += 2
+					case s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+						value.WriteByte('\'')
+						i += 2
+					case s[i] == '\'':
+						closed = true
+						i++
+					default:
+						value.WriteByte(s[i])
+						i++
+					}
+				}
+				if !closed {
+					return nil, fmt.Errorf("VALUES 中的字符串未结束")
+				}
+			} else {
+				for i < len(s) && s[i] != ',' && s[i] != ')' {
+					value.WriteByte(s[i])
+					i++
+				}
+			}
+			tuple = append(tuple, strings.TrimSpace(value.String()))
 
-	return totalCount, nil
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("VALUES 元组未结束")
+			}
+			if s[i] == ')' {
+				i++
+				break
+			}
+			if s[i] != ',' {
+				return nil, fmt.Errorf("VALUES 第 %d 个字符处应为 ',' 或 ')'", i)
+			}
+			i++
+		}
+		tuples = append(tuples, tuple)
+	}
 }
 
-// parseTimeSeriesData 解析时间序列JSON数据
-func parseTimeSeriesData(stockCode string, jsonStr string) ([]*FinanceRecord, error) {
+// parseTimeSeriesData 解析时间序列JSON数据，返回记录和跳过的报告期数
+// 单个报告期的数组布局不符时记录日志并跳过该报告期，不写入错位的指标，也不影响其他报告期
+func parseTimeSeriesData(stockCode string, jsonStr string) ([]*FinanceRecord, int, error) {
 	// SQL中的JSON格式不标准：{1234567:[...], ...}
 	// 需要将数字key加上引号：{"1234567":[...], ...}
 	fixedJSON := fixJSONKeys(jsonStr)
@@ -289,29 +670,34 @@ func parseTimeSeriesData(stockCode string, jsonStr string) ([]*FinanceRecord, er
 	var timeSeriesData map[string][]interface{}
 
 	if err := json.Unmarshal([]byte(fixedJSON), &timeSeriesData); err != nil {
-		return nil, fmt.Errorf("JSON解析失败: %v", err)
+		return nil, 0, fmt.Errorf("JSON解析失败: %v", err)
 	}
 
 	var records []*FinanceRecord
+	skipped := 0
 
 	for timestampStr, values := range timeSeriesData {
 		// 解析时间戳
 		var timestamp int64
 		fmt.Sscanf(timestampStr, "%d", &timestamp)
 
-		// 提取字段（根据实际SQL数据结构）
-		if len(values) < 10 {
+		// 布局不符时跳过该报告期，不错位写入
+		if err := checkRecordLayout(values); err != nil {
+			log.Printf("  ⚠️  跳过 [%s 报告期 %s]: %v\n", stockCode, timestampStr, err)
+			skipped++
 			continue
 		}
 
 		record := &FinanceRecord{
 			StockCode:  stockCode,
 			ReportDate: timestamp,
+			Indicators: make(map[string]float64),
 			Category:   "stock",
 			Topic:      "stock_a_listing_pool",
 		}
 
 		// 提取字段值
+		record.DeclareDate = parseDeclareDate(values[0])
 		if v, ok := values[1].(string); ok {
 			record.EndDate = v
 		}
@@ -321,15 +707,10 @@ func parseTimeSeriesData(stockCode string, jsonStr string) ([]*FinanceRecord, er
 		if v, ok := values[3].(string); ok {
 			record.Period = v
 		}
-		if v, ok := values[4].(float64); ok {
-			record.OperatingIncome = v
-		}
-		// parent_holder_net_profit 的索引需要根据实际数据确定
-		// 暂时使用索引 5-15 之间尝试
-		for i := 5; i < len(values) && i < 15; i++ {
-			if v, ok := values[i].(float64); ok && v > 0 && record.ParentHolderNetProfit == 0 {
-				record.ParentHolderNetProfit = v
-				break
+		// 保留数组中所有数值型指标
+		for i := firstIndicatorIndex; i < len(values); i++ {
+			if v, ok := values[i].(float64); ok {
+				record.Indicators[indicatorName(i)] = v
 			}
 		}
 
@@ -340,7 +721,63 @@ func parseTimeSeriesData(stockCode string, jsonStr string) ([]*FinanceRecord, er
 		records = append(records, record)
 	}
 
-	return records, nil
+	return records, skipped, nil
+}
+
+// checkRecordLayout 校验时间序列数组符合 [0]公告时间 [1]截止日期 [2]年份 [3]报告期 [4..]指标值 的布局：
+// 数组需覆盖所有映射的指标下标，元信息位置需为字符串（或空），指标位置需为数值（或空）
+func checkRecordLayout(values []interface{}) error {
+	maxIndex := firstIndicatorIndex
+	for index := range indicatorNames {
+		maxIndex = max(maxIndex, index)
+	}
+	if len(values) <= maxIndex {
+		return fmt.Errorf("时间序列数组只有 %d 个值，指标映射需要下标 0..%d", len(values), maxIndex)
+	}
+	for i, name := range []string{"截止日期", "年份", "报告期"} {
+		if v := values[i+1]; v != nil {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("数组下标 %d 应为%s（字符串），实际为 %T %v", i+1, name, v, v)
+			}
+		}
+	}
+	for index, name := range indicatorNames {
+		switch v := values[index].(type) {
+		case float64, nil:
+		default:
+			return fmt.Errorf("数组下标 %d 应为指标 %s 的数值，实际为 %T %v", index, name, v, v)
+		}
+	}
+	return nil
+}
+
+// parseDeclareDate 解析公告日期：支持秒/毫秒时间戳与 "2006-01-02" 格式的日期字符串
+func parseDeclareDate(v interface{}) int64 {
+	switch d := v.(type) {
+	case float64:
+		ts := int64(d)
+		if ts > 1e12 { // 毫秒时间戳
+			ts /= 1000
+		}
+		if ts > 0 {
+			return ts
+		}
+	case string:
+		for _, layout := range []string{"2006-01-02", "20060102", "2006-01-02 15:04:05"} {
+			if t, err := time.ParseInLocation(layout, d, time.Local); err == nil {
+				return t.Unix()
+			}
+		}
+	}
+	return 0
+}
+
+// nullableTimestamp 0 值写入为 NULL
+func nullableTimestamp(ts int64) interface{} {
+	if ts <= 0 {
+		return nil
+	}
+	return ts
 }
 
 // fixJSONKeys 修复JSON格式
@@ -379,7 +816,17 @@ func verifyData(db *sql.DB) {
 	db.QueryRow("SELECT COUNT(DISTINCT stock_code) FROM finance_data").Scan(&stockCount)
 	fmt.Printf("  📈 股票数量: %d\n", stockCount)
 
-	// 显示样本数据
+	// 窄表统计
+	var indicatorRows, indicatorKinds int
+	db.QueryRow("SELECT COUNT(*) FROM finance_indicator").Scan(&indicatorRows)
+	db.QueryRow("SELECT COUNT(*) FROM indicator_catalog").Scan(&indicatorKinds)
+	fmt.Printf("  🧮 窄表指标值: %d 条, 指标种类: %d\n", indicatorRows, indicatorKinds)
+
+	// 显示样本数据（仅宽表布局）
+	if *layout != "wide" {
+		fmt.Println()
+		return
+	}
 	rows, err := db.Query(`
 		SELECT subject_key, stock_name, end_date, operating_income, parent_holder_net_profit
 		FROM finance_data
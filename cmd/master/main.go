@@ -9,6 +9,7 @@ import (
 	"KamaitachiGo/pkg/config"
 	"KamaitachiGo/pkg/etcd"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -38,12 +39,41 @@ func main() {
 	})
 	logrus.Infof("Starting Kamaitachi Master Server on port %s", cfg.Server.Port)
 
-	// 创建LRU缓存
-	cache := lru.NewCache(cfg.Cache.MaxBytes, nil)
+	// 创建LRU缓存（供兼容的 MemoryRepository 使用）
+	cache, err := lru.New(cfg.Cache.MaxBytes, cfg.Cache.ShardCount(), lru.PolicyLRU, nil)
+	if err != nil {
+		logrus.Fatalf("Failed to initialize LRU cache: %v", err)
+	}
+	cache.SetDefaultTTL(cfg.Cache.TTL())
+	cache.SetSlidingExpiration(cfg.Cache.SlidingExpiration)
+	cache.StartJanitor(cfg.Cache.JanitorPeriod())
 	logrus.Infof("LRU cache initialized with max bytes: %d", cfg.Cache.MaxBytes)
 
-	// 创建快照管理器
-	snapshotMgr := snapshot.NewManager(cache, cfg.Cache.SnapshotPath)
+	// 创建SQLite仓库
+	sqliteRepo, err := repository.NewSQLiteRepository(*dbPath)
+	if err != nil {
+		logrus.Fatalf("Failed to initialize SQLite repository: %v", err)
+	}
+	defer sqliteRepo.Close()
+	sqliteRepo.StartSchemaReload(cfg.Database.CatalogReloadPeriod())
+	logrus.Infof("SQLite repository initialized (DB: %s)", *dbPath)
+
+	// 创建服务
+	financeService := service.NewFinanceService(sqliteRepo, cfg.Cache.MaxBytes, cfg.Cache.ShardCount(), cfg.Cache.EvictionPolicy)
+	if err := financeService.EnablePolicyComparison(cfg.Cache.ComparePolicyList()); err != nil {
+		logrus.Warnf("Failed to enable cache policy comparison: %v", err)
+	}
+	financeService.ConfigureCacheExpiration(cfg.Cache.TTL(), cfg.Cache.SlidingExpiration, cfg.Cache.JanitorPeriod())
+
+	// 创建快照管理器，持久化 FinanceService 缓存中的 StockDataMap，重启后按原有顺序恢复热数据
+	snapshotMgr := snapshot.NewManager(financeService.Cache(), cfg.Cache.SnapshotPath)
+	snapshotMgr.RegisterCodec(service.StockDataMapCodec{})
+	if compression, err := snapshot.ParseCompression(cfg.Cache.SnapshotCompression); err != nil {
+		logrus.Warnf("%v, snapshots will be saved uncompressed", err)
+	} else {
+		snapshotMgr.SetCompression(compression)
+	}
+	snapshotMgr.SetRetention(cfg.Cache.SnapshotRetain)
 
 	// 尝试加载历史快照
 	count, err := snapshotMgr.Load()
@@ -58,21 +88,10 @@ func main() {
 	snapshotMgr.AutoSnapshot(snapshotInterval)
 	logrus.Infof("Auto snapshot enabled with interval: %v", snapshotInterval)
 
-	// 创建SQLite仓库
-	sqliteRepo, err := repository.NewSQLiteRepository(*dbPath)
-	if err != nil {
-		logrus.Fatalf("Failed to initialize SQLite repository: %v", err)
-	}
-	defer sqliteRepo.Close()
-	logrus.Infof("SQLite repository initialized (DB: %s)", *dbPath)
-
-	// 创建服务
-	financeService := service.NewFinanceService(sqliteRepo, cfg.Cache.MaxBytes)
-
-	// 预热缓存
+	// 预热配置：配置文件中的请求 + 上次运行访问最多的查询
+	warmupSpec := prepareWarmup(&cfg.Warmup, financeService)
 	go func() {
-		time.Sleep(2 * time.Second) // 等待服务启动
-		if err := financeService.WarmupCache(); err != nil {
+		if err := financeService.Warmup(warmupSpec, nil, cfg.Warmup.Workers()); err != nil {
 			logrus.Warnf("Cache warmup failed: %v", err)
 		}
 	}()
@@ -86,11 +105,24 @@ func main() {
 	financeHandler := handler.NewFinanceHandler(financeService)
 	dataHandler := handler.NewDataHandler(dataService)
 	selectionHandler := handler.NewSelectionHandler(selectionService)
+	snapshotHandler := handler.NewSnapshotHandler(snapshotMgr)
+
+	// 就绪检查：数据库可用、预热已完成（快照在启动HTTP服务前同步加载）
+	healthHandler := handler.NewHealthHandler(financeService,
+		handler.ReadinessCheck{Name: "database", Check: sqliteRepo.Ping},
+		handler.ReadinessCheck{Name: "warmup", Check: func() error {
+			if !financeService.WarmupDone() {
+				return fmt.Errorf("warmup in progress")
+			}
+			return nil
+		}},
+	)
 
 	// 设置路由（使用新的finance API）
-	router := setupRouter(financeHandler, dataHandler, selectionHandler)
+	router := setupRouter(financeHandler, dataHandler, selectionHandler, snapshotHandler, healthHandler)
 
 	// 如果配置了etcd，注册服务
+	var registration *etcd.Registration
 	if cfg.Etcd.Endpoints != "" {
 		etcdClient, err := etcd.NewClient([]string{cfg.Etcd.Endpoints})
 		if err != nil {
@@ -99,12 +131,13 @@ func main() {
 			// 注册服务到etcd
 			serviceName := cfg.Server.ServiceName
 			serviceAddr := cfg.Server.ServiceAddr
-			err = etcdClient.Register(serviceName, serviceAddr, cfg.Etcd.TTL)
+			registration, err = etcdClient.Register(serviceName, serviceAddr, cfg.Etcd.TTL)
 			if err != nil {
-				logrus.Errorf("Failed to register service: %v", err)
+				logrus.Errorf("Failed to register service: %v, retrying in background", err)
 			} else {
 				logrus.Infof("Service registered to etcd: %s -> %s", serviceName, serviceAddr)
 			}
+			healthHandler.SetRegistrationStatus(registration.Status)
 			defer etcdClient.Close()
 		}
 	}
@@ -125,13 +158,48 @@ func main() {
 
 	logrus.Info("Shutting down server...")
 
+	// 先注销，不必等待租约过期
+	if registration != nil {
+		registration.Deregister()
+	}
+
 	// 保存快照
 	snapshotMgr.Stop()
+	cache.StopJanitor()
+	financeService.Close()
+	if cfg.Warmup.AccessLogPath != "" {
+		if err := financeService.AccessLog().Save(cfg.Warmup.AccessLogPath); err != nil {
+			logrus.Errorf("Failed to save access log: %v", err)
+		}
+	}
 
 	logrus.Info("Server stopped")
 }
 
-func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.DataHandler, selectionHandler *handler.SelectionHandler) *gin.Engine {
+// prepareWarmup 读取预热配置并开启访问记录，上次运行保存的访问记录合并到预热配置中
+func prepareWarmup(cfg *config.WarmupConfig, financeService *service.FinanceService) *service.WarmupSpec {
+	spec := &service.WarmupSpec{}
+	if cfg.SpecPath != "" {
+		loaded, err := service.LoadWarmupSpec(cfg.SpecPath)
+		if err != nil {
+			logrus.Warnf("Failed to load warmup spec: %v", err)
+		} else {
+			spec.Merge(loaded)
+		}
+	}
+
+	if cfg.AccessLogPath != "" {
+		accessLog, err := service.LoadAccessLog(cfg.AccessLogPath, cfg.AccessLogTop)
+		if err != nil && !os.IsNotExist(err) {
+			logrus.Warnf("Failed to load access log: %v", err)
+		}
+		financeService.EnableAccessLog(accessLog)
+		spec.Merge(accessLog.Spec())
+	}
+	return spec
+}
+
+func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.DataHandler, selectionHandler *handler.SelectionHandler, snapshotHandler *handler.SnapshotHandler, healthHandler *handler.HealthHandler) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	// 使用gin.New()而非Default()，关闭Logger提升性能
 	r := gin.New()
@@ -146,6 +214,12 @@ func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.Da
 		apiGroup.POST("/period", financeHandler.Period)
 		apiGroup.POST("/period/", financeHandler.Period)
 		apiGroup.GET("/stats", financeHandler.Stats)
+
+		// 缓存快照管理：列出/保存/加载/删除快照代
+		apiGroup.GET("/cache/snapshots", snapshotHandler.List)
+		apiGroup.POST("/cache/snapshots", snapshotHandler.Save)
+		apiGroup.POST("/cache/snapshots/:id/load", snapshotHandler.Load)
+		apiGroup.DELETE("/cache/snapshots/:id", snapshotHandler.Delete)
 	}
 
 	// 数据管理接口
@@ -167,9 +241,9 @@ func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.Da
 	}
 
 	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	r.GET("/health", healthHandler.Health)
+	r.GET("/health/live", healthHandler.Live)
+	r.GET("/health/ready", healthHandler.Ready)
 
 	return r
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"KamaitachiGo/internal/handler"
@@ -20,6 +21,10 @@ var (
 	dbPath    = flag.String("db", "./data/finance_test.db", "SQLite database path")
 	port      = flag.Int("port", 8080, "Server port")
 	cacheSize = flag.Int64("cache", 2*1024*1024*1024, "LRU cache size in bytes")
+	shards    = flag.Int("shards", 16, "LRU cache shard count (1 = single lock)")
+	policy    = flag.String("policy", "lru", "Cache eviction policy: lru, lfu, wtinylfu, arc")
+	compare   = flag.String("compare", "", "Comma separated eviction policies to compare hit rates (empty = off)")
+	warmup    = flag.String("warmup", "conf/warmup.json", "Cache warmup spec (empty = no warmup)")
 	debug     = flag.Bool("debug", false, "Enable debug logging")
 )
 
@@ -43,6 +48,8 @@ func main() {
 	logrus.Infof("Database: %s", *dbPath)
 	logrus.Infof("Port: %d", *port)
 	logrus.Infof("Cache Size: %.2f GB", float64(*cacheSize)/(1024*1024*1024))
+	logrus.Infof("Cache Shards: %d", *shards)
+	logrus.Infof("Cache Policy: %s", *policy)
 
 	// 检查数据库文件是否存在
 	if _, err := os.Stat(*dbPath); os.IsNotExist(err) {
@@ -55,26 +62,47 @@ func main() {
 		log.Fatalf("Failed to initialize repository: %v", err)
 	}
 	defer repo.Close()
+	repo.StartSchemaReload(time.Minute)
 	logrus.Info("Repository initialized")
 
 	// 初始化Service
-	financeService := service.NewFinanceService(repo, *cacheSize)
+	financeService := service.NewFinanceService(repo, *cacheSize, *shards, *policy)
+	if *compare != "" {
+		if err := financeService.EnablePolicyComparison(strings.Split(*compare, ",")); err != nil {
+			logrus.Warnf("Failed to enable cache policy comparison: %v", err)
+		}
+	}
 	logrus.Info("Service initialized")
 
 	// 预热缓存
-	go func() {
-		time.Sleep(2 * time.Second) // 等待服务启动
-		if err := financeService.WarmupCache(); err != nil {
-			logrus.Warnf("Cache warmup failed: %v", err)
+	if *warmup != "" {
+		spec, err := service.LoadWarmupSpec(*warmup)
+		if err != nil {
+			logrus.Warnf("Failed to load warmup spec: %v", err)
+		} else {
+			go func() {
+				if err := financeService.Warmup(spec, nil, 4); err != nil {
+					logrus.Warnf("Cache warmup failed: %v", err)
+				}
+			}()
 		}
-	}()
+	}
 
 	// 初始化Handler
 	financeHandler := handler.NewFinanceHandler(financeService)
 	logrus.Info("Handler initialized")
 
 	// 初始化路由
-	router := setupRouter(financeHandler)
+	healthHandler := handler.NewHealthHandler(financeService,
+		handler.ReadinessCheck{Name: "database", Check: repo.Ping},
+		handler.ReadinessCheck{Name: "warmup", Check: func() error {
+			if *warmup != "" && !financeService.WarmupDone() {
+				return fmt.Errorf("warmup in progress")
+			}
+			return nil
+		}},
+	)
+	router := setupRouter(financeHandler, healthHandler)
 	logrus.Info("Router initialized")
 
 	// 启动服务器
@@ -87,7 +115,7 @@ func main() {
 	}
 }
 
-func setupRouter(financeHandler *handler.FinanceHandler) *gin.Engine {
+func setupRouter(financeHandler *handler.FinanceHandler, healthHandler *handler.HealthHandler) *gin.Engine {
 	// 设置Gin模式
 	if !*debug {
 		gin.SetMode(gin.ReleaseMode)
@@ -105,11 +133,9 @@ func setupRouter(financeHandler *handler.FinanceHandler) *gin.Engine {
 	r.Use(middleware.CircuitBreakerMiddleware())
 
 	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-		})
-	})
+	r.GET("/health", healthHandler.Health)
+	r.GET("/health/live", healthHandler.Live)
+	r.GET("/health/ready", healthHandler.Ready)
 
 	// 赛事方API接口
 	apiGroup := r.Group("/kamaitachi/api/data/v1")
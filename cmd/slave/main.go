@@ -4,15 +4,22 @@ import (
 	"KamaitachiGo/internal/cache/lru"
 	"KamaitachiGo/internal/cache/snapshot"
 	"KamaitachiGo/internal/handler"
+	"KamaitachiGo/internal/middleware"
 	"KamaitachiGo/internal/repository"
 	"KamaitachiGo/internal/service"
 	"KamaitachiGo/pkg/config"
 	"KamaitachiGo/pkg/etcd"
+	"KamaitachiGo/pkg/hash"
+	"context"
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -20,9 +27,15 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// drainQuiet 退出时最后一次导出结束后再等待的时间，并行拉取的其他节点在此期间开始导出
+const drainQuiet = 2 * time.Second
+
 var (
 	configFile = flag.String("config", "conf/slave.ini", "Config file path")
 	dbPath     = flag.String("db", "./data/slave1.db", "Database file path")
+
+	// version 注册到etcd的程序版本，构建时通过 -ldflags "-X main.version=..." 注入
+	version = "dev"
 )
 
 func main() {
@@ -59,25 +72,15 @@ func main() {
 
 	logrus.Infof("Starting Kamaitachi Slave Server on port %s", cfg.Server.Port)
 
-	// 创建LRU缓存
-	cache := lru.NewCache(cfg.Cache.MaxBytes, nil)
-	logrus.Infof("LRU cache initialized with max bytes: %d", cfg.Cache.MaxBytes)
-
-	// 创建快照管理器
-	snapshotMgr := snapshot.NewManager(cache, cfg.Cache.SnapshotPath)
-
-	// 尝试加载历史快照
-	count, err := snapshotMgr.Load()
+	// 创建LRU缓存（供兼容的 MemoryRepository 使用）
+	cache, err := lru.New(cfg.Cache.MaxBytes, cfg.Cache.ShardCount(), lru.PolicyLRU, nil)
 	if err != nil {
-		logrus.Warnf("Failed to load snapshot: %v (This is normal for first run)", err)
-	} else {
-		logrus.Infof("Loaded %d entries from snapshot", count)
+		logrus.Fatalf("Failed to initialize LRU cache: %v", err)
 	}
-
-	// 启动自动快照
-	snapshotInterval := time.Duration(cfg.Cache.SnapshotInterval) * time.Minute
-	snapshotMgr.AutoSnapshot(snapshotInterval)
-	logrus.Infof("Auto snapshot enabled with interval: %v", snapshotInterval)
+	cache.SetDefaultTTL(cfg.Cache.TTL())
+	cache.SetSlidingExpiration(cfg.Cache.SlidingExpiration)
+	cache.StartJanitor(cfg.Cache.JanitorPeriod())
+	logrus.Infof("LRU cache initialized with max bytes: %d", cfg.Cache.MaxBytes)
 
 	// 创建SQLite仓库
 	sqliteRepo, err := repository.NewSQLiteRepository(*dbPath)
@@ -85,18 +88,28 @@ func main() {
 		logrus.Fatalf("Failed to initialize SQLite repository: %v", err)
 	}
 	defer sqliteRepo.Close()
+	sqliteRepo.StartSchemaReload(cfg.Database.CatalogReloadPeriod())
 	logrus.Infof("SQLite repository initialized (DB: %s)", *dbPath)
 
 	// 创建服务
-	financeService := service.NewFinanceService(sqliteRepo, cfg.Cache.MaxBytes)
+	financeService := service.NewFinanceService(sqliteRepo, cfg.Cache.MaxBytes, cfg.Cache.ShardCount(), cfg.Cache.EvictionPolicy)
+	if err := financeService.EnablePolicyComparison(cfg.Cache.ComparePolicyList()); err != nil {
+		logrus.Warnf("Failed to enable cache policy comparison: %v", err)
+	}
+	financeService.ConfigureCacheExpiration(cfg.Cache.TTL(), cfg.Cache.SlidingExpiration, cfg.Cache.JanitorPeriod())
 
-	// 预热缓存
-	go func() {
-		time.Sleep(2 * time.Second) // 等待服务启动
-		if err := financeService.WarmupCache(); err != nil {
-			logrus.Warnf("Cache warmup failed: %v", err)
-		}
-	}()
+	// 创建快照管理器，持久化 FinanceService 缓存中的 StockDataMap，重启后按原有顺序恢复热数据
+	snapshotMgr := snapshot.NewManager(financeService.Cache(), cfg.Cache.SnapshotPath)
+	snapshotMgr.RegisterCodec(service.StockDataMapCodec{})
+	if compression, err := snapshot.ParseCompression(cfg.Cache.SnapshotCompression); err != nil {
+		logrus.Warnf("%v, snapshots will be saved uncompressed", err)
+	} else {
+		snapshotMgr.SetCompression(compression)
+	}
+	snapshotMgr.SetRetention(cfg.Cache.SnapshotRetain)
+
+	// 预热配置：配置文件中的请求 + 上次运行访问最多的查询
+	warmupSpec := prepareWarmup(&cfg.Warmup, financeService)
 
 	// 兼容旧的服务（如果需要）
 	memRepo := repository.NewMemoryRepository(cache)
@@ -107,38 +120,98 @@ func main() {
 	financeHandler := handler.NewFinanceHandler(financeService)
 	dataHandler := handler.NewDataHandler(dataService)
 	selectionHandler := handler.NewSelectionHandler(selectionService)
+	snapshotHandler := handler.NewSnapshotHandler(snapshotMgr)
+	handoffHandler := handler.NewHandoffHandler(snapshotMgr, cfg.Server.InternalToken)
+
+	// 就绪检查：数据库可用、快照已加载、预热已完成，全部通过后网关才会将本节点加入哈希环
+	var snapshotLoaded int32
+	healthHandler := handler.NewHealthHandler(financeService,
+		handler.ReadinessCheck{Name: "database", Check: sqliteRepo.Ping},
+		handler.ReadinessCheck{Name: "snapshot", Check: func() error {
+			if atomic.LoadInt32(&snapshotLoaded) == 0 {
+				return fmt.Errorf("snapshot loading")
+			}
+			return nil
+		}},
+		handler.ReadinessCheck{Name: "warmup", Check: func() error {
+			if !financeService.WarmupDone() {
+				return fmt.Errorf("warmup in progress")
+			}
+			return nil
+		}},
+	)
 
 	// 设置路由（使用新的finance API）
-	router := setupRouter(financeHandler, dataHandler, selectionHandler)
+	if cfg.Server.InternalToken == "" {
+		logrus.Warn("internal_token is not configured, cache handoff endpoints will reject all requests")
+	}
+	router := setupRouter(financeHandler, dataHandler, selectionHandler, snapshotHandler, handoffHandler, healthHandler, cfg.Server.InternalToken)
 
 	// 如果配置了etcd，注册服务
+	var owns func(routeKey string) bool
+	var registration *etcd.Registration
 	if cfg.Etcd.Endpoints != "" {
 		etcdClient, err := etcd.NewClient([]string{cfg.Etcd.Endpoints})
 		if err != nil {
 			logrus.Errorf("Failed to connect to etcd: %v", err)
 		} else {
-			// 注册服务到etcd
+			// 注册服务到etcd，描述信息中的权重决定本节点在网关哈希环上的虚拟节点数
 			serviceName := cfg.Server.ServiceName
-			serviceAddr := cfg.Server.ServiceAddr
-			err = etcdClient.Register(serviceName, serviceAddr, cfg.Etcd.TTL)
+			self := &etcd.NodeInfo{
+				Address:  cfg.Server.ServiceAddr,
+				Weight:   max(cfg.Server.Weight, 1),
+				Zone:     cfg.Server.Zone,
+				Version:  version,
+				Capacity: cfg.Cache.MaxBytes,
+			}
+			// 租约丢失（etcd 短暂不可用或租约过期）后自动重新注册，注册状态在 /health 中展示
+			registration, err = etcdClient.RegisterNode(serviceName, self, cfg.Etcd.TTL)
 			if err != nil {
-				logrus.Errorf("Failed to register service: %v", err)
+				logrus.Errorf("Failed to register service: %v, retrying in background", err)
 			} else {
-				logrus.Infof("Service registered to etcd: %s -> %s", serviceName, serviceAddr)
+				logrus.Infof("Service registered to etcd: %s -> %s (weight %d)", serviceName, self.Address, self.Weight)
 			}
+			registration.OnStateChange(func(state etcd.RegistrationState, err error) {
+				if state == etcd.StateLost {
+					logrus.Errorf("Service registration lost, node is invisible to the gateway until re-registered: %v", err)
+				}
+			})
+			healthHandler.SetRegistrationStatus(registration.Status)
+			owns = ringOwnership(etcdClient, serviceName, self, cfg.Routing.Strategy)
 			defer etcdClient.Close()
 		}
 	}
 
-	// 启动HTTP服务器
+	// 启动HTTP服务器，存活检查立即可用，加载和预热完成前就绪检查不通过
+	server := &http.Server{Addr: ":" + cfg.Server.Port, Handler: router}
 	go func() {
-		addr := ":" + cfg.Server.Port
-		logrus.Infof("HTTP server listening on %s", addr)
-		if err := router.Run(addr); err != nil {
+		logrus.Infof("HTTP server listening on %s", server.Addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logrus.Fatalf("Failed to start HTTP server: %v", err)
 		}
 	}()
 
+	go func() {
+		// 尝试加载历史快照
+		count, err := snapshotMgr.Load()
+		if err != nil {
+			logrus.Warnf("Failed to load snapshot: %v (This is normal for first run)", err)
+		} else {
+			logrus.Infof("Loaded %d entries from snapshot", count)
+		}
+		atomic.StoreInt32(&snapshotLoaded, 1)
+
+		// 启动自动快照（加载完成后再启动，避免用未恢复的缓存覆盖历史快照）
+		snapshotInterval := time.Duration(cfg.Cache.SnapshotInterval) * time.Minute
+		snapshotMgr.AutoSnapshot(snapshotInterval)
+		logrus.Infof("Auto snapshot enabled with interval: %v", snapshotInterval)
+
+		// 预热缓存，只预热本节点在哈希环上负责的subject
+		if err := financeService.Warmup(warmupSpec, owns, cfg.Warmup.Workers()); err != nil {
+			logrus.Warnf("Cache warmup failed: %v", err)
+		}
+	}()
+
 	// 等待退出信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -146,13 +219,127 @@ func main() {
 
 	logrus.Info("Shutting down server...")
 
+	// 先注销，让网关尽快开始交接，而不是等待租约过期。网关收到注销后先让其余节点从本节点的 /cache/export
+	// 拉取迁移的数据，交接完成（或超时）后才把本节点移出路由，这期间仍有请求转发到本节点，
+	// 因此注销后继续服务，直到导出结束或超过 drain_timeout 再关闭HTTP服务
+	if registration != nil {
+		deregistered := time.Now()
+		registration.Deregister()
+		if wait := cfg.Server.DrainWait(); wait > 0 {
+			logrus.Infof("Draining: waiting up to %v for the gateway to hand off cached data", wait)
+			if handoffHandler.Drain(deregistered, wait, drainQuiet) {
+				logrus.Infof("Cached data handed off in %v", time.Since(deregistered).Round(time.Millisecond))
+			} else {
+				logrus.Warnf("No handoff completed within %v, shutting down", wait)
+			}
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logrus.Warnf("HTTP server shutdown: %v", err)
+	}
+	cancel()
+
 	// 保存快照
 	snapshotMgr.Stop()
+	cache.StopJanitor()
+	financeService.Close()
+	if cfg.Warmup.AccessLogPath != "" {
+		if err := financeService.AccessLog().Save(cfg.Warmup.AccessLogPath); err != nil {
+			logrus.Errorf("Failed to save access log: %v", err)
+		}
+	}
 
 	logrus.Info("Server stopped")
 }
 
-func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.DataHandler, selectionHandler *handler.SelectionHandler) *gin.Engine {
+// ringOwnership 按网关相同的路由策略（含节点权重）计算路由Key是否由本节点负责，发现失败时返回 nil，即预热全部数据
+// 结果是近似的：环由 etcd 中注册的所有节点（包括本节点）组成，而网关的环只包含通过就绪检查、未被摘除的节点，
+// 两者不一致时部分subject会在非归属节点上预热或被漏掉；预热只是减少冷启动的回源，节点准入时的缓存交接会补齐归属数据。
+// 节点注册或注销时重建环，之后的判断按新的环进行，此前已完成的预热不会重做
+func ringOwnership(etcdClient *etcd.Client, serviceName string, self *etcd.NodeInfo, strategy string) func(routeKey string) bool {
+	if _, err := hash.NewRouter(strategy, hash.DefaultReplicas); err != nil {
+		logrus.Warnf("%v, warming up all subjects", err)
+		return nil
+	}
+	nodes, err := etcdClient.DiscoverNodes(serviceName)
+	if err != nil {
+		logrus.Warnf("Failed to discover %s nodes, warming up all subjects: %v", serviceName, err)
+		return nil
+	}
+
+	var mu sync.Mutex
+	members := make(map[string]int, len(nodes)+1)
+	for _, node := range nodes {
+		members[node.Address] = node.Weight
+	}
+	members[self.Address] = self.Weight
+
+	var ring atomic.Value
+	rebuild := func() {
+		router, _ := hash.NewRouter(strategy, hash.DefaultReplicas)
+		for address, weight := range members {
+			router.AddWeighted(address, weight)
+		}
+		ring.Store(router)
+		logrus.Infof("Warmup ownership computed on a ring of %d nodes", len(members))
+	}
+	rebuild()
+
+	prefix := fmt.Sprintf("/services/%s/", serviceName)
+	etcdClient.WatchPrefix(prefix, func(eventType, key, value string) {
+		address := strings.TrimPrefix(key, prefix)
+		mu.Lock()
+		defer mu.Unlock()
+		switch eventType {
+		case "PUT":
+			node, err := etcd.ParseNodeInfo(value)
+			if err != nil || members[node.Address] == node.Weight {
+				return
+			}
+			members[node.Address] = node.Weight
+		case "DELETE":
+			// 本节点的注册在租约丢失后会重新写入，预热期间始终计入本节点
+			if address == self.Address {
+				return
+			}
+			if _, ok := members[address]; !ok {
+				return
+			}
+			delete(members, address)
+		}
+		rebuild()
+	})
+
+	return func(routeKey string) bool {
+		return ring.Load().(hash.Router).Get(routeKey) == self.Address
+	}
+}
+
+// prepareWarmup 读取预热配置并开启访问记录，上次运行保存的访问记录合并到预热配置中
+func prepareWarmup(cfg *config.WarmupConfig, financeService *service.FinanceService) *service.WarmupSpec {
+	spec := &service.WarmupSpec{}
+	if cfg.SpecPath != "" {
+		loaded, err := service.LoadWarmupSpec(cfg.SpecPath)
+		if err != nil {
+			logrus.Warnf("Failed to load warmup spec: %v", err)
+		} else {
+			spec.Merge(loaded)
+		}
+	}
+
+	if cfg.AccessLogPath != "" {
+		accessLog, err := service.LoadAccessLog(cfg.AccessLogPath, cfg.AccessLogTop)
+		if err != nil && !os.IsNotExist(err) {
+			logrus.Warnf("Failed to load access log: %v", err)
+		}
+		financeService.EnableAccessLog(accessLog)
+		spec.Merge(accessLog.Spec())
+	}
+	return spec
+}
+
+func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.DataHandler, selectionHandler *handler.SelectionHandler, snapshotHandler *handler.SnapshotHandler, handoffHandler *handler.HandoffHandler, healthHandler *handler.HealthHandler, internalToken string) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	// 使用gin.New()而非Default()，关闭Logger提升性能
 	r := gin.New()
@@ -167,6 +354,20 @@ func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.Da
 		apiGroup.POST("/period", financeHandler.Period)
 		apiGroup.POST("/period/", financeHandler.Period)
 		apiGroup.GET("/stats", financeHandler.Stats)
+
+		// 缓存快照管理：列出/保存/加载/删除快照代
+		apiGroup.GET("/cache/snapshots", snapshotHandler.List)
+		apiGroup.POST("/cache/snapshots", snapshotHandler.Save)
+		apiGroup.POST("/cache/snapshots/:id/load", snapshotHandler.Load)
+		apiGroup.DELETE("/cache/snapshots/:id", snapshotHandler.Delete)
+	}
+
+	// 节点间缓存交接：网关切换路由前，新的归属节点从原归属节点拉取迁移的数据
+	// 不在对外的 API 分组中，请求须携带共享令牌
+	internalGroup := r.Group("/kamaitachi/internal/v1", middleware.InternalAuth(internalToken))
+	{
+		internalGroup.POST("/cache/export", handoffHandler.Export)
+		internalGroup.POST("/cache/handoff", handoffHandler.Handoff)
 	}
 
 	// 数据管理接口
@@ -188,9 +389,9 @@ func setupRouter(financeHandler *handler.FinanceHandler, dataHandler *handler.Da
 	}
 
 	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	r.GET("/health", healthHandler.Health)
+	r.GET("/health/live", healthHandler.Live)
+	r.GET("/health/ready", healthHandler.Ready)
 
 	return r
 }
@@ -0,0 +1,72 @@
+// Package batchflight 合并并发的批量加载
+//
+// 单个 key 的加载直接使用 golang.org/x/sync/singleflight。批量查询（一次 SQL 取回多个 subject、
+// 多个缺口）需要一个请求同时领导多个 key：先登记全部 key，只对自己领导的 key 发起一次合并查询，
+// 再分别提交结果，同时等待其他请求领导的 key。singleflight.Do 以回调为单位、一次只能领导一个 key，
+// 无法表达"登记多个 key、一次查询、分别提交"，因此这里只保留 Begin/Finish 这一组原语。
+package batchflight
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrAborted 领导者在完成加载前退出（如发生 panic），等待者收到该错误
+var ErrAborted = errors.New("batchflight: leader aborted before completing the load")
+
+// Call 一次进行中的加载
+type Call struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// Wait 等待加载完成并返回结果
+func (c *Call) Wait() (interface{}, error) {
+	c.wg.Wait()
+	return c.val, c.err
+}
+
+// Group 按 key 登记进行中的加载：同一 key 同时只有一个请求（领导者）执行加载，其余请求等待并共享结果
+type Group struct {
+	mu sync.Mutex
+	m  map[string]*Call
+}
+
+// Begin 登记对 key 的加载。没有进行中的加载时返回新的 Call 和 true，调用方成为领导者，
+// 完成加载后必须调用 Finish；否则返回进行中的 Call 和 false，调用方通过 Wait 获取结果
+func (g *Group) Begin(key string) (*Call, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.m == nil {
+		g.m = make(map[string]*Call)
+	}
+	if c, ok := g.m[key]; ok {
+		return c, false
+	}
+	c := &Call{}
+	c.wg.Add(1)
+	g.m[key] = c
+	return c, true
+}
+
+// Finish 领导者提交加载结果并唤醒所有等待者
+func (g *Group) Finish(key string, c *Call, val interface{}, err error) {
+	g.mu.Lock()
+	c.val = val
+	c.err = err
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
+	g.mu.Unlock()
+
+	c.wg.Done()
+}
+
+// InFlight 返回进行中的加载数量
+func (g *Group) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.m)
+}
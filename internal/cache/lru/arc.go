@@ -0,0 +1,209 @@
+package lru
+
+import "container/list"
+
+// arcNode ARC 策略中的条目状态
+type arcNode struct {
+	entry    *Entry
+	frequent bool // 位于 T2（至少命中过一次）
+	ele      *list.Element
+}
+
+// arcGhost 被淘汰条目的幽灵记录，只保存 key 和大小
+type arcGhost struct {
+	key      string
+	size     int64
+	frequent bool // 位于 B2（从 T2 淘汰）
+}
+
+// arcPolicy 自适应替换缓存（ARC），按字节计量容量
+// T1 保存只访问过一次的条目，T2 保存访问过两次及以上的条目；B1、B2 分别记录最近从 T1、T2 淘汰的 key。
+// 未命中的 key 若出现在 B1 中，说明 T1 偏小，调大 T1 的目标容量 target；出现在 B2 中则调小，
+// 从而在"最近访问"和"频繁访问"两类负载之间自动调整，无需手动配置比例。
+type arcPolicy struct {
+	maxBytes int64
+	target   int64 // T1 的目标容量
+
+	t1 *list.List
+	t2 *list.List
+	b1 *list.List
+	b2 *list.List
+
+	t1Bytes int64
+	t2Bytes int64
+	b1Bytes int64
+	b2Bytes int64
+
+	ghosts map[string]*list.Element
+	victim *Entry // 最近一次 Victim 返回的条目，只有它被 Remove 时才记入幽灵列表
+}
+
+func newARCPolicy(maxBytes int64) *arcPolicy {
+	return &arcPolicy{
+		maxBytes: maxBytes,
+		t1:       list.New(),
+		t2:       list.New(),
+		b1:       list.New(),
+		b2:       list.New(),
+		ghosts:   make(map[string]*list.Element),
+	}
+}
+
+func (p *arcPolicy) Name() string {
+	return PolicyARC
+}
+
+func (p *arcPolicy) Add(e *Entry) {
+	node := &arcNode{entry: e}
+	if ele, ok := p.ghosts[e.Key]; ok {
+		ghost := ele.Value.(*arcGhost)
+		if ghost.frequent {
+			p.target = max(p.target-e.size*max(1, p.b1Bytes/max(p.b2Bytes, 1)), 0)
+		} else {
+			p.target = min(p.target+e.size*max(1, p.b2Bytes/max(p.b1Bytes, 1)), p.maxBytes)
+		}
+		p.removeGhost(ele)
+		node.frequent = true
+	}
+
+	if node.frequent {
+		node.ele = p.t2.PushFront(node)
+		p.t2Bytes += e.size
+	} else {
+		node.ele = p.t1.PushFront(node)
+		p.t1Bytes += e.size
+	}
+	e.node = node
+}
+
+func (p *arcPolicy) Access(e *Entry) {
+	node, ok := e.node.(*arcNode)
+	if !ok {
+		return
+	}
+	if node.frequent {
+		p.t2.MoveToFront(node.ele)
+		return
+	}
+	p.t1.Remove(node.ele)
+	p.t1Bytes -= e.size
+	node.frequent = true
+	node.ele = p.t2.PushFront(node)
+	p.t2Bytes += e.size
+}
+
+func (p *arcPolicy) Miss(key string) {}
+
+func (p *arcPolicy) Resize(e *Entry, delta int64) {
+	node, ok := e.node.(*arcNode)
+	if !ok {
+		return
+	}
+	if node.frequent {
+		p.t2Bytes += delta
+	} else {
+		p.t1Bytes += delta
+	}
+}
+
+func (p *arcPolicy) Remove(e *Entry) {
+	node, ok := e.node.(*arcNode)
+	if !ok {
+		return
+	}
+	if node.frequent {
+		p.t2.Remove(node.ele)
+		p.t2Bytes -= e.size
+	} else {
+		p.t1.Remove(node.ele)
+		p.t1Bytes -= e.size
+	}
+	e.node = nil
+
+	// 过期和主动删除不代表容量不足，只有淘汰的条目才进入幽灵列表
+	if e == p.victim {
+		p.victim = nil
+		p.addGhost(e.Key, e.size, node.frequent)
+	}
+}
+
+// Victim T1 超过目标容量时淘汰 T1 尾部，否则淘汰 T2 尾部；
+// 刚加入的条目是 T1 中唯一的条目时优先淘汰 T2，避免新条目还没被访问就被挤出
+func (p *arcPolicy) Victim() *Entry {
+	var ele *list.Element
+	switch {
+	case p.t2.Len() == 0:
+		ele = p.t1.Back()
+	case p.t1.Len() == 0:
+		ele = p.t2.Back()
+	case p.t1Bytes > p.target && p.t1.Len() > 1:
+		ele = p.t1.Back()
+	default:
+		ele = p.t2.Back()
+	}
+	if ele == nil {
+		return nil
+	}
+	p.victim = ele.Value.(*arcNode).entry
+	return p.victim
+}
+
+func (p *arcPolicy) Entries() []*Entry {
+	entries := make([]*Entry, 0, p.t1.Len()+p.t2.Len())
+	for _, l := range []*list.List{p.t2, p.t1} {
+		for ele := l.Front(); ele != nil; ele = ele.Next() {
+			entries = append(entries, ele.Value.(*arcNode).entry)
+		}
+	}
+	return entries
+}
+
+func (p *arcPolicy) Reset() {
+	p.t1.Init()
+	p.t2.Init()
+	p.b1.Init()
+	p.b2.Init()
+	p.t1Bytes, p.t2Bytes, p.b1Bytes, p.b2Bytes = 0, 0, 0, 0
+	p.target = 0
+	p.ghosts = make(map[string]*list.Element)
+	p.victim = nil
+}
+
+// addGhost 记录被淘汰的 key，并把幽灵列表限制在 T1+B1 不超过容量、四个列表合计不超过两倍容量
+func (p *arcPolicy) addGhost(key string, size int64, frequent bool) {
+	if ele, ok := p.ghosts[key]; ok {
+		p.removeGhost(ele)
+	}
+	ghost := &arcGhost{key: key, size: size, frequent: frequent}
+	if frequent {
+		p.ghosts[key] = p.b2.PushFront(ghost)
+		p.b2Bytes += size
+	} else {
+		p.ghosts[key] = p.b1.PushFront(ghost)
+		p.b1Bytes += size
+	}
+
+	for p.b1.Len() > 0 && p.t1Bytes+p.b1Bytes > p.maxBytes {
+		p.removeGhost(p.b1.Back())
+	}
+	for p.b1.Len()+p.b2.Len() > 0 && p.t1Bytes+p.t2Bytes+p.b1Bytes+p.b2Bytes > 2*p.maxBytes {
+		if p.b2.Len() > 0 {
+			p.removeGhost(p.b2.Back())
+		} else {
+			p.removeGhost(p.b1.Back())
+		}
+	}
+}
+
+// removeGhost 删除一条幽灵记录
+func (p *arcPolicy) removeGhost(ele *list.Element) {
+	ghost := ele.Value.(*arcGhost)
+	if ghost.frequent {
+		p.b2.Remove(ele)
+		p.b2Bytes -= ghost.size
+	} else {
+		p.b1.Remove(ele)
+		p.b1Bytes -= ghost.size
+	}
+	delete(p.ghosts, ghost.key)
+}
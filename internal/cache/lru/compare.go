@@ -0,0 +1,272 @@
+package lru
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Store = (*ComparingStore)(nil)
+
+// ghostValue 影子缓存中的占位值，只记录大小不保存数据
+type ghostValue struct {
+	size int
+}
+
+func (v ghostValue) Len() int {
+	return v.size
+}
+
+// ComparingStore 淘汰策略对比：在真实缓存之外，为每个待比较的策略维护一个同容量的影子缓存，
+// 影子缓存只保存 key 和大小，重放真实缓存收到的全部读写，从而在同一份线上流量下统计各策略的命中率。
+// 真实缓存的行为不受影响，额外开销为每个策略每个条目约一百多字节。
+// 影子缓存与真实缓存按相同的哈希分片、每个分片独立加锁，重放不会把分片缓存的并发读写串行化。
+type ComparingStore struct {
+	Store
+	names    []string
+	shards   []*shadowShard
+	mask     uint32
+	maxBytes int64
+
+	mu     sync.Mutex
+	stopCh chan struct{} // 关闭后台清理协程
+}
+
+// shadowShard 一个分片内各策略的影子缓存，caches 与 names 顺序一致
+type shadowShard struct {
+	mu     sync.Mutex
+	caches []*Cache
+}
+
+// NewComparingStore 包装 primary，为 policies 中的每个策略创建容量为 maxBytes 的影子缓存
+// primary 为分片缓存时，影子缓存使用相同的分片数，每个分片的容量与真实缓存的分片一致
+func NewComparingStore(primary Store, maxBytes int64, policies []string) (*ComparingStore, error) {
+	n := 1
+	if sharded, ok := primary.(*ShardedCache); ok {
+		n = len(sharded.shards)
+	}
+	var shardBytes int64
+	if maxBytes > 0 {
+		shardBytes = (maxBytes + int64(n) - 1) / int64(n)
+	}
+
+	c := &ComparingStore{
+		Store:    primary,
+		shards:   make([]*shadowShard, n),
+		mask:     uint32(n - 1),
+		maxBytes: maxBytes,
+	}
+	for i := range c.shards {
+		c.shards[i] = &shadowShard{}
+	}
+
+	sliding := primary.SlidingExpiration()
+	seen := make(map[string]bool)
+	for _, name := range policies {
+		p, err := NewPolicy(name, shardBytes)
+		if err != nil {
+			return nil, err
+		}
+		if seen[p.Name()] {
+			continue
+		}
+		seen[p.Name()] = true
+		c.names = append(c.names, p.Name())
+
+		for i, shard := range c.shards {
+			if i > 0 {
+				p, _ = NewPolicy(name, shardBytes)
+			}
+			shadow := NewCacheWithPolicy(shardBytes, p, nil)
+			shadow.SetSlidingExpiration(sliding)
+			shard.caches = append(shard.caches, shadow)
+		}
+	}
+	return c, nil
+}
+
+// shard 返回 key 所在的影子分片，与 ShardedCache 的分片方式一致
+func (c *ComparingStore) shard(key string) *shadowShard {
+	return c.shards[keyHash(key)&c.mask]
+}
+
+// Get 获取缓存值，并在各影子缓存中重放同一次读取
+// 影子缓存未命中时按真实值的大小回填，模拟业务在未命中后回源写入
+func (c *ComparingStore) Get(key string) (Value, bool) {
+	value, ok := c.Store.Get(key)
+
+	shard := c.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	for _, shadow := range shard.caches {
+		ghost, hit := shadow.Get(key)
+		if ok && (!hit || ghost.Len() != value.Len()) {
+			shadow.Add(key, ghostValue{size: value.Len()})
+		}
+	}
+	return value, ok
+}
+
+// Add 添加缓存值，同步写入各影子缓存
+func (c *ComparingStore) Add(key string, value Value) {
+	c.Store.Add(key, value)
+
+	shard := c.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	for _, shadow := range shard.caches {
+		shadow.Add(key, ghostValue{size: value.Len()})
+	}
+}
+
+// AddWithTTL 添加缓存值并指定过期时间，同步写入各影子缓存
+func (c *ComparingStore) AddWithTTL(key string, value Value, ttl time.Duration) {
+	c.Store.AddWithTTL(key, value, ttl)
+
+	shard := c.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	for _, shadow := range shard.caches {
+		shadow.AddWithTTL(key, ghostValue{size: value.Len()}, ttl)
+	}
+}
+
+// Restore 按快照中的时间戳恢复条目，同步恢复到各影子缓存
+func (c *ComparingStore) Restore(entry *Entry) bool {
+	if !c.Store.Restore(entry) {
+		return false
+	}
+
+	shard := c.shard(entry.Key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	for _, shadow := range shard.caches {
+		ghost := *entry
+		ghost.Value = ghostValue{size: entry.Value.Len()}
+		shadow.Restore(&ghost)
+	}
+	return true
+}
+
+// Remove 移除指定缓存，同步移除各影子缓存中的条目
+func (c *ComparingStore) Remove(key string) {
+	c.Store.Remove(key)
+
+	shard := c.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	for _, shadow := range shard.caches {
+		shadow.Remove(key)
+	}
+}
+
+// Clear 清空缓存和各影子缓存
+func (c *ComparingStore) Clear() {
+	c.Store.Clear()
+	c.eachShadow(func(shadow *Cache) {
+		shadow.Clear()
+	})
+}
+
+// DeleteExpired 清理过期数据，同时清理各影子缓存
+func (c *ComparingStore) DeleteExpired() int {
+	removed := c.Store.DeleteExpired()
+	c.eachShadow(func(shadow *Cache) {
+		shadow.DeleteExpired()
+	})
+	return removed
+}
+
+// SetDefaultTTL 设置默认过期时间，影子缓存保持一致
+func (c *ComparingStore) SetDefaultTTL(ttl time.Duration) {
+	c.Store.SetDefaultTTL(ttl)
+	c.eachShadow(func(shadow *Cache) {
+		shadow.SetDefaultTTL(ttl)
+	})
+}
+
+// SetSlidingExpiration 开启或关闭滑动过期，影子缓存保持一致
+func (c *ComparingStore) SetSlidingExpiration(enabled bool) {
+	c.Store.SetSlidingExpiration(enabled)
+	c.eachShadow(func(shadow *Cache) {
+		shadow.SetSlidingExpiration(enabled)
+	})
+}
+
+// eachShadow 依次对全部分片的全部影子缓存执行 fn，每次只持有一个分片的锁
+func (c *ComparingStore) eachShadow(fn func(shadow *Cache)) {
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		for _, shadow := range shard.caches {
+			fn(shadow)
+		}
+		shard.mu.Unlock()
+	}
+}
+
+// StartJanitor 启动后台清理协程；清理通过 DeleteExpired 进行，影子缓存一并清理
+func (c *ComparingStore) StartJanitor(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	c.StopJanitor()
+
+	stopCh := make(chan struct{})
+	c.mu.Lock()
+	c.stopCh = stopCh
+	c.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.DeleteExpired()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// StopJanitor 停止后台清理协程，同时停止被包装缓存自身的清理协程
+func (c *ComparingStore) StopJanitor() {
+	c.Store.StopJanitor()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopCh != nil {
+		close(c.stopCh)
+		c.stopCh = nil
+	}
+}
+
+// Policies 返回参与对比的策略名称
+func (c *ComparingStore) Policies() []string {
+	return append([]string(nil), c.names...)
+}
+
+// ShadowStats 返回各策略影子缓存的统计（各分片合计），key 为策略名称
+func (c *ComparingStore) ShadowStats() map[string]Stats {
+	stats := make(map[string]Stats, len(c.names))
+	for _, name := range c.names {
+		stats[name] = Stats{Policy: name, MaxBytes: c.maxBytes, Shards: len(c.shards)}
+	}
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		for i, shadow := range shard.caches {
+			name := c.names[i]
+			total, shardStats := stats[name], shadow.Stats()
+			total.Entries += shardStats.Entries
+			total.UsedBytes += shardStats.UsedBytes
+			total.Hits += shardStats.Hits
+			total.Misses += shardStats.Misses
+			total.Evictions += shardStats.Evictions
+			total.Expirations += shardStats.Expirations
+			stats[name] = total
+		}
+		shard.mu.Unlock()
+	}
+	return stats
+}
@@ -0,0 +1,127 @@
+package lru
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// lfuItem LFU 策略中的条目状态
+type lfuItem struct {
+	entry *Entry
+	freq  uint64 // 在缓存期间的访问次数
+	tick  uint64 // 最近一次访问的序号，频率相同时淘汰更久未访问的
+	index int    // 在堆中的下标
+}
+
+// lfuHeap 按 (freq, tick) 排序的小顶堆，堆顶为淘汰候选
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*lfuItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// lfuPolicy 最不经常使用：淘汰访问次数最少的条目，次数相同按最近访问时间
+// 频率只统计条目在缓存中的访问，被淘汰后重新加入时从1开始
+type lfuPolicy struct {
+	h    lfuHeap
+	tick uint64
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{}
+}
+
+func (p *lfuPolicy) Name() string {
+	return PolicyLFU
+}
+
+func (p *lfuPolicy) Add(e *Entry) {
+	p.tick++
+	item := &lfuItem{entry: e, freq: 1, tick: p.tick}
+	e.node = item
+	heap.Push(&p.h, item)
+}
+
+func (p *lfuPolicy) Access(e *Entry) {
+	item, ok := e.node.(*lfuItem)
+	if !ok {
+		return
+	}
+	p.tick++
+	item.freq++
+	item.tick = p.tick
+	heap.Fix(&p.h, item.index)
+}
+
+// Restored 按快照中的命中次数恢复访问频率，频率相同的条目保持恢复顺序
+func (p *lfuPolicy) Restored(e *Entry, hits uint64) {
+	item, ok := e.node.(*lfuItem)
+	if !ok {
+		return
+	}
+	item.freq = 1 + hits
+	heap.Fix(&p.h, item.index)
+}
+
+func (p *lfuPolicy) Miss(key string) {}
+
+func (p *lfuPolicy) Resize(e *Entry, delta int64) {}
+
+func (p *lfuPolicy) Remove(e *Entry) {
+	if item, ok := e.node.(*lfuItem); ok && item.index >= 0 {
+		heap.Remove(&p.h, item.index)
+	}
+	e.node = nil
+}
+
+func (p *lfuPolicy) Victim() *Entry {
+	if len(p.h) == 0 {
+		return nil
+	}
+	return p.h[0].entry
+}
+
+func (p *lfuPolicy) Entries() []*Entry {
+	items := make([]*lfuItem, len(p.h))
+	copy(items, p.h)
+	sort.Slice(items, func(i, j int) bool {
+		return p.h.Less(items[j].index, items[i].index)
+	})
+	entries := make([]*Entry, len(items))
+	for i, item := range items {
+		entries[i] = item.entry
+	}
+	return entries
+}
+
+func (p *lfuPolicy) Reset() {
+	p.h = nil
+	p.tick = 0
+}
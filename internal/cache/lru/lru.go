@@ -1,19 +1,73 @@
 package lru
 
 import (
-	"container/list"
 	"sync"
 	"time"
+	"unsafe"
 )
 
-// Cache LRU缓存结构
+// DefaultTTL 未调用 SetDefaultTTL 时条目的默认过期时间
+const DefaultTTL = time.Hour
+
+// Cache 缓存结构，淘汰顺序由 Policy 决定（默认LRU）
 type Cache struct {
-	mu        sync.RWMutex
-	maxBytes  int64
-	usedBytes int64
-	ll        *list.List
-	cache     map[string]*list.Element
-	OnEvicted func(key string, value Value)
+	mu         sync.RWMutex
+	maxBytes   int64
+	usedBytes  int64
+	policy     Policy
+	cache      map[string]*Entry
+	defaultTTL time.Duration // Add 使用的过期时间，0 表示永不过期
+	sliding    bool          // 滑动过期：每次命中都重新计算过期时间
+	stopCh     chan struct{} // 关闭后台清理协程
+	hits       int64
+	misses     int64
+	evictions  int64 // 因容量不足被淘汰的条目数
+	expired    int64 // 因过期被移除的条目数
+	OnEvicted  func(key string, value Value)
+}
+
+// Stats 缓存统计
+type Stats struct {
+	Policy      string `json:"policy"`
+	Entries     int    `json:"entries"`
+	UsedBytes   int64  `json:"used_bytes"`
+	MaxBytes    int64  `json:"max_bytes"`
+	Hits        int64  `json:"hits"`
+	Misses      int64  `json:"misses"`
+	Evictions   int64  `json:"evictions"`
+	Expirations int64  `json:"expirations"`
+	Shards      int    `json:"shards"`
+}
+
+// HitRate 返回命中率（百分比）
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total) * 100
+}
+
+// Store 缓存接口，Cache（单锁）与 ShardedCache（分片）均实现该接口
+type Store interface {
+	Get(key string) (Value, bool)
+	Add(key string, value Value)
+	AddWithTTL(key string, value Value, ttl time.Duration)
+	Remove(key string)
+	Resize(key string, delta int64)
+	Len() int
+	GetAll() []*Entry
+	Restore(entry *Entry) bool
+	Clear()
+	DeleteExpired() int
+	UsedBytes() int64
+	MaxBytes() int64
+	Stats() Stats
+	SetDefaultTTL(ttl time.Duration)
+	SetSlidingExpiration(enabled bool)
+	SlidingExpiration() bool
+	StartJanitor(interval time.Duration)
+	StopJanitor()
 }
 
 // Entry 缓存条目
@@ -21,7 +75,31 @@ type Entry struct {
 	Key        string
 	Value      Value
 	CreateAt   int64
-	ExpireTime int64 // 过期时间（秒）
+	AccessAt   int64       // 最近一次命中的时间，滑动过期以此为起点
+	ExpireTime int64       // 过期时间（秒），0 表示永不过期
+	Hits       uint64      // 在缓存期间被命中或覆盖写的次数，快照恢复时据此重建淘汰策略的状态
+	touched    int64       // 最近一次访问的纳秒时间戳，用于分片缓存合并各分片的访问顺序
+	size       int64       // 已计入 usedBytes 的字节数
+	gen        uint64      // 值被替换的次数，用于识别过期的大小上报回调
+	node       interface{} // 淘汰策略的内部数据
+}
+
+// ExpireAt 返回条目的过期时间点（Unix秒），sliding 表示是否按最近命中时间计算；永不过期时返回 0
+func (e *Entry) ExpireAt(sliding bool) int64 {
+	if e.ExpireTime <= 0 {
+		return 0
+	}
+	base := e.CreateAt
+	if sliding && e.AccessAt > base {
+		base = e.AccessAt
+	}
+	return base + e.ExpireTime
+}
+
+// expired 判断条目在 now 时是否已过期
+func (e *Entry) expired(now int64, sliding bool) bool {
+	expireAt := e.ExpireAt(sliding)
+	return expireAt > 0 && now > expireAt
 }
 
 // Value 缓存值接口
@@ -29,146 +107,375 @@ type Value interface {
 	Len() int
 }
 
+// ResizeNotifier 可选接口：值被原地修改后通过回调上报大小变化
+// 缓存在值加入时注册回调，值被移除或替换时以 nil 注销；回调内部会获取缓存锁，不能在 OnEvicted 等缓存回调中触发
+type ResizeNotifier interface {
+	Value
+	SetResizeHook(hook func(delta int64))
+}
+
+// entryOverhead 每个条目除 key 和值本身之外的固定开销：Entry 结构体、策略节点（约64字节）和 map 中的槽位
+const entryOverhead = int64(unsafe.Sizeof(Entry{})) + 64 +
+	int64(unsafe.Sizeof("")) + int64(unsafe.Sizeof(&Entry{})) + 8
+
 // NewCache 创建LRU缓存
 func NewCache(maxBytes int64, onEvicted func(string, Value)) *Cache {
+	return NewCacheWithPolicy(maxBytes, newLRUPolicy(), onEvicted)
+}
+
+// NewCacheWithPolicy 创建使用指定淘汰策略的缓存
+func NewCacheWithPolicy(maxBytes int64, policy Policy, onEvicted func(string, Value)) *Cache {
 	return &Cache{
-		maxBytes:  maxBytes,
-		ll:        list.New(),
-		cache:     make(map[string]*list.Element),
-		OnEvicted: onEvicted,
+		maxBytes:   maxBytes,
+		policy:     policy,
+		cache:      make(map[string]*Entry),
+		defaultTTL: DefaultTTL,
+		OnEvicted:  onEvicted,
+	}
+}
+
+// SetDefaultTTL 设置 Add 使用的默认过期时间，ttl<=0 表示永不过期；只影响之后写入的条目
+func (c *Cache) SetDefaultTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultTTL = ttl
+}
+
+// SetSlidingExpiration 开启或关闭滑动过期，开启后条目每次命中都会延长过期时间，热点数据可以常驻
+func (c *Cache) SetSlidingExpiration(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sliding = enabled
+}
+
+// ttlSeconds 将过期时间换算为秒，不足1秒按1秒计
+func ttlSeconds(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
 	}
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// entrySize 计算条目占用的字节数
+func entrySize(key string, value Value) int64 {
+	return entryOverhead + int64(len(key)) + int64(value.Len())
 }
 
 // Get 获取缓存值
 func (c *Cache) Get(key string) (Value, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	
-	if ele, ok := c.cache[key]; ok {
-		entry := ele.Value.(*Entry)
-		// 检查是否过期
-		if entry.ExpireTime > 0 && time.Now().Unix() > entry.CreateAt+entry.ExpireTime {
+
+	if entry, ok := c.cache[key]; ok {
+		// 过期的条目直接移除
+		t := time.Now()
+		now := t.Unix()
+		if entry.expired(now, c.sliding) {
+			c.removeEntry(entry)
+			c.expired++
+			c.misses++
+			c.policy.Miss(key)
 			return nil, false
 		}
-		c.ll.MoveToFront(ele)
+		entry.AccessAt = now
+		entry.touched = t.UnixNano()
+		entry.Hits++
+		c.policy.Access(entry)
+		c.hits++
 		return entry.Value, true
 	}
+	c.misses++
+	c.policy.Miss(key)
 	return nil, false
 }
 
-// Add 添加缓存值
+// Add 添加缓存值，使用缓存的默认过期时间
 func (c *Cache) Add(key string, value Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	
-	if ele, ok := c.cache[key]; ok {
+
+	c.add(key, value, c.defaultTTL)
+}
+
+// AddWithTTL 添加缓存值并指定过期时间，ttl<=0 表示永不过期
+func (c *Cache) AddWithTTL(key string, value Value, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(key, value, ttl)
+}
+
+// add 添加或更新条目，调用方需持有锁
+func (c *Cache) add(key string, value Value, ttl time.Duration) *Entry {
+	t := time.Now()
+	now := t.Unix()
+	entry, ok := c.cache[key]
+	if ok {
 		// 更新现有条目
-		c.ll.MoveToFront(ele)
-		entry := ele.Value.(*Entry)
-		oldSize := int64(len(entry.Key)) + int64(entry.Value.Len())
+		detachResizeHook(entry.Value)
 		entry.Value = value
-		entry.CreateAt = time.Now().Unix()
-		newSize := int64(len(key)) + int64(value.Len())
-		c.usedBytes += newSize - oldSize
+		entry.CreateAt = now
+		entry.AccessAt = now
+		entry.ExpireTime = ttlSeconds(ttl)
+		entry.touched = t.UnixNano()
+		entry.gen++
+		entry.Hits++
+		newSize := entrySize(key, value)
+		delta := newSize - entry.size
+		c.usedBytes += delta
+		entry.size = newSize
+		c.policy.Access(entry)
+		c.policy.Resize(entry, delta)
+		c.attachResizeHook(entry)
 	} else {
 		// 添加新条目
-		entry := &Entry{
+		entry = &Entry{
 			Key:        key,
 			Value:      value,
-			CreateAt:   time.Now().Unix(),
-			ExpireTime: 3600, // 默认1小时过期
+			CreateAt:   now,
+			AccessAt:   now,
+			ExpireTime: ttlSeconds(ttl),
+			touched:    t.UnixNano(),
+			size:       entrySize(key, value),
 		}
-		ele := c.ll.PushFront(entry)
-		c.cache[key] = ele
-		c.usedBytes += int64(len(key)) + int64(value.Len())
+		c.cache[key] = entry
+		c.usedBytes += entry.size
+		c.policy.Add(entry)
+		c.attachResizeHook(entry)
 	}
-	
-	// 清理过期数据
-	c.removeExpired()
-	
-	// 如果超过最大容量，移除最旧的数据
-	for c.maxBytes > 0 && c.usedBytes > c.maxBytes {
-		c.removeOldest()
+
+	// 如果超过最大容量，按淘汰策略移除数据
+	c.evict()
+	return entry
+}
+
+// Restore 按快照中的时间戳恢复条目（CreateAt、AccessAt、ExpireTime 保持原值），条目作为最近访问的数据加入，并按 Hits 重建淘汰策略的状态
+// 已过期的条目被忽略；按保留优先级从低到高依次恢复即可还原淘汰顺序。返回条目是否被恢复
+func (c *Cache) Restore(entry *Entry) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.expired(time.Now().Unix(), c.sliding) {
+		return false
 	}
+	restored := c.add(entry.Key, entry.Value, time.Duration(entry.ExpireTime)*time.Second)
+	if current, ok := c.cache[entry.Key]; !ok || current != restored {
+		return false // 条目大于缓存容量，加入后立即被淘汰
+	}
+	restored.CreateAt = entry.CreateAt
+	restored.AccessAt = entry.AccessAt
+	restorePolicyState(c.policy, restored, entry.Hits)
+	return true
 }
 
-// Remove 移除指定缓存
-func (c *Cache) Remove(key string) {
+// Resize 调整条目的已计入大小，用于值被原地修改后上报大小变化，必要时淘汰数据
+// 不改变条目的访问顺序；条目不存在时忽略
+func (c *Cache) Resize(key string, delta int64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	
-	if ele, ok := c.cache[key]; ok {
-		c.removeElement(ele)
+
+	c.resize(key, delta)
+}
+
+// resize 调整条目大小，调用方需持有锁
+func (c *Cache) resize(key string, delta int64) {
+	entry, ok := c.cache[key]
+	if !ok || delta == 0 {
+		return
 	}
+	entry.size += delta
+	c.usedBytes += delta
+	c.policy.Resize(entry, delta)
+	c.evict()
 }
 
-// removeOldest 移除最旧的缓存
-func (c *Cache) removeOldest() {
-	ele := c.ll.Back()
-	if ele != nil {
-		c.removeElement(ele)
+// attachResizeHook 为支持大小上报的值注册回调，调用方需持有锁
+func (c *Cache) attachResizeHook(entry *Entry) {
+	notifier, ok := entry.Value.(ResizeNotifier)
+	if !ok {
+		return
+	}
+	gen := entry.gen
+	notifier.SetResizeHook(func(delta int64) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// 条目可能已被移除或替换为新值，只接受当前值的上报
+		if current, ok := c.cache[entry.Key]; ok && current == entry && entry.gen == gen {
+			c.resize(entry.Key, delta)
+		}
+	})
+}
+
+// detachResizeHook 注销值的大小上报回调
+func detachResizeHook(value Value) {
+	if notifier, ok := value.(ResizeNotifier); ok {
+		notifier.SetResizeHook(nil)
+	}
+}
+
+// evict 超过最大容量时按淘汰策略移除数据，调用方需持有锁
+func (c *Cache) evict() {
+	for c.maxBytes > 0 && c.usedBytes > c.maxBytes && len(c.cache) > 0 {
+		victim := c.policy.Victim()
+		if victim == nil {
+			return
+		}
+		c.removeEntry(victim)
+		c.evictions++
 	}
 }
 
-// removeElement 移除指定元素
-func (c *Cache) removeElement(ele *list.Element) {
-	c.ll.Remove(ele)
-	entry := ele.Value.(*Entry)
+// Remove 移除指定缓存
+func (c *Cache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.cache[key]; ok {
+		c.removeEntry(entry)
+	}
+}
+
+// removeEntry 移除指定条目
+func (c *Cache) removeEntry(entry *Entry) {
+	c.policy.Remove(entry)
 	delete(c.cache, entry.Key)
-	c.usedBytes -= int64(len(entry.Key)) + int64(entry.Value.Len())
-	
+	c.usedBytes -= entry.size
+	detachResizeHook(entry.Value)
+
 	if c.OnEvicted != nil {
 		c.OnEvicted(entry.Key, entry.Value)
 	}
 }
 
-// removeExpired 移除过期数据
-func (c *Cache) removeExpired() {
+// DeleteExpired 遍历全部条目并移除已过期的数据（与条目在淘汰顺序中的位置无关），返回移除的数量
+func (c *Cache) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	now := time.Now().Unix()
-	for ele := c.ll.Back(); ele != nil; {
-		entry := ele.Value.(*Entry)
-		if entry.ExpireTime > 0 && now > entry.CreateAt+entry.ExpireTime {
-			prev := ele.Prev()
-			c.removeElement(ele)
-			ele = prev
-		} else {
-			break
+	removed := 0
+	for _, entry := range c.cache {
+		if entry.expired(now, c.sliding) {
+			c.removeEntry(entry)
+			removed++
 		}
 	}
+	c.expired += int64(removed)
+	return removed
+}
+
+// StartJanitor 启动后台清理协程，每隔 interval 清理一次过期数据；重复调用时先停止旧的协程
+func (c *Cache) StartJanitor(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	c.StopJanitor()
+
+	stopCh := make(chan struct{})
+	c.mu.Lock()
+	c.stopCh = stopCh
+	c.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.DeleteExpired()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// StopJanitor 停止后台清理协程
+func (c *Cache) StopJanitor() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopCh != nil {
+		close(c.stopCh)
+		c.stopCh = nil
+	}
 }
 
 // Len 返回缓存条目数量
 func (c *Cache) Len() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.ll.Len()
+	return len(c.cache)
+}
+
+// UsedBytes 返回当前已使用的字节数
+func (c *Cache) UsedBytes() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.usedBytes
+}
+
+// MaxBytes 返回最大容量（字节），0 表示不限制
+func (c *Cache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// Stats 返回缓存统计
+func (c *Cache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Stats{
+		Policy:      c.policy.Name(),
+		Entries:     len(c.cache),
+		UsedBytes:   c.usedBytes,
+		MaxBytes:    c.maxBytes,
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		Expirations: c.expired,
+		Shards:      1,
+	}
+}
+
+// SlidingExpiration 返回是否开启滑动过期
+func (c *Cache) SlidingExpiration() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sliding
 }
 
 // Clear 清空缓存
 func (c *Cache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	
-	c.ll.Init()
-	c.cache = make(map[string]*list.Element)
+
+	for _, entry := range c.cache {
+		detachResizeHook(entry.Value)
+	}
+	c.policy.Reset()
+	c.cache = make(map[string]*Entry)
 	c.usedBytes = 0
 }
 
-// GetAll 获取所有缓存条目（只读视图）
+// GetAll 获取所有缓存条目（只读视图），按淘汰策略的保留优先级从高到低排列（LRU 为最近使用在前）
 func (c *Cache) GetAll() []*Entry {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	
-	entries := make([]*Entry, 0, c.ll.Len())
-	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
-		entry := ele.Value.(*Entry)
+
+	all := c.policy.Entries()
+	entries := make([]*Entry, 0, len(all))
+	for _, entry := range all {
 		entries = append(entries, &Entry{
 			Key:        entry.Key,
 			Value:      entry.Value,
 			CreateAt:   entry.CreateAt,
+			AccessAt:   entry.AccessAt,
 			ExpireTime: entry.ExpireTime,
+			Hits:       entry.Hits,
+			touched:    entry.touched,
+			size:       entry.size,
 		})
 	}
 	return entries
 }
-
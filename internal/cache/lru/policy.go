@@ -0,0 +1,132 @@
+package lru
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+// 可选的淘汰策略名称
+const (
+	PolicyLRU      = "lru"
+	PolicyLFU      = "lfu"
+	PolicyWTinyLFU = "wtinylfu"
+	PolicyARC      = "arc"
+)
+
+// Policies 全部支持的淘汰策略
+var Policies = []string{PolicyLRU, PolicyLFU, PolicyWTinyLFU, PolicyARC}
+
+// Policy 淘汰策略，决定容量不足时移除哪个条目
+// 所有方法都在 Cache 持有锁时调用，实现无需自行加锁
+type Policy interface {
+	// Name 策略名称
+	Name() string
+	// Add 新条目加入缓存
+	Add(e *Entry)
+	// Access 条目被命中或被覆盖写
+	Access(e *Entry)
+	// Miss 查找未命中，频率类策略用来记录访问历史
+	Miss(key string)
+	// Resize 条目的已计入大小变化了 delta（e.size 已是新值）
+	Resize(e *Entry, delta int64)
+	// Remove 条目被移除（淘汰、过期或主动删除）
+	Remove(e *Entry)
+	// Victim 返回下一个应被淘汰的条目，没有条目时返回 nil；Cache 随后会调用 Remove
+	Victim() *Entry
+	// Entries 按保留优先级从高到低返回全部条目
+	Entries() []*Entry
+	// Reset 清空策略状态
+	Reset()
+}
+
+// maxReplayHits 恢复条目时最多重放的命中次数，超过后对各策略的状态不再有影响（W-TinyLFU 的频率计数上限为15）
+const maxReplayHits = 16
+
+// policyRestorer 可以直接按命中次数重建条目状态的淘汰策略
+type policyRestorer interface {
+	// Restored 条目刚通过 Add 恢复，hits 为保存快照时的命中次数
+	Restored(e *Entry, hits uint64)
+}
+
+// restorePolicyState 按快照中的命中次数重建刚恢复条目的策略状态（LFU 的频率、ARC 的 T2、W-TinyLFU 的访问频率）
+// 策略未实现 policyRestorer 时重放有限次数的 Access
+func restorePolicyState(p Policy, e *Entry, hits uint64) {
+	e.Hits = hits
+	if r, ok := p.(policyRestorer); ok {
+		r.Restored(e, hits)
+		return
+	}
+	for i := uint64(0); i < min(hits, maxReplayHits); i++ {
+		p.Access(e)
+	}
+}
+
+// NewPolicy 按名称创建淘汰策略，maxBytes 为该策略管理的缓存容量
+func NewPolicy(name string, maxBytes int64) (Policy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", PolicyLRU:
+		return newLRUPolicy(), nil
+	case PolicyLFU:
+		return newLFUPolicy(), nil
+	case PolicyWTinyLFU, "w-tinylfu", "tinylfu":
+		return newTinyLFUPolicy(maxBytes), nil
+	case PolicyARC:
+		return newARCPolicy(maxBytes), nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy: %s", name)
+	}
+}
+
+// lruPolicy 最近最少使用：链表头部为最近访问，淘汰尾部
+type lruPolicy struct {
+	ll *list.List
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{ll: list.New()}
+}
+
+func (p *lruPolicy) Name() string {
+	return PolicyLRU
+}
+
+func (p *lruPolicy) Add(e *Entry) {
+	e.node = p.ll.PushFront(e)
+}
+
+func (p *lruPolicy) Access(e *Entry) {
+	if ele, ok := e.node.(*list.Element); ok {
+		p.ll.MoveToFront(ele)
+	}
+}
+
+func (p *lruPolicy) Miss(key string) {}
+
+func (p *lruPolicy) Resize(e *Entry, delta int64) {}
+
+func (p *lruPolicy) Remove(e *Entry) {
+	if ele, ok := e.node.(*list.Element); ok {
+		p.ll.Remove(ele)
+	}
+	e.node = nil
+}
+
+func (p *lruPolicy) Victim() *Entry {
+	if ele := p.ll.Back(); ele != nil {
+		return ele.Value.(*Entry)
+	}
+	return nil
+}
+
+func (p *lruPolicy) Entries() []*Entry {
+	entries := make([]*Entry, 0, p.ll.Len())
+	for ele := p.ll.Front(); ele != nil; ele = ele.Next() {
+		entries = append(entries, ele.Value.(*Entry))
+	}
+	return entries
+}
+
+func (p *lruPolicy) Reset() {
+	p.ll.Init()
+}
@@ -0,0 +1,259 @@
+package lru
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultShards 默认分片数
+const DefaultShards = 16
+
+var (
+	_ Store = (*Cache)(nil)
+	_ Store = (*ShardedCache)(nil)
+)
+
+// ShardedCache 分片缓存：按 key 的哈希将条目分散到多个独立加锁的 Cache，
+// 不同分片上的读写互不阻塞。淘汰和容量限制在分片内进行，每个分片的容量为总容量的 1/N
+type ShardedCache struct {
+	shards   []*Cache
+	mask     uint32
+	maxBytes int64
+
+	mu     sync.Mutex
+	stopCh chan struct{} // 关闭后台清理协程
+}
+
+// New 创建缓存，shards<=1 时返回单锁的 Cache，否则返回分片缓存；policy 为淘汰策略名称，空串表示LRU
+func New(maxBytes int64, shards int, policy string, onEvicted func(string, Value)) (Store, error) {
+	if shards <= 1 {
+		p, err := NewPolicy(policy, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewCacheWithPolicy(maxBytes, p, onEvicted), nil
+	}
+	return NewShardedCacheWithPolicy(maxBytes, shards, policy, onEvicted)
+}
+
+// NewShardedCache 创建使用LRU淘汰的分片缓存，分片数向上取整为2的幂
+func NewShardedCache(maxBytes int64, shards int, onEvicted func(string, Value)) *ShardedCache {
+	c, _ := NewShardedCacheWithPolicy(maxBytes, shards, PolicyLRU, onEvicted)
+	return c
+}
+
+// NewShardedCacheWithPolicy 创建分片缓存，每个分片使用独立的淘汰策略实例
+func NewShardedCacheWithPolicy(maxBytes int64, shards int, policy string, onEvicted func(string, Value)) (*ShardedCache, error) {
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+
+	var shardBytes int64
+	if maxBytes > 0 {
+		shardBytes = (maxBytes + int64(n) - 1) / int64(n)
+	}
+
+	c := &ShardedCache{
+		shards:   make([]*Cache, n),
+		mask:     uint32(n - 1),
+		maxBytes: maxBytes,
+	}
+	for i := range c.shards {
+		p, err := NewPolicy(policy, shardBytes)
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = NewCacheWithPolicy(shardBytes, p, onEvicted)
+	}
+	return c, nil
+}
+
+// shard 返回 key 所在的分片
+func (c *ShardedCache) shard(key string) *Cache {
+	return c.shards[keyHash(key)&c.mask]
+}
+
+// keyHash 计算 key 的 FNV-1a 哈希，用于选择分片
+func keyHash(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// Get 获取缓存值
+func (c *ShardedCache) Get(key string) (Value, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Add 添加缓存值，使用缓存的默认过期时间
+func (c *ShardedCache) Add(key string, value Value) {
+	c.shard(key).Add(key, value)
+}
+
+// AddWithTTL 添加缓存值并指定过期时间，ttl<=0 表示永不过期
+func (c *ShardedCache) AddWithTTL(key string, value Value, ttl time.Duration) {
+	c.shard(key).AddWithTTL(key, value, ttl)
+}
+
+// Remove 移除指定缓存
+func (c *ShardedCache) Remove(key string) {
+	c.shard(key).Remove(key)
+}
+
+// Resize 调整条目的已计入大小
+func (c *ShardedCache) Resize(key string, delta int64) {
+	c.shard(key).Resize(key, delta)
+}
+
+// Len 返回所有分片的条目数量之和
+func (c *ShardedCache) Len() int {
+	total := 0
+	for _, shard := range c.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+// GetAll 获取所有缓存条目，按最近访问时间从新到旧归并各分片，近似全局的LRU顺序
+// 归并时每个分片内部保持淘汰策略的保留优先级顺序，按顺序恢复即可还原各分片的淘汰状态
+func (c *ShardedCache) GetAll() []*Entry {
+	lists := make([][]*Entry, len(c.shards))
+	total := 0
+	for i, shard := range c.shards {
+		lists[i] = shard.GetAll()
+		total += len(lists[i])
+	}
+
+	entries := make([]*Entry, 0, total)
+	for len(entries) < total {
+		next := -1
+		for i, l := range lists {
+			if len(l) > 0 && (next < 0 || l[0].touched > lists[next][0].touched) {
+				next = i
+			}
+		}
+		entries = append(entries, lists[next][0])
+		lists[next] = lists[next][1:]
+	}
+	return entries
+}
+
+// Restore 按快照中的时间戳恢复条目
+func (c *ShardedCache) Restore(entry *Entry) bool {
+	return c.shard(entry.Key).Restore(entry)
+}
+
+// Clear 清空所有分片
+func (c *ShardedCache) Clear() {
+	for _, shard := range c.shards {
+		shard.Clear()
+	}
+}
+
+// DeleteExpired 清理所有分片的过期数据，返回移除的数量
+func (c *ShardedCache) DeleteExpired() int {
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.DeleteExpired()
+	}
+	return removed
+}
+
+// UsedBytes 返回所有分片已使用的字节数之和
+func (c *ShardedCache) UsedBytes() int64 {
+	var total int64
+	for _, shard := range c.shards {
+		total += shard.UsedBytes()
+	}
+	return total
+}
+
+// MaxBytes 返回总容量（字节），0 表示不限制
+func (c *ShardedCache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// Stats 返回汇总后的缓存统计
+func (c *ShardedCache) Stats() Stats {
+	total := Stats{Policy: c.shards[0].policy.Name(), MaxBytes: c.maxBytes, Shards: len(c.shards)}
+	for _, shard := range c.shards {
+		stats := shard.Stats()
+		total.Entries += stats.Entries
+		total.UsedBytes += stats.UsedBytes
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Expirations += stats.Expirations
+	}
+	return total
+}
+
+// ShardStats 返回各分片的统计，用于观察分片是否均衡
+func (c *ShardedCache) ShardStats() []Stats {
+	stats := make([]Stats, len(c.shards))
+	for i, shard := range c.shards {
+		stats[i] = shard.Stats()
+	}
+	return stats
+}
+
+// SetDefaultTTL 设置所有分片的默认过期时间
+func (c *ShardedCache) SetDefaultTTL(ttl time.Duration) {
+	for _, shard := range c.shards {
+		shard.SetDefaultTTL(ttl)
+	}
+}
+
+// SetSlidingExpiration 开启或关闭所有分片的滑动过期
+func (c *ShardedCache) SetSlidingExpiration(enabled bool) {
+	for _, shard := range c.shards {
+		shard.SetSlidingExpiration(enabled)
+	}
+}
+
+// SlidingExpiration 返回是否开启滑动过期
+func (c *ShardedCache) SlidingExpiration() bool {
+	return c.shards[0].SlidingExpiration()
+}
+
+// StartJanitor 启动一个后台清理协程，依次清理各分片的过期数据
+func (c *ShardedCache) StartJanitor(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	c.StopJanitor()
+
+	stopCh := make(chan struct{})
+	c.mu.Lock()
+	c.stopCh = stopCh
+	c.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.DeleteExpired()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// StopJanitor 停止后台清理协程
+func (c *ShardedCache) StopJanitor() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopCh != nil {
+		close(c.stopCh)
+		c.stopCh = nil
+	}
+}
@@ -0,0 +1,314 @@
+package lru
+
+import (
+	"container/list"
+	"hash/fnv"
+)
+
+// W-TinyLFU 各区域的容量比例
+const (
+	tinyLFUWindowRatio    = 0.01 // 窗口区占总容量的比例
+	tinyLFUProtectedRatio = 0.80 // 保护区占主区的比例
+	// tinyLFUAvgEntryBytes 估算条目数量时假定的平均条目大小，用于确定频率草图的宽度
+	tinyLFUAvgEntryBytes = 4096
+)
+
+// 条目所在的区域
+const (
+	segWindow = iota
+	segProbation
+	segProtected
+)
+
+// tinyLFUNode W-TinyLFU 策略中的条目状态
+type tinyLFUNode struct {
+	entry *Entry
+	seg   int
+	ele   *list.Element
+}
+
+// tinyLFUPolicy W-TinyLFU 淘汰策略
+// 新条目先进入容量约1%的LRU窗口区，从窗口区被挤出时与主区的淘汰候选比较历史访问频率，
+// 频率更高者留下；主区为分段LRU（试用区+保护区），试用区条目再次命中后晋升到保护区。
+// 访问频率由 count-min 草图记录，包含未命中的请求，并定期减半以适应热点变化，
+// 因此一次性的批量扫描不会冲掉反复访问的热点数据。
+type tinyLFUPolicy struct {
+	maxBytes     int64
+	windowMax    int64
+	protectedMax int64
+
+	window    *list.List
+	probation *list.List
+	protected *list.List
+
+	windowBytes    int64
+	probationBytes int64
+	protectedBytes int64
+
+	sketch *countMinSketch
+}
+
+func newTinyLFUPolicy(maxBytes int64) *tinyLFUPolicy {
+	windowMax := int64(float64(maxBytes) * tinyLFUWindowRatio)
+	if windowMax < 1 {
+		windowMax = 1
+	}
+	mainMax := maxBytes - windowMax
+	if mainMax < 0 {
+		mainMax = 0
+	}
+	return &tinyLFUPolicy{
+		maxBytes:     maxBytes,
+		windowMax:    windowMax,
+		protectedMax: int64(float64(mainMax) * tinyLFUProtectedRatio),
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		sketch:       newCountMinSketch(maxBytes / tinyLFUAvgEntryBytes),
+	}
+}
+
+func (p *tinyLFUPolicy) Name() string {
+	return PolicyWTinyLFU
+}
+
+func (p *tinyLFUPolicy) Add(e *Entry) {
+	node := &tinyLFUNode{entry: e, seg: segWindow}
+	node.ele = p.window.PushFront(node)
+	p.windowBytes += e.size
+	e.node = node
+}
+
+func (p *tinyLFUPolicy) Access(e *Entry) {
+	p.sketch.Increment(e.Key)
+
+	node, ok := e.node.(*tinyLFUNode)
+	if !ok {
+		return
+	}
+	switch node.seg {
+	case segWindow:
+		p.window.MoveToFront(node.ele)
+	case segProtected:
+		p.protected.MoveToFront(node.ele)
+	case segProbation:
+		// 试用区条目再次命中，晋升到保护区
+		p.probation.Remove(node.ele)
+		p.probationBytes -= e.size
+		node.seg = segProtected
+		node.ele = p.protected.PushFront(node)
+		p.protectedBytes += e.size
+		p.demoteProtected()
+	}
+}
+
+// demoteProtected 保护区超出容量时，把最久未访问的条目降级回试用区
+func (p *tinyLFUPolicy) demoteProtected() {
+	for p.protectedBytes > p.protectedMax && p.protected.Len() > 1 {
+		node := p.protected.Back().Value.(*tinyLFUNode)
+		p.protected.Remove(node.ele)
+		p.protectedBytes -= node.entry.size
+		node.seg = segProbation
+		node.ele = p.probation.PushFront(node)
+		p.probationBytes += node.entry.size
+	}
+}
+
+func (p *tinyLFUPolicy) Miss(key string) {
+	p.sketch.Increment(key)
+}
+
+func (p *tinyLFUPolicy) Resize(e *Entry, delta int64) {
+	node, ok := e.node.(*tinyLFUNode)
+	if !ok {
+		return
+	}
+	switch node.seg {
+	case segWindow:
+		p.windowBytes += delta
+	case segProbation:
+		p.probationBytes += delta
+	case segProtected:
+		p.protectedBytes += delta
+		p.demoteProtected()
+	}
+}
+
+func (p *tinyLFUPolicy) Remove(e *Entry) {
+	node, ok := e.node.(*tinyLFUNode)
+	if !ok {
+		return
+	}
+	switch node.seg {
+	case segWindow:
+		p.window.Remove(node.ele)
+		p.windowBytes -= e.size
+	case segProbation:
+		p.probation.Remove(node.ele)
+		p.probationBytes -= e.size
+	case segProtected:
+		p.protected.Remove(node.ele)
+		p.protectedBytes -= e.size
+	}
+	e.node = nil
+}
+
+// mainVictim 主区的淘汰候选：优先试用区尾部，其次保护区尾部
+func (p *tinyLFUPolicy) mainVictim() *Entry {
+	if ele := p.probation.Back(); ele != nil {
+		return ele.Value.(*tinyLFUNode).entry
+	}
+	if ele := p.protected.Back(); ele != nil {
+		return ele.Value.(*tinyLFUNode).entry
+	}
+	return nil
+}
+
+func (p *tinyLFUPolicy) Victim() *Entry {
+	for p.windowBytes > p.windowMax && p.window.Len() > 0 {
+		node := p.window.Back().Value.(*tinyLFUNode)
+		candidate := node.entry
+
+		// 主区还有空间时直接转入试用区
+		mainBytes := p.probationBytes + p.protectedBytes
+		if mainBytes+candidate.size <= p.maxBytes-p.windowMax {
+			p.window.Remove(node.ele)
+			p.windowBytes -= candidate.size
+			node.seg = segProbation
+			node.ele = p.probation.PushFront(node)
+			p.probationBytes += candidate.size
+			continue
+		}
+
+		// 准入过滤：窗口区挤出的条目与主区候选比较访问频率，频率低者被淘汰
+		victim := p.mainVictim()
+		if victim == nil {
+			return candidate
+		}
+		if p.sketch.Estimate(candidate.Key) > p.sketch.Estimate(victim.Key) {
+			return victim
+		}
+		return candidate
+	}
+
+	if victim := p.mainVictim(); victim != nil {
+		return victim
+	}
+	if ele := p.window.Back(); ele != nil {
+		return ele.Value.(*tinyLFUNode).entry
+	}
+	return nil
+}
+
+func (p *tinyLFUPolicy) Entries() []*Entry {
+	entries := make([]*Entry, 0, p.protected.Len()+p.window.Len()+p.probation.Len())
+	for _, l := range []*list.List{p.protected, p.window, p.probation} {
+		for ele := l.Front(); ele != nil; ele = ele.Next() {
+			entries = append(entries, ele.Value.(*tinyLFUNode).entry)
+		}
+	}
+	return entries
+}
+
+func (p *tinyLFUPolicy) Reset() {
+	p.window.Init()
+	p.probation.Init()
+	p.protected.Init()
+	p.windowBytes = 0
+	p.probationBytes = 0
+	p.protectedBytes = 0
+	p.sketch.Clear()
+}
+
+// count-min 草图参数
+const (
+	sketchDepth      = 4
+	sketchMinWidth   = 1 << 10
+	sketchMaxWidth   = 1 << 16
+	sketchMaxCounter = 15
+	// sketchResetFactor 记录的访问次数达到宽度的该倍数后，全部计数减半
+	sketchResetFactor = 10
+)
+
+// countMinSketch 近似记录 key 的访问频率，每行一个4位计数器数组（用 uint8 存储）
+type countMinSketch struct {
+	rows    [sketchDepth][]uint8
+	mask    uint64
+	samples int
+	limit   int
+}
+
+// newCountMinSketch 按预期条目数创建草图，宽度取不小于条目数的2的幂
+func newCountMinSketch(expected int64) *countMinSketch {
+	width := sketchMinWidth
+	for int64(width) < expected && width < sketchMaxWidth {
+		width <<= 1
+	}
+	s := &countMinSketch{
+		mask:  uint64(width - 1),
+		limit: width * sketchResetFactor,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// indexes 用双重哈希计算 key 在各行中的位置
+func (s *countMinSketch) indexes(key string) [sketchDepth]uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum, (sum>>32)|1
+	var idx [sketchDepth]uint64
+	for i := range idx {
+		idx[i] = (h1 + uint64(i)*h2) & s.mask
+	}
+	return idx
+}
+
+// Increment 记录一次访问
+func (s *countMinSketch) Increment(key string) {
+	idx := s.indexes(key)
+	for i, j := range idx {
+		if s.rows[i][j] < sketchMaxCounter {
+			s.rows[i][j]++
+		}
+	}
+	s.samples++
+	if s.samples >= s.limit {
+		s.reset()
+	}
+}
+
+// Estimate 返回 key 的近似访问频率
+func (s *countMinSketch) Estimate(key string) uint8 {
+	idx := s.indexes(key)
+	min := uint8(sketchMaxCounter)
+	for i, j := range idx {
+		if s.rows[i][j] < min {
+			min = s.rows[i][j]
+		}
+	}
+	return min
+}
+
+// reset 全部计数减半，让旧的热点逐渐失效
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.samples /= 2
+}
+
+// Clear 清空全部计数
+func (s *countMinSketch) Clear() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = 0
+		}
+	}
+	s.samples = 0
+}
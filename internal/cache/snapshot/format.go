@@ -0,0 +1,484 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// 快照文件格式（版本4）：
+//
+//	文件头   magic "KSNP" | version uint16 | compression uint8 | reserved uint8 | created_at int64 | crc uint32
+//	数据块   stored_len uint32 | raw_len uint32 | codec uint8 | crc uint32 | payload[stored_len]
+//	结束块   0 | 0 | 0xFF | crc uint32 | entry_count uint64
+//
+// 整数均为小端序，crc 为 CRC-32C，覆盖块头的前9个字节和 payload（结束块为 entry_count）。
+// payload 解压后是若干条连续的记录，记录不跨块：
+//
+//	key、type 为 uvarint 长度前缀的字符串，type_version 为 uvarint，
+//	create_at、access_at、expire_time 为 varint，hits 为 uvarint，value 为 uvarint 长度前缀的字节串
+//
+// 记录按保留优先级从低到高写入（分片缓存的每个分片内保持该顺序），顺序读取并依次恢复即可还原淘汰顺序；
+// hits 为条目的命中次数，恢复时用来重建 LFU、ARC、W-TinyLFU 的频率状态。版本3的记录没有 hits，仍可读取。
+const (
+	fileMagic = "KSNP"
+	// FormatVersion 快照文件格式版本（版本1为 lru.Entry 的 JSON 数组，版本2为整体序列化的 JSON 文档）
+	FormatVersion = 4
+	// minFormatVersion 仍可读取的最早版本（版本3的记录没有 hits）
+	minFormatVersion = 3
+
+	// DefaultBlockSize 数据块解压后的目标大小
+	DefaultBlockSize = 1 << 20
+	// maxBlockSize 单个数据块的上限，防止损坏的长度字段导致过大的内存分配
+	maxBlockSize = 256 << 20
+
+	fileHeaderSize  = 20
+	blockHeaderSize = 13
+	endBlockCodec   = 0xFF
+)
+
+// ErrCorrupted 快照文件损坏（校验和不匹配、长度异常或被截断）
+var ErrCorrupted = errors.New("snapshot file is corrupted")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Compression 数据块压缩算法
+type Compression uint8
+
+const (
+	CompressionNone Compression = iota
+	CompressionZstd
+	CompressionLZ4
+)
+
+// ParseCompression 解析压缩算法名称：none/zstd/lz4，空串表示不压缩
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "none":
+		return CompressionNone, nil
+	case "zstd":
+		return CompressionZstd, nil
+	case "lz4":
+		return CompressionLZ4, nil
+	default:
+		return CompressionNone, fmt.Errorf("unknown snapshot compression: %s", name)
+	}
+}
+
+// String 返回压缩算法名称
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionZstd:
+		return "zstd"
+	case CompressionLZ4:
+		return "lz4"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+// record 快照中的一个缓存条目
+type record struct {
+	Key         string
+	Type        string
+	TypeVersion int
+	CreateAt    int64
+	AccessAt    int64
+	ExpireTime  int64
+	Hits        uint64
+	Value       []byte
+}
+
+// fileHeader 快照文件头
+type fileHeader struct {
+	Version     uint16
+	Compression Compression
+	CreatedAt   int64
+}
+
+// blockWriter 按块写入快照：记录先追加到内存中的当前块，达到块大小后压缩、计算校验和并写出
+type blockWriter struct {
+	w           *bufio.Writer
+	compression Compression
+	blockSize   int
+	buf         []byte // 当前块（未压缩）
+	out         []byte // 压缩输出缓冲
+	zenc        *zstd.Encoder
+	count       uint64
+}
+
+// newBlockWriter 创建写入器并写出文件头
+func newBlockWriter(w io.Writer, compression Compression, blockSize int, createdAt int64) (*blockWriter, error) {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	bw := &blockWriter{
+		w:           bufio.NewWriterSize(w, 64*1024),
+		compression: compression,
+		blockSize:   blockSize,
+		buf:         make([]byte, 0, blockSize),
+	}
+	if compression == CompressionZstd {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			return nil, err
+		}
+		bw.zenc = enc
+	}
+
+	header := make([]byte, fileHeaderSize)
+	copy(header, fileMagic)
+	binary.LittleEndian.PutUint16(header[4:], FormatVersion)
+	header[6] = byte(compression)
+	binary.LittleEndian.PutUint64(header[8:], uint64(createdAt))
+	binary.LittleEndian.PutUint32(header[16:], crc32.Checksum(header[:16], crcTable))
+	if _, err := bw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+// Write 追加一条记录
+func (bw *blockWriter) Write(rec *record) error {
+	bw.buf = appendString(bw.buf, rec.Key)
+	bw.buf = appendString(bw.buf, rec.Type)
+	bw.buf = binary.AppendUvarint(bw.buf, uint64(rec.TypeVersion))
+	bw.buf = binary.AppendVarint(bw.buf, rec.CreateAt)
+	bw.buf = binary.AppendVarint(bw.buf, rec.AccessAt)
+	bw.buf = binary.AppendVarint(bw.buf, rec.ExpireTime)
+	bw.buf = binary.AppendUvarint(bw.buf, rec.Hits)
+	bw.buf = binary.AppendUvarint(bw.buf, uint64(len(rec.Value)))
+	bw.buf = append(bw.buf, rec.Value...)
+	bw.count++
+
+	if len(bw.buf) >= bw.blockSize {
+		return bw.flush()
+	}
+	return nil
+}
+
+// flush 压缩并写出当前块
+func (bw *blockWriter) flush() error {
+	if len(bw.buf) == 0 {
+		return nil
+	}
+	if len(bw.buf) > maxBlockSize {
+		return fmt.Errorf("snapshot block of %d bytes exceeds the limit of %d bytes", len(bw.buf), maxBlockSize)
+	}
+
+	codec := bw.compression
+	payload := bw.buf
+	switch codec {
+	case CompressionZstd:
+		bw.out = bw.zenc.EncodeAll(bw.buf, bw.out[:0])
+		payload = bw.out
+	case CompressionLZ4:
+		if bound := lz4.CompressBlockBound(len(bw.buf)); cap(bw.out) < bound {
+			bw.out = make([]byte, bound)
+		}
+		n, err := lz4.CompressBlock(bw.buf, bw.out[:cap(bw.out)], nil)
+		if err != nil {
+			return err
+		}
+		payload = bw.out[:n]
+	}
+	// 不可压缩的数据原样保存
+	if codec != CompressionNone && (len(payload) == 0 || len(payload) >= len(bw.buf)) {
+		codec = CompressionNone
+		payload = bw.buf
+	}
+
+	header := make([]byte, blockHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(bw.buf)))
+	header[8] = byte(codec)
+	crc := crc32.Update(crc32.Checksum(header[:9], crcTable), crcTable, payload)
+	binary.LittleEndian.PutUint32(header[9:], crc)
+	if _, err := bw.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := bw.w.Write(payload); err != nil {
+		return err
+	}
+	bw.buf = bw.buf[:0]
+	return nil
+}
+
+// Close 写出剩余数据和结束块，不关闭底层的 io.Writer
+func (bw *blockWriter) Close() error {
+	if bw.zenc != nil {
+		defer bw.zenc.Close()
+	}
+	if err := bw.flush(); err != nil {
+		return err
+	}
+
+	end := make([]byte, blockHeaderSize+8)
+	end[8] = endBlockCodec
+	binary.LittleEndian.PutUint64(end[blockHeaderSize:], bw.count)
+	crc := crc32.Update(crc32.Checksum(end[:9], crcTable), crcTable, end[blockHeaderSize:])
+	binary.LittleEndian.PutUint32(end[9:], crc)
+	if _, err := bw.w.Write(end); err != nil {
+		return err
+	}
+	return bw.w.Flush()
+}
+
+// blockReader 按块读取快照，每个块读入后先校验再解压
+type blockReader struct {
+	r      *bufio.Reader
+	header fileHeader
+	block  []byte // 当前块（已解压）
+	pos    int
+	raw    []byte // 读取缓冲
+	zdec   *zstd.Decoder
+	count  uint64
+	done   bool
+}
+
+// newBlockReader 创建读取器并校验文件头
+func newBlockReader(r io.Reader) (*blockReader, error) {
+	br := &blockReader{r: bufio.NewReaderSize(r, 64*1024)}
+
+	header := make([]byte, fileHeaderSize)
+	n, err := io.ReadFull(br.r, header)
+	// 早期版本的快照是 JSON，文件可能比文件头还短
+	if n > 0 && string(header[:min(n, 4)]) != fileMagic[:min(n, 4)] {
+		switch header[0] {
+		case '[':
+			return nil, fmt.Errorf("unsupported snapshot format version 1 (untyped JSON entries)")
+		case '{':
+			return nil, fmt.Errorf("unsupported snapshot format version 2 (JSON document)")
+		}
+		return nil, fmt.Errorf("%w: bad magic", ErrCorrupted)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: short file header: %v", ErrCorrupted, err)
+	}
+	if crc32.Checksum(header[:16], crcTable) != binary.LittleEndian.Uint32(header[16:]) {
+		return nil, fmt.Errorf("%w: file header checksum mismatch", ErrCorrupted)
+	}
+	br.header = fileHeader{
+		Version:     binary.LittleEndian.Uint16(header[4:]),
+		Compression: Compression(header[6]),
+		CreatedAt:   int64(binary.LittleEndian.Uint64(header[8:])),
+	}
+	if br.header.Version < minFormatVersion || br.header.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d (expected %d to %d)", br.header.Version, minFormatVersion, FormatVersion)
+	}
+	return br, nil
+}
+
+// Close 释放解压器
+func (br *blockReader) Close() {
+	if br.zdec != nil {
+		br.zdec.Close()
+	}
+}
+
+// Next 读取下一条记录；全部读完且结束块校验通过后返回 io.EOF
+// 返回的 record.Value 引用内部缓冲，在下一次调用 Next 之前有效
+func (br *blockReader) Next() (*record, error) {
+	for br.pos >= len(br.block) {
+		if br.done {
+			return nil, io.EOF
+		}
+		if err := br.readBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	rec := &record{}
+	var err error
+	if rec.Key, err = br.readString(); err != nil {
+		return nil, err
+	}
+	if rec.Type, err = br.readString(); err != nil {
+		return nil, err
+	}
+	typeVersion, err := br.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	rec.TypeVersion = int(typeVersion)
+	if rec.CreateAt, err = br.readVarint(); err != nil {
+		return nil, err
+	}
+	if rec.AccessAt, err = br.readVarint(); err != nil {
+		return nil, err
+	}
+	if rec.ExpireTime, err = br.readVarint(); err != nil {
+		return nil, err
+	}
+	if br.header.Version >= 4 {
+		if rec.Hits, err = br.readUvarint(); err != nil {
+			return nil, err
+		}
+	}
+	if rec.Value, err = br.readBytes(); err != nil {
+		return nil, err
+	}
+	br.count++
+	return rec, nil
+}
+
+// readBlock 读取、校验并解压下一个块
+func (br *blockReader) readBlock() error {
+	header := make([]byte, blockHeaderSize)
+	if _, err := io.ReadFull(br.r, header); err != nil {
+		return fmt.Errorf("%w: truncated before end block: %v", ErrCorrupted, err)
+	}
+	storedLen := binary.LittleEndian.Uint32(header[0:])
+	rawLen := binary.LittleEndian.Uint32(header[4:])
+	codec := header[8]
+	crc := binary.LittleEndian.Uint32(header[9:])
+
+	if codec == endBlockCodec {
+		tail := make([]byte, 8)
+		if _, err := io.ReadFull(br.r, tail); err != nil {
+			return fmt.Errorf("%w: truncated end block: %v", ErrCorrupted, err)
+		}
+		if crc32.Update(crc32.Checksum(header[:9], crcTable), crcTable, tail) != crc {
+			return fmt.Errorf("%w: end block checksum mismatch", ErrCorrupted)
+		}
+		if count := binary.LittleEndian.Uint64(tail); count != br.count {
+			return fmt.Errorf("%w: expected %d entries, read %d", ErrCorrupted, count, br.count)
+		}
+		br.done = true
+		br.block = br.block[:0]
+		br.pos = 0
+		return nil
+	}
+
+	if storedLen > maxBlockSize || rawLen > maxBlockSize {
+		return fmt.Errorf("%w: block length %d/%d exceeds the limit", ErrCorrupted, storedLen, rawLen)
+	}
+	if cap(br.raw) < int(storedLen) {
+		br.raw = make([]byte, storedLen)
+	}
+	payload := br.raw[:storedLen]
+	if _, err := io.ReadFull(br.r, payload); err != nil {
+		return fmt.Errorf("%w: truncated block: %v", ErrCorrupted, err)
+	}
+	if crc32.Update(crc32.Checksum(header[:9], crcTable), crcTable, payload) != crc {
+		return fmt.Errorf("%w: block checksum mismatch", ErrCorrupted)
+	}
+
+	switch Compression(codec) {
+	case CompressionNone:
+		br.block = append(br.block[:0], payload...)
+	case CompressionZstd:
+		if br.zdec == nil {
+			dec, err := zstd.NewReader(nil)
+			if err != nil {
+				return err
+			}
+			br.zdec = dec
+		}
+		block, err := br.zdec.DecodeAll(payload, br.block[:0])
+		if err != nil {
+			return fmt.Errorf("%w: zstd: %v", ErrCorrupted, err)
+		}
+		br.block = block
+	case CompressionLZ4:
+		if cap(br.block) < int(rawLen) {
+			br.block = make([]byte, rawLen)
+		}
+		n, err := lz4.UncompressBlock(payload, br.block[:rawLen])
+		if err != nil {
+			return fmt.Errorf("%w: lz4: %v", ErrCorrupted, err)
+		}
+		br.block = br.block[:n]
+	default:
+		return fmt.Errorf("%w: unknown block codec %d", ErrCorrupted, codec)
+	}
+	if len(br.block) != int(rawLen) {
+		return fmt.Errorf("%w: block length %d, expected %d", ErrCorrupted, len(br.block), rawLen)
+	}
+	br.pos = 0
+	return nil
+}
+
+func (br *blockReader) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(br.block[br.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: bad varint", ErrCorrupted)
+	}
+	br.pos += n
+	return v, nil
+}
+
+func (br *blockReader) readVarint() (int64, error) {
+	v, n := binary.Varint(br.block[br.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: bad varint", ErrCorrupted)
+	}
+	br.pos += n
+	return v, nil
+}
+
+func (br *blockReader) readBytes() ([]byte, error) {
+	length, err := br.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(br.block)-br.pos) {
+		return nil, fmt.Errorf("%w: record field overruns block", ErrCorrupted)
+	}
+	b := br.block[br.pos : br.pos+int(length)]
+	br.pos += int(length)
+	return b, nil
+}
+
+func (br *blockReader) readString() (string, error) {
+	b, err := br.readBytes()
+	return string(b), err
+}
+
+// readSummary 只读取文件头和结束块，返回文件头和条目总数，不校验数据块
+func readSummary(path string) (*fileHeader, uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	r, err := newBlockReader(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	end := make([]byte, blockHeaderSize+8)
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if stat.Size() < fileHeaderSize+int64(len(end)) {
+		return &r.header, 0, fmt.Errorf("%w: missing end block", ErrCorrupted)
+	}
+	if _, err := f.ReadAt(end, stat.Size()-int64(len(end))); err != nil {
+		return &r.header, 0, err
+	}
+	crc := crc32.Update(crc32.Checksum(end[:9], crcTable), crcTable, end[blockHeaderSize:])
+	if end[8] != endBlockCodec || binary.LittleEndian.Uint32(end[9:]) != crc {
+		return &r.header, 0, fmt.Errorf("%w: missing end block", ErrCorrupted)
+	}
+	return &r.header, binary.LittleEndian.Uint64(end[blockHeaderSize:]), nil
+}
+
+// appendString 追加 uvarint 长度前缀的字符串
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
@@ -0,0 +1,186 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// 快照文件格式的校验测试：对一份正常写出的文件做各种破坏（改写字节、截断），
+// 读取时必须返回 ErrCorrupted，而不是读出部分记录或把坏数据当作正常数据。
+
+const testRecords = 40
+
+// encodeRecords 以很小的块大小写出 testRecords 条记录，使文件包含多个数据块
+func encodeRecords(t *testing.T, compression Compression) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := newBlockWriter(&buf, compression, 128, 1700000000)
+	if err != nil {
+		t.Fatalf("create writer: %v", err)
+	}
+	for i := 0; i < testRecords; i++ {
+		err := w.Write(&record{
+			Key:         fmt.Sprintf("key-%03d", i),
+			Type:        "test",
+			TypeVersion: 1,
+			CreateAt:    int64(1700000000 + i),
+			AccessAt:    int64(1700000100 + i),
+			Hits:        uint64(i),
+			Value:       bytes.Repeat([]byte{byte('a' + i%26)}, 16),
+		})
+		if err != nil {
+			t.Fatalf("write record %d: %v", i, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// decodeRecords 顺序读完文件，返回读到的记录数
+func decodeRecords(data []byte) (int, error) {
+	r, err := newBlockReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	count := 0
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if want := fmt.Sprintf("key-%03d", count); rec.Key != want || rec.Hits != uint64(count) {
+			return count, fmt.Errorf("record %d is %s (hits %d), want %s (hits %d)", count, rec.Key, rec.Hits, want, count)
+		}
+		count++
+	}
+}
+
+func TestBlockFormatCorruption(t *testing.T) {
+	endBlockSize := blockHeaderSize + 8
+
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+		wantErr bool
+	}{
+		{
+			name:    "intact",
+			corrupt: func(data []byte) []byte { return data },
+		},
+		{
+			name: "file header checksum mismatch",
+			corrupt: func(data []byte) []byte {
+				data[8] ^= 0xFF
+				return data
+			},
+			wantErr: true,
+		},
+		{
+			name: "block checksum mismatch",
+			corrupt: func(data []byte) []byte {
+				data[fileHeaderSize+blockHeaderSize] ^= 0xFF
+				return data
+			},
+			wantErr: true,
+		},
+		{
+			name: "block length altered",
+			corrupt: func(data []byte) []byte {
+				data[fileHeaderSize] ^= 0x40
+				return data
+			},
+			wantErr: true,
+		},
+		{
+			name: "end block checksum mismatch",
+			corrupt: func(data []byte) []byte {
+				data[len(data)-8]++
+				return data
+			},
+			wantErr: true,
+		},
+		{
+			name: "short file header",
+			corrupt: func(data []byte) []byte {
+				return data[:fileHeaderSize-4]
+			},
+			wantErr: true,
+		},
+		{
+			name: "truncated block",
+			corrupt: func(data []byte) []byte {
+				return data[:fileHeaderSize+blockHeaderSize+5]
+			},
+			wantErr: true,
+		},
+		{
+			name: "truncated end block",
+			corrupt: func(data []byte) []byte {
+				return data[:len(data)-4]
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing end block",
+			corrupt: func(data []byte) []byte {
+				return data[:len(data)-endBlockSize]
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, compression := range []Compression{CompressionNone, CompressionZstd, CompressionLZ4} {
+		for _, tt := range tests {
+			t.Run(compression.String()+"/"+tt.name, func(t *testing.T) {
+				data := tt.corrupt(encodeRecords(t, compression))
+				count, err := decodeRecords(data)
+				if !tt.wantErr {
+					if err != nil {
+						t.Fatalf("decode: %v", err)
+					}
+					if count != testRecords {
+						t.Fatalf("decoded %d records, want %d", count, testRecords)
+					}
+					return
+				}
+				if !errors.Is(err, ErrCorrupted) {
+					t.Fatalf("decode returned %v after %d records, want ErrCorrupted", err, count)
+				}
+			})
+		}
+	}
+}
+
+func TestBlockFormatLegacyJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "version 1", data: `[{"key":"a"}]`},
+		{name: "version 2", data: `{"entries":[]}`},
+		{name: "shorter than the header", data: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newBlockReader(bytes.NewReader([]byte(tt.data)))
+			if err == nil {
+				t.Fatalf("legacy JSON snapshot was accepted")
+			}
+			// 旧格式是版本不支持，不是文件损坏
+			if errors.Is(err, ErrCorrupted) {
+				t.Fatalf("legacy JSON snapshot reported as corrupted: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRetention 默认保留的快照代数
+	DefaultRetention = 3
+
+	// generationLayout 快照代的编号格式（UTC 时间，精确到毫秒），按字符串排序即按时间排序
+	generationLayout = "20060102T150405.000Z"
+)
+
+var (
+	// ErrGenerationNotFound 指定的快照代不存在
+	ErrGenerationNotFound = errors.New("snapshot generation not found")
+	// ErrInvalidGeneration 快照代编号格式不正确
+	ErrInvalidGeneration = errors.New("invalid snapshot generation id")
+)
+
+// Generation 一代快照文件的信息
+type Generation struct {
+	ID          string    `json:"id"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+	Compression string    `json:"compression,omitempty"`
+	Entries     uint64    `json:"entries"`
+	Error       string    `json:"error,omitempty"` // 文件头或结束块无法读取时的错误，加载时会被跳过
+}
+
+// NewGenerationID 按时间生成快照代编号，网关可以生成一个编号分发给所有节点，使同一次备份在各节点上编号相同
+func NewGenerationID(t time.Time) string {
+	return t.UTC().Format(generationLayout)
+}
+
+// parseGenerationID 校验快照代编号并返回对应的时间
+func parseGenerationID(id string) (time.Time, error) {
+	t, err := time.Parse(generationLayout, id)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidGeneration, id)
+	}
+	return t, nil
+}
+
+// generationPath 快照代对应的文件路径：<snapshot_path>.<id>
+func (m *Manager) generationPath(id string) string {
+	return m.snapshotPath + "." + id
+}
+
+// generations 列出所有快照代，按编号从新到旧排列，调用方需持有 m.mu
+func (m *Manager) generations() ([]*Generation, error) {
+	m.migrateLegacy()
+
+	matches, err := filepath.Glob(m.snapshotPath + ".*")
+	if err != nil {
+		return nil, err
+	}
+	prefix := m.snapshotPath + "."
+	var result []*Generation
+	for _, path := range matches {
+		id := strings.TrimPrefix(path, prefix)
+		createdAt, err := parseGenerationID(id)
+		if err != nil {
+			// 临时文件等其他同前缀的文件
+			continue
+		}
+		stat, err := os.Stat(path)
+		if err != nil || stat.IsDir() {
+			continue
+		}
+		gen := &Generation{ID: id, Path: path, Size: stat.Size(), CreatedAt: createdAt}
+		header, entries, err := readSummary(path)
+		if header != nil {
+			gen.Compression = header.Compression.String()
+			gen.CreatedAt = time.Unix(header.CreatedAt, 0)
+		}
+		if err != nil {
+			gen.Error = err.Error()
+		} else {
+			gen.Entries = entries
+		}
+		result = append(result, gen)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	return result, nil
+}
+
+// migrateLegacy 将单文件方式保存的快照（snapshot_path 及其 .prev）按修改时间改名为快照代
+func (m *Manager) migrateLegacy() {
+	for _, path := range []string{m.snapshotPath + ".prev", m.snapshotPath} {
+		stat, err := os.Stat(path)
+		if err != nil || stat.IsDir() {
+			continue
+		}
+		target := m.generationPath(NewGenerationID(stat.ModTime()))
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := os.Rename(path, target); err != nil {
+			logrus.Warnf("[Snapshot Manager] Failed to migrate legacy snapshot %s: %v", path, err)
+			continue
+		}
+		logrus.Infof("[Snapshot Manager] Migrated legacy snapshot %s to %s", path, target)
+	}
+}
+
+// prune 删除超出保留代数的旧快照，调用方需持有 m.mu
+func (m *Manager) prune() {
+	gens, err := m.generations()
+	if err != nil {
+		logrus.Warnf("[Snapshot Manager] Failed to list snapshot generations: %v", err)
+		return
+	}
+	for i := m.retention; i < len(gens); i++ {
+		if err := os.Remove(gens[i].Path); err != nil {
+			logrus.Warnf("[Snapshot Manager] Failed to remove snapshot generation %s: %v", gens[i].ID, err)
+			continue
+		}
+		logrus.Infof("[Snapshot Manager] Removed snapshot generation %s (retention: %d)", gens[i].ID, m.retention)
+	}
+}
@@ -0,0 +1,237 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"KamaitachiGo/internal/cache/lru"
+)
+
+// 快照代的测试：最新一代不可用时 Load 回退到更早的一代；单文件方式保存的旧快照按修改时间迁移为快照代。
+
+// testValue 测试用的缓存值
+type testValue string
+
+func (v testValue) Len() int {
+	return len(v)
+}
+
+// testCodec testValue 的编解码器
+type testCodec struct{}
+
+func (testCodec) Type() string { return "test" }
+
+func (testCodec) Version() int { return 1 }
+
+func (testCodec) Accepts(value lru.Value) bool {
+	_, ok := value.(testValue)
+	return ok
+}
+
+func (testCodec) Encode(value lru.Value) ([]byte, error) {
+	return []byte(value.(testValue)), nil
+}
+
+func (testCodec) Decode(version int, data []byte) (lru.Value, error) {
+	if version != 1 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+	return testValue(data), nil
+}
+
+// newTestManager 创建使用空缓存的快照管理器
+func newTestManager(path string) (*Manager, *lru.Cache) {
+	cache := lru.NewCache(0, nil)
+	m := NewManager(cache, path)
+	m.RegisterCodec(testCodec{})
+	return m, cache
+}
+
+// saveGenerations 依次保存两代快照：较早的一代有 2 个条目，最新的一代有 3 个条目，返回两代的编号（从旧到新）
+func saveGenerations(t *testing.T, path string) []string {
+	t.Helper()
+
+	m, cache := newTestManager(path)
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		cache.Add(fmt.Sprintf("key-%d", i), testValue(fmt.Sprintf("value-%d", i)))
+		if i == 0 {
+			continue
+		}
+		id := NewGenerationID(base.Add(time.Duration(i) * time.Second))
+		if _, err := m.SaveGeneration(id); err != nil {
+			t.Fatalf("save generation %s: %v", id, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestLoadFallsBackToPreviousGeneration(t *testing.T) {
+	truncate := func(path string) error {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.Truncate(path, stat.Size()-5)
+	}
+	flipByte := func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data[fileHeaderSize+blockHeaderSize] ^= 0xFF
+		return os.WriteFile(path, data, 0644)
+	}
+	legacyJSON := func(path string) error {
+		return os.WriteFile(path, []byte(`[{"key":"key-0"}]`), 0644)
+	}
+
+	tests := []struct {
+		name    string
+		corrupt map[int]func(path string) error // 按代的下标（0 为较早的一代）破坏文件
+		want    int
+		wantErr bool
+	}{
+		{name: "newest intact", want: 3},
+		{name: "newest truncated", corrupt: map[int]func(string) error{1: truncate}, want: 2},
+		{name: "newest checksum mismatch", corrupt: map[int]func(string) error{1: flipByte}, want: 2},
+		{name: "newest unsupported version", corrupt: map[int]func(string) error{1: legacyJSON}, want: 2},
+		{name: "newest removed", corrupt: map[int]func(string) error{1: os.Remove}, want: 2},
+		{name: "all corrupted", corrupt: map[int]func(string) error{0: flipByte, 1: truncate}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.snapshot")
+			ids := saveGenerations(t, path)
+			for i, corrupt := range tt.corrupt {
+				if err := corrupt(path + "." + ids[i]); err != nil {
+					t.Fatalf("corrupt generation %s: %v", ids[i], err)
+				}
+			}
+
+			m, cache := newTestManager(path)
+			count, err := m.Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("load succeeded with %d entries, want an error", count)
+				}
+				if cache.Len() != 0 {
+					t.Fatalf("failed load restored %d entries", cache.Len())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if count != tt.want || cache.Len() != tt.want {
+				t.Fatalf("loaded %d entries (cache has %d), want %d", count, cache.Len(), tt.want)
+			}
+			for i := 0; i < tt.want; i++ {
+				value, ok := cache.Get(fmt.Sprintf("key-%d", i))
+				if !ok || value.(testValue) != testValue(fmt.Sprintf("value-%d", i)) {
+					t.Errorf("key-%d restored as %v (%v)", i, value, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateLegacySnapshots(t *testing.T) {
+	prevTime := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	currentTime := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		legacy   map[string]time.Time // 旧文件的后缀（"" 为 snapshot_path 本身）及其修改时间
+		existing []time.Time          // 迁移前已存在的快照代
+		want     []time.Time          // 迁移后的快照代，从新到旧
+		leftover []string             // 未迁移、仍保留的旧文件后缀
+	}{
+		{
+			name:   "current only",
+			legacy: map[string]time.Time{"": currentTime},
+			want:   []time.Time{currentTime},
+		},
+		{
+			name:   "current and prev",
+			legacy: map[string]time.Time{"": currentTime, ".prev": prevTime},
+			want:   []time.Time{currentTime, prevTime},
+		},
+		{
+			name:     "generation already exists",
+			legacy:   map[string]time.Time{"": currentTime, ".prev": prevTime},
+			existing: []time.Time{currentTime},
+			want:     []time.Time{currentTime, prevTime},
+			leftover: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.snapshot")
+			ids := saveGenerations(t, path)
+			// 以一代正常的快照作为旧文件的内容
+			data, err := os.ReadFile(path + "." + ids[0])
+			if err != nil {
+				t.Fatalf("read generation: %v", err)
+			}
+			for _, id := range ids {
+				if err := os.Remove(path + "." + id); err != nil {
+					t.Fatalf("remove generation: %v", err)
+				}
+			}
+
+			for suffix, modTime := range tt.legacy {
+				if err := os.WriteFile(path+suffix, data, 0644); err != nil {
+					t.Fatalf("write legacy snapshot: %v", err)
+				}
+				if err := os.Chtimes(path+suffix, modTime, modTime); err != nil {
+					t.Fatalf("set legacy snapshot time: %v", err)
+				}
+			}
+			for _, created := range tt.existing {
+				if err := os.WriteFile(path+"."+NewGenerationID(created), data, 0644); err != nil {
+					t.Fatalf("write generation: %v", err)
+				}
+			}
+
+			m, _ := newTestManager(path)
+			gens, err := m.Generations()
+			if err != nil {
+				t.Fatalf("list generations: %v", err)
+			}
+			if len(gens) != len(tt.want) {
+				t.Fatalf("got %d generations, want %d", len(gens), len(tt.want))
+			}
+			for i, gen := range gens {
+				if want := NewGenerationID(tt.want[i]); gen.ID != want {
+					t.Errorf("generation %d is %s, want %s", i, gen.ID, want)
+				}
+				if gen.Error != "" || gen.Entries != 2 {
+					t.Errorf("generation %s has %d entries, error %q", gen.ID, gen.Entries, gen.Error)
+				}
+			}
+
+			leftover := make(map[string]bool)
+			for _, suffix := range tt.leftover {
+				leftover[suffix] = true
+			}
+			for suffix := range tt.legacy {
+				_, err := os.Stat(path + suffix)
+				if exists := err == nil; exists != leftover[suffix] {
+					t.Errorf("legacy snapshot %q exists: %v, want %v", path+suffix, exists, leftover[suffix])
+				}
+			}
+
+			if count, err := m.Load(); err != nil || count != 2 {
+				t.Fatalf("load migrated snapshot: %d entries, %v", count, err)
+			}
+		})
+	}
+}
@@ -1,104 +1,379 @@
 package snapshot
 
 import (
-	"KamaitachiGo/internal/cache/lru"
-	"KamaitachiGo/pkg/json"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
-	
+
+	"KamaitachiGo/internal/cache/lru"
+
 	"github.com/sirupsen/logrus"
 )
 
+// Codec 缓存值的编解码器，每种需要持久化的值类型注册一个
+type Codec interface {
+	// Type 写入快照的类型名，恢复时据此选择编解码器
+	Type() string
+	// Version 值编码的版本，编码结构变化时递增
+	Version() int
+	// Accepts 判断是否能编码该值
+	Accepts(value lru.Value) bool
+	// Encode 编码缓存值
+	Encode(value lru.Value) ([]byte, error)
+	// Decode 解码缓存值，version 为写入时的编码版本
+	Decode(version int, data []byte) (lru.Value, error)
+}
+
 // Manager 快照管理器
+// 每次保存生成一代新的快照文件 <snapshot_path>.<编号>，保留最近 retention 代；
+// 加载时从最新一代开始，文件缺失、损坏或格式不支持时依次回退到更早的一代
 type Manager struct {
-	cache        *lru.Cache
+	cache        lru.Store
 	snapshotPath string
+	compression  Compression
+	retention    int
+	codecs       map[string]Codec
+	order        []Codec // 按注册顺序匹配值的类型
 	mu           sync.Mutex
 	stopChan     chan struct{}
 }
 
-// NewManager 创建快照管理器
-func NewManager(cache *lru.Cache, snapshotPath string) *Manager {
+// NewManager 创建快照管理器，通过 RegisterCodec 注册需要持久化的值类型
+func NewManager(cache lru.Store, snapshotPath string) *Manager {
 	return &Manager{
 		cache:        cache,
 		snapshotPath: snapshotPath,
+		retention:    DefaultRetention,
+		codecs:       make(map[string]Codec),
 		stopChan:     make(chan struct{}),
 	}
 }
 
-// Save 保存快照
+// SetCompression 设置保存快照时数据块的压缩算法，加载时按文件中记录的算法解压
+func (m *Manager) SetCompression(compression Compression) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compression = compression
+}
+
+// SetRetention 设置保留的快照代数，小于1时使用默认值
+func (m *Manager) SetRetention(retention int) {
+	if retention < 1 {
+		retention = DefaultRetention
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retention = retention
+}
+
+// RegisterCodec 注册缓存值的编解码器，没有编解码器的值不会被保存
+func (m *Manager) RegisterCodec(codec Codec) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.codecs[codec.Type()]; !ok {
+		m.order = append(m.order, codec)
+	}
+	m.codecs[codec.Type()] = codec
+}
+
+// codecFor 查找能编码该值的编解码器
+func (m *Manager) codecFor(value lru.Value) Codec {
+	for _, codec := range m.order {
+		if codec.Accepts(value) {
+			return codec
+		}
+	}
+	return nil
+}
+
+// Save 保存一代新的快照
 func (m *Manager) Save() error {
+	_, err := m.SaveGeneration("")
+	return err
+}
+
+// SaveGeneration 以指定编号保存一代快照，id 为空时按当前时间生成；编号已存在时覆盖
+func (m *Manager) SaveGeneration(id string) (*Generation, error) {
+	if id == "" {
+		id = NewGenerationID(time.Now())
+	} else if _, err := parseGenerationID(id); err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	
+
 	// 确保目录存在
 	dir := filepath.Dir(m.snapshotPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create snapshot directory: %w", err)
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
 	}
-	
-	// 获取所有缓存数据
-	entries := m.cache.GetAll()
-	
-	// 序列化数据
-	data, err := json.Marshal(entries)
-	if err != nil {
-		return fmt.Errorf("failed to marshal snapshot data: %w", err)
-	}
-	
+
 	// 写入临时文件
+	path := m.generationPath(id)
 	tmpFile := m.snapshotPath + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write snapshot file: %w", err)
+	count, skipped, err := m.writeFile(tmpFile)
+	if err != nil {
+		os.Remove(tmpFile)
+		return nil, err
 	}
-	
+
 	// 重命名为正式文件（原子操作）
-	if err := os.Rename(tmpFile, m.snapshotPath); err != nil {
-		return fmt.Errorf("failed to rename snapshot file: %w", err)
+	if err := os.Rename(tmpFile, path); err != nil {
+		return nil, fmt.Errorf("failed to rename snapshot file: %w", err)
 	}
-	
-	logrus.Infof("[Snapshot Manager] Saved snapshot successfully, entries count: %d", len(entries))
-	return nil
+	m.prune()
+
+	logrus.Infof("[Snapshot Manager] Saved snapshot generation %s successfully, entries count: %d, skipped: %d, compression: %s", id, count, skipped, m.compression)
+
+	gen := &Generation{ID: id, Path: path, CreatedAt: time.Now(), Compression: m.compression.String(), Entries: uint64(count)}
+	if stat, err := os.Stat(path); err == nil {
+		gen.Size = stat.Size()
+	}
+	return gen, nil
+}
+
+// writeFile 将缓存逐条编码写入文件，返回写入和跳过的条目数
+func (m *Manager) writeFile(path string) (int, int, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer f.Close()
+
+	count, skipped, err := m.writeEntries(f, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, 0, fmt.Errorf("failed to sync snapshot file: %w", err)
+	}
+	return count, skipped, nil
 }
 
-// Load 加载快照
+// writeEntries 将缓存中 filter 接受的条目（filter 为 nil 时全部）以快照格式写入 w，返回写入和跳过的条目数
+func (m *Manager) writeEntries(out io.Writer, filter func(key string) bool) (int, int, error) {
+	w, err := newBlockWriter(out, m.compression, DefaultBlockSize, time.Now().Unix())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+
+	// GetAll 按保留优先级从高到低返回，从优先级最低的条目开始写入，加载时顺序恢复即可
+	entries := m.cache.GetAll()
+	count := 0
+	skipped := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if filter != nil && !filter(entry.Key) {
+			continue
+		}
+		codec := m.codecFor(entry.Value)
+		if codec == nil {
+			skipped++
+			continue
+		}
+		value, err := codec.Encode(entry.Value)
+		if err != nil {
+			logrus.Warnf("[Snapshot Manager] Failed to encode entry %s: %v", entry.Key, err)
+			skipped++
+			continue
+		}
+		if err := w.Write(&record{
+			Key:         entry.Key,
+			Type:        codec.Type(),
+			TypeVersion: codec.Version(),
+			CreateAt:    entry.CreateAt,
+			AccessAt:    entry.AccessAt,
+			ExpireTime:  entry.ExpireTime,
+			Hits:        entry.Hits,
+			Value:       value,
+		}); err != nil {
+			return 0, 0, fmt.Errorf("failed to write snapshot data: %w", err)
+		}
+		count++
+	}
+	if err := w.Close(); err != nil {
+		return 0, 0, fmt.Errorf("failed to write snapshot data: %w", err)
+	}
+	return count, skipped, nil
+}
+
+// Load 加载最新可用的一代快照，按保存时的顺序还原缓存的淘汰顺序，返回恢复的条目数
+// 最新一代损坏或格式不支持时回退到更早的一代
 func (m *Manager) Load() (int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	
-	// 检查文件是否存在
-	if _, err := os.Stat(m.snapshotPath); os.IsNotExist(err) {
+
+	gens, err := m.generations()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list snapshot generations: %w", err)
+	}
+	if len(gens) == 0 {
 		return 0, fmt.Errorf("snapshot file not found: %s", m.snapshotPath)
 	}
-	
-	// 读取文件
-	data, err := os.ReadFile(m.snapshotPath)
+
+	var lastErr error
+	for _, gen := range gens {
+		count, err := m.load(gen)
+		if err == nil {
+			return count, nil
+		}
+		if count > 0 {
+			// 已经恢复了部分条目，不再回退
+			return count, err
+		}
+		logrus.Warnf("[Snapshot Manager] Snapshot generation %s is unusable (%v), falling back to the previous generation", gen.ID, err)
+		lastErr = err
+	}
+	return 0, lastErr
+}
+
+// LoadGeneration 加载指定的一代快照，条目合并到当前缓存中（同名条目被覆盖），返回恢复的条目数
+func (m *Manager) LoadGeneration(id string) (int, error) {
+	if _, err := parseGenerationID(id); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := m.generationPath(id)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, fmt.Errorf("%w: %s", ErrGenerationNotFound, id)
+	}
+	return m.load(&Generation{ID: id, Path: path})
+}
+
+// Generations 列出所有快照代，按编号从新到旧排列
+func (m *Manager) Generations() ([]*Generation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.generations()
+}
+
+// DeleteGeneration 删除指定的一代快照
+func (m *Manager) DeleteGeneration(id string) error {
+	if _, err := parseGenerationID(id); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.Remove(m.generationPath(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrGenerationNotFound, id)
+		}
+		return fmt.Errorf("failed to remove snapshot generation %s: %w", id, err)
+	}
+	logrus.Infof("[Snapshot Manager] Removed snapshot generation %s", id)
+	return nil
+}
+
+// load 先完整校验再恢复一代快照，损坏的文件不会恢复出部分数据，调用方需持有 m.mu
+func (m *Manager) load(gen *Generation) (int, error) {
+	header, err := verifyFile(gen.Path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load snapshot %s: %w", gen.Path, err)
+	}
+
+	count, skipped, err := m.restoreFile(gen.Path)
+	if err != nil {
+		return count, fmt.Errorf("failed to load snapshot %s: %w", gen.Path, err)
+	}
+
+	logrus.Infof("[Snapshot Manager] Loaded snapshot generation %s successfully, entries count: %d, skipped: %d, compression: %s, saved at: %s",
+		gen.ID, count, skipped, header.Compression, time.Unix(header.CreatedAt, 0).Format("2006-01-02 15:04:05"))
+	return count, nil
+}
+
+// verifyFile 读完整个文件，校验文件头、每个块的校验和、记录结构和条目总数
+func verifyFile(path string) (*fileHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := newBlockReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	for {
+		if _, err := r.Next(); err == io.EOF {
+			return &r.header, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// restoreFile 逐条解码并恢复到缓存，返回恢复和跳过的条目数
+func (m *Manager) restoreFile(path string) (int, int, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read snapshot file: %w", err)
+		return 0, 0, err
 	}
-	
-	// 反序列化数据
-	var entries []*lru.Entry
-	if err := json.Unmarshal(data, &entries); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal snapshot data: %w", err)
+	defer f.Close()
+
+	return m.restoreEntries(context.Background(), f, nil)
+}
+
+// restoreEntries 读取快照格式的数据并恢复 filter 接受的条目（filter 为 nil 时全部），返回恢复和跳过的条目数；ctx 结束时停止
+func (m *Manager) restoreEntries(ctx context.Context, in io.Reader, filter func(key string) bool) (int, int, error) {
+	r, err := newBlockReader(in)
+	if err != nil {
+		return 0, 0, err
 	}
-	
-	// 恢复数据到缓存
+	defer r.Close()
+
+	// 从保留优先级最低的条目开始恢复，最后恢复的条目成为最近使用的条目
 	count := 0
-	for _, entry := range entries {
-		// 检查数据是否过期
-		if entry.ExpireTime > 0 && time.Now().Unix() > entry.CreateAt+entry.ExpireTime {
+	skipped := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, skipped, err
+		}
+		rec, err := r.Next()
+		if err == io.EOF {
+			return count, skipped, nil
+		}
+		if err != nil {
+			return count, skipped, err
+		}
+		if filter != nil && !filter(rec.Key) {
+			skipped++
 			continue
 		}
-		m.cache.Add(entry.Key, entry.Value)
-		count++
+		codec, ok := m.codecs[rec.Type]
+		if !ok {
+			skipped++
+			continue
+		}
+		value, err := codec.Decode(rec.TypeVersion, rec.Value)
+		if err != nil {
+			logrus.Warnf("[Snapshot Manager] Failed to decode entry %s (%s v%d): %v", rec.Key, rec.Type, rec.TypeVersion, err)
+			skipped++
+			continue
+		}
+		// 过期时间按原始的写入/访问时间计算，已过期的条目不恢复；命中次数用于重建淘汰策略的频率状态
+		if m.cache.Restore(&lru.Entry{
+			Key:        rec.Key,
+			Value:      value,
+			CreateAt:   rec.CreateAt,
+			AccessAt:   rec.AccessAt,
+			ExpireTime: rec.ExpireTime,
+			Hits:       rec.Hits,
+		}) {
+			count++
+		}
 	}
-	
-	logrus.Infof("[Snapshot Manager] Loaded snapshot successfully, entries count: %d", count)
-	return count, nil
 }
 
 // AutoSnapshot 自动保存快照
@@ -106,7 +381,7 @@ func (m *Manager) AutoSnapshot(interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
-		
+
 		for {
 			select {
 			case <-ticker.C:
@@ -134,18 +409,24 @@ func (m *Manager) Stop() {
 
 // GetSnapshotInfo 获取快照信息
 func (m *Manager) GetSnapshotInfo() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	info := make(map[string]interface{})
-	
-	if stat, err := os.Stat(m.snapshotPath); err == nil {
-		info["path"] = m.snapshotPath
-		info["size"] = stat.Size()
-		info["modTime"] = stat.ModTime().Format("2006-01-02 15:04:05")
-	} else {
+	info["path"] = m.snapshotPath
+	if gens, err := m.generations(); err != nil {
 		info["error"] = err.Error()
+	} else {
+		info["generations"] = gens
+		if len(gens) > 0 {
+			info["latest"] = gens[0].ID
+		}
 	}
-	
+
+	info["compression"] = m.compression.String()
+	info["retention"] = m.retention
+	info["version"] = FormatVersion
 	info["cacheEntries"] = m.cache.Len()
-	
+
 	return info
 }
-
@@ -0,0 +1,39 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Export 将缓存中 filter 接受的条目以快照格式（含压缩与校验）写入 w，供其他节点通过 Import 接收，返回写入的条目数
+func (m *Manager) Export(w io.Writer, filter func(key string) bool) (int, error) {
+	count, _, err := m.writeEntries(w, filter)
+	return count, err
+}
+
+// Import 读取 Export 写出的数据并恢复 filter 接受的条目（filter 为 nil 时全部），已有的同名条目被覆盖，返回恢复的条目数
+// 数据块逐块校验，中途出错或 ctx 结束时停止，已恢复的条目保留
+func (m *Manager) Import(ctx context.Context, r io.Reader, filter func(key string) bool) (int, error) {
+	dropped := 0
+	accept := func(key string) bool {
+		if filter == nil || filter(key) {
+			return true
+		}
+		dropped++
+		return false
+	}
+	count, skipped, err := m.restoreEntries(ctx, r, accept)
+	if dropped > 0 {
+		logrus.Warnf("[Snapshot Manager] Dropped %d imported entries rejected by the filter", dropped)
+	}
+	if err != nil {
+		return count, fmt.Errorf("failed to import cache entries: %w", err)
+	}
+	if skipped -= dropped; skipped > 0 {
+		logrus.Warnf("[Snapshot Manager] Skipped %d imported entries with unknown or undecodable types", skipped)
+	}
+	return count, nil
+}
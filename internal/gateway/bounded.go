@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"math"
+	"sync"
+)
+
+// LoadTracker 统计各节点正在处理的转发请求数，并实现有界负载一致性哈希（consistent hashing with bounded loads）：
+// 节点的在途请求数超过 factor 倍平均负载时，请求溢出到哈希环上的下一个后继节点。
+// 负载不高时请求总是落在主节点，保持数据局部性；只有热点节点的超额部分被分摊
+type LoadTracker struct {
+	mu       sync.Mutex
+	factor   float64 // 负载上限系数，<=1 时不限制
+	inflight map[string]int
+	total    int
+	spills   int64 // 溢出到后继节点的请求数
+}
+
+// NewLoadTracker 创建负载统计，factor 为负载上限系数（如 1.25），<=1 表示只统计不限制
+func NewLoadTracker(factor float64) *LoadTracker {
+	return &LoadTracker{
+		factor:   factor,
+		inflight: make(map[string]int),
+	}
+}
+
+// Bounded 是否启用有界负载
+func (t *LoadTracker) Bounded() bool {
+	return t.factor > 1
+}
+
+// Choose 按顺序返回第一个负载未达上限的候选节点下标，candidates 为哈希环上从主节点开始的所有节点，
+// weight 返回节点的权重（<1 按1处理）；节点的上限按权重占比分配：
+// ceil(factor * (总在途请求数+1) * 节点权重 / 候选节点权重之和)。所有节点都已达上限时返回 0
+func (t *LoadTracker) Choose(candidates []string, weight func(node string) int) int {
+	if !t.Bounded() || len(candidates) <= 1 {
+		return 0
+	}
+
+	weights := make([]int, len(candidates))
+	totalWeight := 0
+	for i, node := range candidates {
+		weights[i] = max(weight(node), 1)
+		totalWeight += weights[i]
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, node := range candidates {
+		limit := int(math.Ceil(t.factor * float64(t.total+1) * float64(weights[i]) / float64(totalWeight)))
+		if t.inflight[node] < limit {
+			if i > 0 {
+				t.spills++
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// Begin 节点开始处理一个转发请求
+func (t *LoadTracker) Begin(node string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inflight[node]++
+	t.total++
+}
+
+// End 节点处理完一个转发请求
+func (t *LoadTracker) End(node string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inflight[node] <= 1 {
+		delete(t.inflight, node)
+	} else {
+		t.inflight[node]--
+	}
+	t.total--
+}
+
+// Stats 返回各节点在途请求数与溢出次数，供网关管理接口展示
+func (t *LoadTracker) Stats() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	inflight := make(map[string]int, len(t.inflight))
+	for node, n := range t.inflight {
+		inflight[node] = n
+	}
+	return map[string]interface{}{
+		"bounded":     t.Bounded(),
+		"load_factor": t.factor,
+		"inflight":    inflight,
+		"total":       t.total,
+		"spills":      t.spills,
+	}
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"KamaitachiGo/internal/model"
+	"KamaitachiGo/pkg/json"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handoffer 成员变化时在网关切换路由前协调缓存交接：通知新的归属节点从原归属节点拉取迁移的subject数据
+type Handoffer struct {
+	client   *http.Client
+	strategy string
+	replicas int
+	timeout  time.Duration
+	token    string // 节点间内部接口的共享令牌
+
+	mu   sync.Mutex
+	last map[string]interface{} // 最近一次交接的结果
+}
+
+// NewHandoffer 创建缓存交接，strategy/replicas 与网关路由一致，timeout 为等待所有节点交接完成的上限，
+// 超时后网关照常切换路由（未交接的数据按需从数据库加载）；token 为节点间内部接口的共享令牌
+func NewHandoffer(client *http.Client, strategy string, replicas int, timeout time.Duration, token string) *Handoffer {
+	return &Handoffer{
+		client:   client,
+		strategy: strategy,
+		replicas: replicas,
+		timeout:  timeout,
+		token:    token,
+	}
+}
+
+// Run 按变化前后的成员 previous、nodes（地址到权重）让 targets 中的节点并行从 sources 拉取归属于自己的条目，全部完成或超时后返回
+func (h *Handoffer) Run(previous, nodes map[string]int, targets, sources []string) []*model.HandoffResult {
+	if len(targets) == 0 {
+		return nil
+	}
+	ring := model.RingSpec{Strategy: h.strategy, Replicas: h.replicas, Nodes: nodes}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	results := make([]*model.HandoffResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		from := make([]string, 0, len(sources))
+		for _, source := range sources {
+			if source != target {
+				from = append(from, source)
+			}
+		}
+		wg.Add(1)
+		go func(i int, target string, from []string) {
+			defer wg.Done()
+			req := &model.HandoffRequest{Ring: ring, Previous: previous, Target: target, Sources: from}
+			if deadline, ok := ctx.Deadline(); ok {
+				req.Timeout = max(time.Until(deadline).Milliseconds(), 1)
+			}
+			results[i] = h.handoff(ctx, req)
+		}(i, target, from)
+	}
+	wg.Wait()
+
+	imported := 0
+	for _, result := range results {
+		imported += result.Imported
+		if result.Error != "" {
+			logrus.Warnf("[Handoff] Node %s: %s", result.Target, result.Error)
+		} else if len(result.Missing) > 0 {
+			logrus.Warnf("[Handoff] Node %s could not pull from %v", result.Target, result.Missing)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Target < results[j].Target })
+	elapsed := time.Since(start).Round(time.Millisecond)
+	logrus.Infof("[Handoff] %d entries handed off to %v from %v in %v", imported, targets, sources, elapsed)
+
+	h.mu.Lock()
+	h.last = map[string]interface{}{
+		"time":     start.Format("2006-01-02 15:04:05"),
+		"elapsed":  elapsed.String(),
+		"sources":  sources,
+		"imported": imported,
+		"results":  results,
+	}
+	h.mu.Unlock()
+	return results
+}
+
+// Last 返回最近一次交接的结果，供网关管理接口展示
+func (h *Handoffer) Last() map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.last
+}
+
+// handoff 请求一个节点执行交接
+func (h *Handoffer) handoff(ctx context.Context, req *model.HandoffRequest) *model.HandoffResult {
+	result := &model.HandoffResult{Target: req.Target}
+	body, err := json.Marshal(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+req.Target+model.HandoffPath, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(model.InternalTokenHeader, h.token)
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	var parsed struct {
+		StatusCode int                  `json:"status_code"`
+		StatusMsg  string               `json:"status_msg"`
+		Data       *model.HandoffResult `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		result.Error = fmt.Sprintf("unexpected response (status %d): %v", resp.StatusCode, err)
+		return result
+	}
+	if parsed.Data != nil {
+		result = parsed.Data
+		result.Target = req.Target
+	}
+	if parsed.StatusCode != 0 {
+		result.Error = parsed.StatusMsg
+	}
+	return result
+}
@@ -0,0 +1,335 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"KamaitachiGo/internal/model"
+	"KamaitachiGo/pkg/etcd"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OutlierConfig 已加入哈希环节点的健康探测与异常摘除配置
+type OutlierConfig struct {
+	ProbeInterval      time.Duration // 主动探测间隔
+	ProbeFailures      int           // 连续探测失败多少次后摘除
+	ConsecutiveErrors  int           // 转发请求连续 5xx/超时多少次后摘除
+	BaseEjectionTime   time.Duration // 首次摘除时长，之后每次摘除按次数倍增
+	MaxEjectionTime    time.Duration // 摘除时长上限
+	MaxEjectionPercent int           // 最多同时摘除的节点比例，避免所有节点被摘除
+}
+
+// DefaultOutlierConfig 默认的健康探测与异常摘除配置
+func DefaultOutlierConfig() OutlierConfig {
+	return OutlierConfig{
+		ProbeInterval:      5 * time.Second,
+		ProbeFailures:      2,
+		ConsecutiveErrors:  5,
+		BaseEjectionTime:   30 * time.Second,
+		MaxEjectionTime:    5 * time.Minute,
+		MaxEjectionPercent: 50,
+	}
+}
+
+// memberState 已通过就绪检查的节点的健康状态
+type memberState struct {
+	info              *etcd.NodeInfo
+	weight            int  // 在哈希环上的权重，注册的权重变化后在交接完成时更新
+	leaving           bool // 已注销，等待交接完成后移出成员和哈希环
+	consecutiveErrors int  // 转发请求连续失败次数
+	probeFailures     int  // 主动探测连续失败次数
+	probing           bool
+	lastError         string
+	ejections         int       // 累计被摘除次数，决定下次摘除时长
+	ejection          *ejection // 非 nil 表示当前已从哈希环中摘除
+}
+
+// ejection 一次摘除
+type ejection struct {
+	reason string
+	since  time.Time
+	until  time.Time // 到期后探测通过即恢复；手动摘除时为零值，只能手动恢复
+	manual bool
+}
+
+// SetOutlierConfig 设置健康探测与异常摘除配置，需在 Start 之前调用，未设置（<=0）的字段使用默认值
+func (g *ReadinessGate) SetOutlierConfig(cfg OutlierConfig) {
+	def := DefaultOutlierConfig()
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = def.ProbeInterval
+	}
+	if cfg.ProbeFailures <= 0 {
+		cfg.ProbeFailures = def.ProbeFailures
+	}
+	if cfg.ConsecutiveErrors <= 0 {
+		cfg.ConsecutiveErrors = def.ConsecutiveErrors
+	}
+	if cfg.BaseEjectionTime <= 0 {
+		cfg.BaseEjectionTime = def.BaseEjectionTime
+	}
+	if cfg.MaxEjectionTime <= 0 {
+		cfg.MaxEjectionTime = def.MaxEjectionTime
+	}
+	if cfg.MaxEjectionPercent <= 0 {
+		cfg.MaxEjectionPercent = def.MaxEjectionPercent
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.outlier = cfg
+}
+
+// ReportSuccess 转发请求成功，清零连续失败次数
+func (g *ReadinessGate) ReportSuccess(node string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if m, ok := g.members[node]; ok {
+		m.consecutiveErrors = 0
+	}
+}
+
+// ReportFailure 转发请求失败（连接错误、超时或 5xx），连续失败达到阈值时摘除节点
+func (g *ReadinessGate) ReportFailure(node string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m, ok := g.members[node]
+	if !ok || m.ejection != nil {
+		return
+	}
+	m.consecutiveErrors++
+	m.lastError = err.Error()
+	if m.consecutiveErrors >= g.outlier.ConsecutiveErrors {
+		g.eject(node, m, fmt.Sprintf("%d consecutive request failures, last: %v", m.consecutiveErrors, err))
+	}
+}
+
+// Eject 手动摘除节点，直到调用 Reinstate；配置了缓存交接时先交接再摘除。节点不存在或正在退出时返回 model.ErrNotFound
+// 摘除与其他成员变化按顺序执行，Eject 等待交接和摘除完成后返回
+func (g *ReadinessGate) Eject(node, reason string) error {
+	if reason == "" {
+		reason = "manual"
+	}
+	done := make(chan error, 1)
+	g.changes.enqueue(func() { done <- g.ejectManually(node, reason) })
+	return <-done
+}
+
+// ejectManually 成员变化队列中执行的手动摘除：计划内摘除（如维护）时其余节点先拉取本节点负责的数据
+func (g *ReadinessGate) ejectManually(node, reason string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m, ok := g.members[node]
+	if !ok || m.leaving {
+		return model.ErrNotFound(fmt.Sprintf("node %s is not a member", node))
+	}
+	if m.ejection != nil {
+		m.ejection.manual = true
+		m.ejection.until = time.Time{}
+		return nil
+	}
+
+	next := g.routingNodes()
+	delete(next, node)
+	g.runHandoff(next, otherNodes(next, node), []string{node})
+	if g.members[node] != m || m.leaving {
+		return model.ErrNotFound(fmt.Sprintf("node %s was removed during handoff", node))
+	}
+	if m.ejection != nil {
+		m.ejection.manual = true
+		m.ejection.until = time.Time{}
+		return nil
+	}
+
+	m.ejections++
+	m.ejection = &ejection{reason: reason, since: time.Now(), manual: true}
+	g.ring.Remove(node)
+	logrus.Warnf("[Outlier] Node %s ejected manually: %s", node, reason)
+	return nil
+}
+
+// Reinstate 恢复被摘除的节点；节点不存在返回 model.ErrNotFound，未被摘除返回 model.ErrInvalidParameter
+func (g *ReadinessGate) Reinstate(node string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m, ok := g.members[node]
+	if !ok {
+		return model.ErrNotFound(fmt.Sprintf("node %s is not a member", node))
+	}
+	if m.ejection == nil {
+		return model.ErrInvalidParameter(fmt.Sprintf("node %s is not ejected", node))
+	}
+	g.reinstate(node, m)
+	return nil
+}
+
+// eject 从哈希环中摘除节点，调用方需持有 g.mu
+// 已摘除的节点数达到上限时不再摘除，避免全部流量无处可去
+func (g *ReadinessGate) eject(node string, m *memberState, reason string) {
+	ejected := 0
+	for _, other := range g.members {
+		if other.ejection != nil {
+			ejected++
+		}
+	}
+	allowed := len(g.members) * g.outlier.MaxEjectionPercent / 100
+	if allowed >= len(g.members) {
+		allowed = len(g.members) - 1
+	}
+	if ejected >= allowed {
+		logrus.Warnf("[Outlier] Node %s is unhealthy (%s) but %d of %d nodes are already ejected (max %d%%), keeping it",
+			node, reason, ejected, len(g.members), g.outlier.MaxEjectionPercent)
+		return
+	}
+
+	m.ejections++
+	duration := g.ejectionTime(m)
+	now := time.Now()
+	m.ejection = &ejection{reason: reason, since: now, until: now.Add(duration)}
+	g.ring.Remove(node)
+	logrus.Warnf("[Outlier] Node %s ejected for %v: %s", node, duration, reason)
+}
+
+// ejectionTime 摘除时长：基础时长乘以累计摘除次数，不超过上限
+func (g *ReadinessGate) ejectionTime(m *memberState) time.Duration {
+	duration := g.outlier.BaseEjectionTime * time.Duration(m.ejections)
+	if duration > g.outlier.MaxEjectionTime {
+		duration = g.outlier.MaxEjectionTime
+	}
+	return duration
+}
+
+// reinstate 将节点重新加入哈希环，调用方需持有 g.mu
+func (g *ReadinessGate) reinstate(node string, m *memberState) {
+	logrus.Infof("[Outlier] Node %s reinstated after %v", node, time.Since(m.ejection.since).Round(time.Millisecond))
+	m.ejection = nil
+	m.consecutiveErrors = 0
+	m.probeFailures = 0
+	m.weight = m.info.Weight
+	g.ring.AddWeighted(node, m.weight)
+}
+
+// monitor 定期探测所有已加入的节点：在线节点连续探测失败后摘除，摘除到期的节点探测通过后恢复
+func (g *ReadinessGate) monitor() {
+	g.mu.Lock()
+	interval := g.outlier.ProbeInterval
+	g.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			g.mu.Lock()
+			nodes := make([]string, 0, len(g.members))
+			for node, m := range g.members {
+				if m.probing {
+					continue
+				}
+				// 手动摘除或尚未到期的节点不探测
+				if m.ejection != nil && (m.ejection.manual || now.Before(m.ejection.until)) {
+					continue
+				}
+				m.probing = true
+				nodes = append(nodes, node)
+			}
+			g.mu.Unlock()
+
+			for _, node := range nodes {
+				go g.checkMember(node)
+			}
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// checkMember 主动探测一个已加入的节点并更新其状态
+func (g *ReadinessGate) checkMember(node string) {
+	err := g.probe(node)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m, ok := g.members[node]
+	if !ok {
+		return
+	}
+	m.probing = false
+
+	if m.ejection != nil {
+		if m.ejection.manual {
+			return
+		}
+		if err == nil {
+			g.reinstate(node, m)
+			return
+		}
+		// 仍不健康，延长摘除时间
+		m.ejections++
+		m.lastError = err.Error()
+		m.ejection.until = time.Now().Add(g.ejectionTime(m))
+		logrus.Warnf("[Outlier] Node %s still unhealthy (%v), ejection extended to %s", node, err, m.ejection.until.Format("15:04:05"))
+		return
+	}
+
+	if err != nil {
+		m.probeFailures++
+		m.lastError = err.Error()
+		if m.probeFailures >= g.outlier.ProbeFailures {
+			g.eject(node, m, fmt.Sprintf("%d consecutive probe failures, last: %v", m.probeFailures, err))
+		}
+		return
+	}
+	m.probeFailures = 0
+}
+
+// Nodes 返回所有已加入节点的健康与摘除状态，供网关管理接口展示
+func (g *ReadinessGate) Nodes() []map[string]interface{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	names := make([]string, 0, len(g.members))
+	for node := range g.members {
+		names = append(names, node)
+	}
+	sort.Strings(names)
+
+	result := make([]map[string]interface{}, 0, len(names))
+	for _, node := range names {
+		m := g.members[node]
+		state := map[string]interface{}{
+			"node":               node,
+			"weight":             m.weight,
+			"registered_weight":  m.info.Weight,
+			"zone":               m.info.Zone,
+			"version":            m.info.Version,
+			"capacity":           m.info.Capacity,
+			"routing":            m.ejection == nil,
+			"leaving":            m.leaving,
+			"consecutive_errors": m.consecutiveErrors,
+			"probe_failures":     m.probeFailures,
+			"ejections":          m.ejections,
+			"last_error":         m.lastError,
+		}
+		if e := m.ejection; e != nil {
+			ejected := map[string]interface{}{
+				"reason": e.reason,
+				"since":  e.since.Format("2006-01-02 15:04:05"),
+				"manual": e.manual,
+			}
+			if !e.until.IsZero() {
+				ejected["until"] = e.until.Format("2006-01-02 15:04:05")
+			}
+			state["ejection"] = ejected
+		}
+		result = append(result, state)
+	}
+	return result
+}
@@ -0,0 +1,375 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"KamaitachiGo/pkg/etcd"
+	"KamaitachiGo/pkg/hash"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReadinessPath Slave 就绪检查接口
+const ReadinessPath = "/health/ready"
+
+// pendingNode 已注册但尚未通过就绪检查的节点
+type pendingNode struct {
+	info      *etcd.NodeInfo
+	since     time.Time
+	attempts  int
+	lastError string
+	probing   bool
+}
+
+// ReadinessGate 节点准入：etcd 中注册的节点先进入等待列表，定期探测其就绪检查接口，
+// 通过后才加入一致性哈希环，避免请求落到尚未加载快照、预热或数据库不可用的节点。
+// 已加入的节点由 outlier.go 中的主动探测和被动异常检测临时摘除、恢复
+type ReadinessGate struct {
+	mu       sync.Mutex
+	ring     hash.Router
+	client   *http.Client
+	interval time.Duration
+	timeout  time.Duration
+	pending  map[string]*pendingNode
+	members  map[string]*memberState
+	outlier  OutlierConfig
+	handoff  *Handoffer  // 非 nil 时成员变化前先交接缓存
+	changes  changeQueue // 成员变化（交接缓存后切换路由）按发生顺序在后台执行
+	stopCh   chan struct{}
+}
+
+// changeQueue 成员变化队列：按提交顺序在后台协程中逐个执行，提交方（如 etcd 监听回调）不等待交接完成；
+// 逐个执行保证每次交接都基于上一次变化后的路由，切换路由的顺序与成员变化的顺序一致
+type changeQueue struct {
+	mu      sync.Mutex
+	queue   []func()
+	running bool
+}
+
+// enqueue 提交一次成员变化，没有执行中的协程时启动一个
+func (q *changeQueue) enqueue(change func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queue = append(q.queue, change)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+// run 依次执行队列中的变化，队列为空时退出
+func (q *changeQueue) run() {
+	for {
+		q.mu.Lock()
+		if len(q.queue) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		change := q.queue[0]
+		q.queue = q.queue[1:]
+		q.mu.Unlock()
+
+		change()
+	}
+}
+
+// NewReadinessGate 创建节点准入，interval 为等待中节点的探测间隔
+func NewReadinessGate(ring hash.Router, client *http.Client, interval time.Duration) *ReadinessGate {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &ReadinessGate{
+		ring:     ring,
+		client:   client,
+		interval: interval,
+		timeout:  2 * time.Second,
+		pending:  make(map[string]*pendingNode),
+		members:  make(map[string]*memberState),
+		outlier:  DefaultOutlierConfig(),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetHandoff 设置缓存交接：节点加入、手动摘除、注销或权重变化时，先让新的归属节点拉取迁移的数据再切换路由
+func (g *ReadinessGate) SetHandoff(handoff *Handoffer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.handoff = handoff
+}
+
+// Offer 节点注册（或更新描述信息）：已加入的节点只更新描述信息，权重变化时在后台交接缓存后调整其在哈希环上的虚拟节点；
+// 其余节点进入等待列表并立即探测一次
+func (g *ReadinessGate) Offer(info *etcd.NodeInfo) {
+	node := info.Address
+
+	g.mu.Lock()
+	if m, ok := g.members[node]; ok {
+		if m.leaving {
+			// 注销后、移出哈希环前重新注册（如租约恢复），保留在路由中
+			m.leaving = false
+			logrus.Infof("[Readiness] Node %s re-registered before its removal completed, keeping it in the consistent hash ring", node)
+		}
+		if m.info.Weight != info.Weight {
+			g.changes.enqueue(func() { g.reweight(node, m) })
+		}
+		m.info = info
+		g.mu.Unlock()
+		return
+	}
+	if p, ok := g.pending[node]; ok {
+		p.info = info
+	} else {
+		g.pending[node] = &pendingNode{info: info, since: time.Now()}
+		logrus.Infof("[Readiness] Node %s (weight %d, zone %q, version %q) registered, waiting for readiness before routing traffic",
+			node, info.Weight, info.Zone, info.Version)
+	}
+	g.mu.Unlock()
+
+	g.probeAsync(node)
+}
+
+// reweight 成员变化队列中执行的权重变化：按最新注册的权重交接缓存后调整虚拟节点，
+// 权重增加时本节点从其他节点拉取，减少时其他节点从本节点拉取
+func (g *ReadinessGate) reweight(node string, m *memberState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	weight := m.info.Weight
+	if g.members[node] != m || m.leaving || m.ejection != nil || m.weight == weight {
+		return
+	}
+	current := g.routingNodes()
+	next := g.routingNodes()
+	next[node] = weight
+	targets, sources := []string{node}, otherNodes(current, node)
+	if weight < m.weight {
+		targets, sources = sources, targets
+	}
+	g.runHandoff(next, targets, sources)
+
+	// 交接期间节点可能已注销或被摘除
+	if g.members[node] != m || m.leaving || m.ejection != nil {
+		return
+	}
+	g.ring.AddWeighted(node, weight)
+	logrus.Infof("[Readiness] Node %s weight changed %d -> %d, consistent hash ring updated", node, m.weight, weight)
+	m.weight = weight
+}
+
+// Remove 节点注销：从等待列表中移除；节点仍在路由中时标记为退出中，在后台让其余节点拉取它负责的数据后
+// 再移出成员和哈希环，交接期间照常路由（节点已退出时请求由后继节点重试）
+func (g *ReadinessGate) Remove(node string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.pending, node)
+	m, ok := g.members[node]
+	if !ok || m.leaving {
+		return
+	}
+	if m.ejection != nil {
+		// 已摘除的节点不在哈希环中，无需交接
+		delete(g.members, node)
+		return
+	}
+	m.leaving = true
+	g.changes.enqueue(func() { g.leave(node, m) })
+}
+
+// leave 成员变化队列中执行的节点退出：其余节点拉取它负责的数据后将其移出成员和哈希环
+func (g *ReadinessGate) leave(node string, m *memberState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.members[node] != m || !m.leaving {
+		return
+	}
+	next := g.routingNodes()
+	delete(next, node)
+	g.runHandoff(next, otherNodes(next, node), []string{node})
+
+	// 交接期间节点可能重新注册
+	if g.members[node] != m || !m.leaving {
+		return
+	}
+	delete(g.members, node)
+	if m.ejection == nil {
+		g.ring.Remove(node)
+	}
+	logrus.Infof("[Readiness] Node %s removed from consistent hash ring", node)
+}
+
+// routingNodes 当前参与路由的节点及其在哈希环上的权重（不含被摘除的节点），调用方需持有 g.mu
+func (g *ReadinessGate) routingNodes() map[string]int {
+	nodes := make(map[string]int, len(g.members))
+	for node, m := range g.members {
+		if m.ejection == nil {
+			nodes[node] = m.weight
+		}
+	}
+	return nodes
+}
+
+// otherNodes 返回 nodes 中除 exclude 外的节点
+func otherNodes(nodes map[string]int, exclude string) []string {
+	result := make([]string, 0, len(nodes))
+	for node := range nodes {
+		if node != exclude {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// runHandoff 切换路由前交接缓存，next 为变化后参与路由的节点；只在成员变化队列中调用，
+// 调用方需持有 g.mu，交接期间暂时释放
+func (g *ReadinessGate) runHandoff(next map[string]int, targets, sources []string) {
+	if g.handoff == nil || len(targets) == 0 || len(sources) == 0 {
+		return
+	}
+	handoff := g.handoff
+	current := g.routingNodes()
+	g.mu.Unlock()
+	defer g.mu.Lock()
+	handoff.Run(current, next, targets, sources)
+}
+
+// Start 启动等待中节点的定期探测和已加入节点的主动健康探测
+func (g *ReadinessGate) Start() {
+	go g.monitor()
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				g.mu.Lock()
+				nodes := make([]string, 0, len(g.pending))
+				for node := range g.pending {
+					nodes = append(nodes, node)
+				}
+				g.mu.Unlock()
+				for _, node := range nodes {
+					g.probeAsync(node)
+				}
+			case <-g.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止探测
+func (g *ReadinessGate) Stop() {
+	close(g.stopCh)
+}
+
+// probeAsync 在后台探测一次，同一节点同时只有一个探测
+func (g *ReadinessGate) probeAsync(node string) {
+	g.mu.Lock()
+	p, ok := g.pending[node]
+	if !ok || p.probing {
+		g.mu.Unlock()
+		return
+	}
+	p.probing = true
+	g.mu.Unlock()
+
+	go func() {
+		err := g.probe(node)
+		if err == nil {
+			// 加入哈希环与其他成员变化按顺序执行，加入完成前该节点不再重复探测
+			g.changes.enqueue(func() { g.join(node, p) })
+			return
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		p.attempts++
+		p.probing = false
+		// 探测期间节点可能已注销
+		if g.pending[node] != p {
+			return
+		}
+		if p.lastError != err.Error() {
+			logrus.Infof("[Readiness] Node %s not ready: %v", node, err)
+		}
+		p.lastError = err.Error()
+	}()
+}
+
+// join 成员变化队列中执行的节点加入：从当前节点拉取将迁移到本节点的数据后加入哈希环
+func (g *ReadinessGate) join(node string, p *pendingNode) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p.attempts++
+	if g.pending[node] != p {
+		p.probing = false
+		return
+	}
+	current := g.routingNodes()
+	next := g.routingNodes()
+	next[node] = p.info.Weight
+	g.runHandoff(next, []string{node}, otherNodes(current, node))
+
+	p.probing = false
+	// 交接期间节点可能已注销
+	if g.pending[node] != p {
+		return
+	}
+	delete(g.pending, node)
+	g.members[node] = &memberState{info: p.info, weight: p.info.Weight}
+	g.ring.AddWeighted(node, p.info.Weight)
+	logrus.Infof("[Readiness] Node %s is ready after %v (%d probes), added to consistent hash ring with weight %d",
+		node, time.Since(p.since).Round(time.Millisecond), p.attempts, p.info.Weight)
+}
+
+// probe 请求节点的就绪检查接口，返回 200 表示就绪
+// 返回 404 的节点没有就绪检查接口（旧版本），视为就绪以保持兼容
+func (g *ReadinessGate) probe(node string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+node+ReadinessPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("readiness check returned status %d", resp.StatusCode)
+	}
+}
+
+// Pending 返回等待就绪的节点及其状态，供网关健康检查展示
+func (g *ReadinessGate) Pending() map[string]interface{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	result := make(map[string]interface{}, len(g.pending))
+	for node, p := range g.pending {
+		result[node] = map[string]interface{}{
+			"weight":     p.info.Weight,
+			"zone":       p.info.Zone,
+			"version":    p.info.Version,
+			"waiting":    time.Since(p.since).Round(time.Second).String(),
+			"attempts":   p.attempts,
+			"last_error": p.lastError,
+		}
+	}
+	return result
+}
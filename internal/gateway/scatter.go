@@ -0,0 +1,246 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"KamaitachiGo/internal/model"
+)
+
+// Group 归属于同一节点的一组subject
+type Group struct {
+	Node     string
+	Subjects []string
+}
+
+// Sender 向指定节点发送请求体，返回响应体
+type Sender func(node string, body []byte) ([]byte, error)
+
+// GroupSubjects 按归属节点拆分subjects，保持各节点首次出现的顺序
+func GroupSubjects(subjects []string, owner func(string) string) []Group {
+	index := make(map[string]int)
+	groups := make([]Group, 0)
+	for _, subject := range subjects {
+		node := owner(subject)
+		i, ok := index[node]
+		if !ok {
+			i = len(groups)
+			index[node] = i
+			groups = append(groups, Group{Node: node})
+		}
+		groups[i].Subjects = append(groups[i].Subjects, subject)
+	}
+	return groups
+}
+
+// ScatterSnapshot 将快照请求按节点拆分并行发送，合并后做全局排序与分页
+// 每个子请求取前 offset+limit 条，合并后再统一截取，保证分页结果与单节点查询一致
+func ScatterSnapshot(body []byte, groups []Group, send Sender) (*model.SnapshotResponse, error) {
+	var req model.SnapshotRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid snapshot request: %w", err)
+	}
+	req.ApplyDefaults()
+
+	sorts, err := model.ParseSortKeys(req.Field, int(req.Order))
+	if err != nil {
+		return nil, err
+	}
+
+	// 排序用到的指标若未在 ids 中请求，子请求需要一并取回，合并后再剔除
+	ids := splitIDs(req.IDs)
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+	subIDs := append([]string(nil), ids...)
+	for _, key := range sorts {
+		if _, meta := model.LookupMetaSortField(key.Field); !meta && !requested[key.Field] {
+			subIDs = append(subIDs, key.Field)
+		}
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid snapshot request: %w", err)
+	}
+	raw["ids"] = strings.Join(subIDs, ",")
+	// 报告期等元数据不在响应的指标中，按它们排序时要求节点附带排序值
+	for _, key := range sorts {
+		if field, ok := model.LookupMetaSortField(key.Field); ok && field.Record {
+			raw["sort_meta"] = true
+		}
+	}
+	raw["field"] = req.Field
+	raw["order"] = int(req.Order)
+	raw["offset"] = 0
+	raw["limit"] = req.Offset + req.Limit
+
+	parts := make([]*model.SnapshotResponse, len(groups))
+	err = fanOut(raw, groups, send, func(i int, respBody []byte) error {
+		var resp model.SnapshotResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return err
+		}
+		parts[i] = &resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if part.StatusCode != 0 {
+			return part, nil
+		}
+		// 还原元数据排序值，不向客户端输出
+		for _, record := range part.Data {
+			if meta := record.SortMeta; meta != nil {
+				record.ReportDate = meta.ReportDate
+				record.EndDate = meta.EndDate
+				record.DeclareDate = meta.DeclareDate
+				record.SortMeta = nil
+			}
+		}
+	}
+
+	merged := mergeSnapshotRecords(parts, sorts)
+	merged = model.PaginateSnapshot(merged, req.Offset, req.Limit)
+
+	// 剔除仅为排序取回的指标
+	for _, record := range merged {
+		for id := range record.Data {
+			if !requested[id] {
+				delete(record.Data, id)
+			}
+		}
+	}
+
+	return &model.SnapshotResponse{
+		StatusCode: 0,
+		StatusMsg:  "success",
+		Data:       merged,
+	}, nil
+}
+
+// ScatterPeriod 将区间请求按节点拆分并行发送，按请求中的subject顺序合并
+func ScatterPeriod(body []byte, groups []Group, send Sender) (*model.PeriodResponse, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid period request: %w", err)
+	}
+
+	parts := make([]*model.PeriodResponse, len(groups))
+	err := fanOut(raw, groups, send, func(i int, respBody []byte) error {
+		var resp model.PeriodResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return err
+		}
+		parts[i] = &resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bySubject := make(map[string]*model.PeriodRecord)
+	for _, part := range parts {
+		if part.StatusCode != 0 {
+			return part, nil
+		}
+		for _, record := range part.Data {
+			bySubject[record.Subject.Subject] = record
+		}
+	}
+
+	subjects, _ := raw["subjects"].(string)
+	records := make([]*model.PeriodRecord, 0, len(bySubject))
+	for _, subject := range model.SplitList(subjects) {
+		if record, ok := bySubject[subject]; ok {
+			records = append(records, record)
+		}
+	}
+
+	return &model.PeriodResponse{
+		StatusCode: 0,
+		StatusMsg:  "success",
+		Data:       records,
+	}, nil
+}
+
+// fanOut 为每个分组生成子请求并行发送，handle 处理第 i 个分组的响应体
+func fanOut(raw map[string]interface{}, groups []Group, send Sender, handle func(i int, respBody []byte) error) error {
+	bodies := make([][]byte, len(groups))
+	for i, group := range groups {
+		sub := make(map[string]interface{}, len(raw))
+		for k, v := range raw {
+			sub[k] = v
+		}
+		sub["subjects"] = strings.Join(group.Subjects, ",")
+		b, err := json.Marshal(sub)
+		if err != nil {
+			return err
+		}
+		bodies[i] = b
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(groups))
+	for i, group := range groups {
+		wg.Add(1)
+		go func(i int, node string) {
+			defer wg.Done()
+			respBody, err := send(node, bodies[i])
+			if err != nil {
+				errs[i] = fmt.Errorf("node %s: %w", node, err)
+				return
+			}
+			if err := handle(i, respBody); err != nil {
+				errs[i] = fmt.Errorf("node %s: invalid response: %w", node, err)
+			}
+		}(i, group.Node)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeSnapshotRecords 多路归并各节点已排好序的结果，比较规则与节点内排序相同（model.LessSnapshot）
+func mergeSnapshotRecords(parts []*model.SnapshotResponse, sorts []model.SortKey) []*model.SnapshotRecord {
+	heads := make([]int, len(parts))
+	total := 0
+	for _, part := range parts {
+		total += len(part.Data)
+	}
+
+	merged := make([]*model.SnapshotRecord, 0, total)
+	for len(merged) < total {
+		best := -1
+		for i, part := range parts {
+			if heads[i] >= len(part.Data) {
+				continue
+			}
+			if best < 0 || model.LessSnapshot(part.Data[heads[i]], parts[best].Data[heads[best]], sorts) {
+				best = i
+			}
+		}
+		merged = append(merged, parts[best].Data[heads[best]])
+		heads[best]++
+	}
+	return merged
+}
+
+// splitIDs 解析逗号分隔的指标ID，为空时使用默认指标
+func splitIDs(ids string) []string {
+	result := model.SplitList(ids)
+	if len(result) == 0 {
+		result = append(result, model.DefaultIndicatorIDs...)
+	}
+	return result
+}
@@ -0,0 +1,320 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"KamaitachiGo/internal/model"
+)
+
+// 分发查询的合并测试：每个节点按子请求排序、分页后返回，网关合并的结果必须与单节点对全部subject查询的结果一致。
+
+// scatterNodes 测试使用的节点，subject 按下标轮流归属
+var scatterNodes = []string{"node-a", "node-b", "node-c"}
+
+// fakeSlave 模拟各节点的查询：数据来自同一份 records，按子请求的 subjects、ids、排序与分页返回
+type fakeSlave struct {
+	records map[string]*model.SnapshotRecord
+	periods map[string]*model.PeriodRecord
+	fail    map[string]error // 节点返回的传输错误
+	status  map[string]int   // 节点返回的非零状态码
+
+	mu       sync.Mutex
+	requests []model.SnapshotRequest
+}
+
+func newFakeSlave() *fakeSlave {
+	f := &fakeSlave{
+		records: make(map[string]*model.SnapshotRecord),
+		periods: make(map[string]*model.PeriodRecord),
+	}
+	// operating_income 有并列值和缺失值，report_date 只有两个取值，排序时需要按 subject 打破并列
+	for i := 0; i < 8; i++ {
+		subject := fmt.Sprintf("33:%06d", i)
+		data := map[string]interface{}{"parent_holder_net_profit": float64(i * 7 % 5)}
+		if i != 3 {
+			data["operating_income"] = float64(i % 4 * 100)
+		}
+		f.records[subject] = &model.SnapshotRecord{
+			Subject:    &model.SubjectInfo{Subject: subject, Name: fmt.Sprintf("名称%d", i)},
+			Data:       data,
+			ReportDate: int64(1700000000 + i%2*86400),
+			EndDate:    fmt.Sprintf("2023-%02d-30", 6+i%2*3),
+		}
+		f.periods[subject] = &model.PeriodRecord{
+			Subject: &model.SubjectInfo{Subject: subject},
+			Data:    []*model.PeriodDataItem{{ReportDate: int64(1700000000 + i)}},
+		}
+	}
+	return f
+}
+
+// owner 按 subject 的序号分配节点
+func (f *fakeSlave) owner(subject string) string {
+	var i int
+	fmt.Sscanf(subject, "33:%d", &i)
+	return scatterNodes[i%len(scatterNodes)]
+}
+
+// query 单节点对 subjects 的快照查询结果：按完整数据排序分页，只返回 ids 中的指标
+func (f *fakeSlave) query(req model.SnapshotRequest) ([]*model.SnapshotRecord, error) {
+	req.ApplyDefaults()
+	sorts, err := model.ParseSortKeys(req.Field, int(req.Order))
+	if err != nil {
+		return nil, err
+	}
+	ids := splitIDs(req.IDs)
+	records := make([]*model.SnapshotRecord, 0)
+	for _, subject := range model.SplitList(req.Subjects) {
+		src, ok := f.records[subject]
+		if !ok {
+			continue
+		}
+		record := *src
+		if req.SortMeta {
+			record.SortMeta = &model.SnapshotSortMeta{ReportDate: src.ReportDate, EndDate: src.EndDate, DeclareDate: src.DeclareDate}
+		}
+		records = append(records, &record)
+	}
+	model.SortSnapshotRecords(records, sorts)
+	records = model.PaginateSnapshot(records, req.Offset, req.Limit)
+
+	for _, record := range records {
+		data := make(map[string]interface{})
+		for _, id := range ids {
+			if v, ok := record.Data[id]; ok {
+				data[id] = v
+			}
+		}
+		record.Data = data
+	}
+	return records, nil
+}
+
+// sendSnapshot 模拟节点处理快照子请求
+func (f *fakeSlave) sendSnapshot(node string, body []byte) ([]byte, error) {
+	if err := f.fail[node]; err != nil {
+		return nil, err
+	}
+	if code := f.status[node]; code != 0 {
+		return json.Marshal(&model.SnapshotResponse{StatusCode: code, StatusMsg: "failed on " + node})
+	}
+
+	var req model.SnapshotRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	for _, subject := range model.SplitList(req.Subjects) {
+		if owner := f.owner(subject); owner != node {
+			return nil, fmt.Errorf("subject %s sent to %s, owned by %s", subject, node, owner)
+		}
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	records, err := f.query(req)
+	if err != nil {
+		return json.Marshal(&model.SnapshotResponse{StatusCode: 400, StatusMsg: err.Error()})
+	}
+	return json.Marshal(&model.SnapshotResponse{StatusMsg: "success", Data: records})
+}
+
+// sendPeriod 模拟节点处理区间子请求，按节点收到的顺序返回
+func (f *fakeSlave) sendPeriod(node string, body []byte) ([]byte, error) {
+	if err := f.fail[node]; err != nil {
+		return nil, err
+	}
+	if code := f.status[node]; code != 0 {
+		return json.Marshal(&model.PeriodResponse{StatusCode: code, StatusMsg: "failed on " + node})
+	}
+
+	var req model.PeriodRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	records := make([]*model.PeriodRecord, 0)
+	for _, subject := range model.SplitList(req.Subjects) {
+		if owner := f.owner(subject); owner != node {
+			return nil, fmt.Errorf("subject %s sent to %s, owned by %s", subject, node, owner)
+		}
+		if record, ok := f.periods[subject]; ok {
+			records = append([]*model.PeriodRecord{record}, records...)
+		}
+	}
+	return json.Marshal(&model.PeriodResponse{StatusMsg: "success", Data: records})
+}
+
+// recordSubjects 返回记录的 subject 列表
+func recordSubjects(records []*model.SnapshotRecord) []string {
+	subjects := make([]string, len(records))
+	for i, record := range records {
+		subjects[i] = record.Subject.Subject
+	}
+	return subjects
+}
+
+func TestScatterSnapshotMatchesSingleNode(t *testing.T) {
+	allSubjects := make([]string, 0, 8)
+	for i := 7; i >= 0; i-- {
+		allSubjects = append(allSubjects, fmt.Sprintf("33:%06d", i))
+	}
+	all := strings.Join(allSubjects, ",")
+
+	tests := []struct {
+		name string
+		req  model.SnapshotRequest
+	}{
+		{name: "default sort", req: model.SnapshotRequest{Subjects: all, Limit: 3}},
+		{name: "offset inside the first node's page", req: model.SnapshotRequest{Subjects: all, Offset: 2, Limit: 3}},
+		{name: "offset near the end", req: model.SnapshotRequest{Subjects: all, Offset: 6, Limit: 5}},
+		{name: "offset past the end", req: model.SnapshotRequest{Subjects: all, Offset: 20, Limit: 5}},
+		{name: "limit covers everything", req: model.SnapshotRequest{Subjects: all, Limit: 100}},
+		{name: "ascending with missing values", req: model.SnapshotRequest{Subjects: all, Order: 1, Limit: 4}},
+		{
+			name: "sort by an indicator that was not requested",
+			req:  model.SnapshotRequest{Subjects: all, IDs: "operating_income", Field: "parent_holder_net_profit", Offset: 1, Limit: 4},
+		},
+		{
+			name: "sort by report date with ties",
+			req:  model.SnapshotRequest{Subjects: all, IDs: "operating_income", Field: "report_date", Order: 1, Offset: 1, Limit: 5},
+		},
+		{
+			name: "multiple sort keys",
+			req:  model.SnapshotRequest{Subjects: all, Field: "end_date desc, operating_income asc, name", Offset: 3, Limit: 4},
+		},
+		{name: "single subject", req: model.SnapshotRequest{Subjects: "33:000004", Limit: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSlave()
+			body, err := json.Marshal(&tt.req)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			groups := GroupSubjects(model.SplitList(tt.req.Subjects), f.owner)
+
+			resp, err := ScatterSnapshot(body, groups, f.sendSnapshot)
+			if err != nil {
+				t.Fatalf("scatter: %v", err)
+			}
+			if resp.StatusCode != 0 {
+				t.Fatalf("scatter returned status %d: %s", resp.StatusCode, resp.StatusMsg)
+			}
+
+			want, err := f.query(tt.req)
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			if got, wantSubjects := recordSubjects(resp.Data), recordSubjects(want); !reflect.DeepEqual(got, wantSubjects) {
+				t.Fatalf("scatter returned %v, want %v", got, wantSubjects)
+			}
+			for i, record := range resp.Data {
+				if !reflect.DeepEqual(record.Data, want[i].Data) {
+					t.Errorf("%s data is %v, want %v", record.Subject.Subject, record.Data, want[i].Data)
+				}
+				if record.SortMeta != nil {
+					t.Errorf("%s still carries sort metadata", record.Subject.Subject)
+				}
+			}
+
+			// 每个子请求都从头取 offset+limit 条
+			req := tt.req
+			req.ApplyDefaults()
+			if len(f.requests) != len(groups) {
+				t.Fatalf("sent %d sub-requests, want %d", len(f.requests), len(groups))
+			}
+			for _, sub := range f.requests {
+				if sub.Offset != 0 || sub.Limit != req.Offset+req.Limit {
+					t.Errorf("sub-request offset/limit is %d/%d, want 0/%d", sub.Offset, sub.Limit, req.Offset+req.Limit)
+				}
+			}
+		})
+	}
+}
+
+func TestScatterSnapshotNodeFailure(t *testing.T) {
+	errDown := errors.New("connection refused")
+	tests := []struct {
+		name       string
+		fail       map[string]error
+		status     map[string]int
+		wantErr    bool
+		wantStatus int
+	}{
+		{name: "transport error", fail: map[string]error{"node-b": errDown}, wantErr: true},
+		{name: "error status", status: map[string]int{"node-c": 500}, wantStatus: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSlave()
+			f.fail, f.status = tt.fail, tt.status
+			body := []byte(`{"subjects":"33:000000,33:000001,33:000002","limit":10}`)
+			groups := GroupSubjects([]string{"33:000000", "33:000001", "33:000002"}, f.owner)
+
+			resp, err := ScatterSnapshot(body, groups, f.sendSnapshot)
+			if tt.wantErr {
+				if !errors.Is(err, errDown) {
+					t.Fatalf("scatter returned %v, want %v", err, errDown)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scatter: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("scatter returned status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestScatterPeriodKeepsRequestOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		subjects string
+		want     []string
+	}{
+		{
+			name:     "interleaved owners",
+			subjects: "33:000005,33:000000,33:000004,33:000001,33:000003",
+			want:     []string{"33:000005", "33:000000", "33:000004", "33:000001", "33:000003"},
+		},
+		{
+			name:     "duplicates and unknown subjects",
+			subjects: "33:000002, 33:000099,33:000002,33:000006",
+			want:     []string{"33:000002", "33:000006"},
+		},
+		{name: "single node", subjects: "33:000006,33:000003,33:000000", want: []string{"33:000006", "33:000003", "33:000000"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSlave()
+			body, err := json.Marshal(&model.PeriodRequest{Subjects: tt.subjects, From: 1, To: 2})
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			groups := GroupSubjects(model.SplitList(tt.subjects), f.owner)
+
+			resp, err := ScatterPeriod(body, groups, f.sendPeriod)
+			if err != nil {
+				t.Fatalf("scatter: %v", err)
+			}
+			got := make([]string, len(resp.Data))
+			for i, record := range resp.Data {
+				got[i] = record.Subject.Subject
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("scatter returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
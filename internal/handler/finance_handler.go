@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -57,15 +58,7 @@ func (h *FinanceHandler) Snapshot(c *gin.Context) {
 	c.Request.Body = io.NopCloser(strings.NewReader(string(raw)))
 
 	// 设置默认值
-	if req.Field == "" {
-		req.Field = "operating_income"
-	}
-	if req.Order == 0 {
-		req.Order = -1
-	}
-	if req.Limit == 0 {
-		req.Limit = 10
-	}
+	req.ApplyDefaults()
 
 	logrus.Debugf("snapshot request: ids=%s, subjects=%s, topic=%s, field=%s, order=%d, offset=%d, limit=%d",
 		req.IDs, req.Subjects, req.Topic, req.Field, req.Order, req.Offset, req.Limit)
@@ -74,12 +67,15 @@ func (h *FinanceHandler) Snapshot(c *gin.Context) {
 	if err != nil {
 		logrus.Errorf("query snapshot error: %v", err)
 		c.JSON(http.StatusOK, model.SnapshotResponse{
-			StatusCode: 500,
+			StatusCode: errorStatusCode(err),
 			StatusMsg:  fmt.Sprintf("query error: %v", err),
 			Data:       nil,
 		})
 		return
 	}
+	if response.StatusCode == 0 {
+		h.service.AccessLog().RecordSnapshot(&req)
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -105,12 +101,15 @@ func (h *FinanceHandler) Period(c *gin.Context) {
 	if err != nil {
 		logrus.Errorf("query period error: %v", err)
 		c.JSON(http.StatusOK, model.PeriodResponse{
-			StatusCode: 500,
+			StatusCode: errorStatusCode(err),
 			StatusMsg:  fmt.Sprintf("query error: %v", err),
 			Data:       nil,
 		})
 		return
 	}
+	if response.StatusCode == 0 {
+		h.service.AccessLog().RecordPeriod(&req)
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -138,3 +137,12 @@ func (h *FinanceHandler) ResetCacheStats(c *gin.Context) {
 		"status_msg":  "cache stats reset successfully",
 	})
 }
+
+// errorStatusCode 从错误中提取业务状态码，参数错误等返回对应的状态码，其余按500处理
+func errorStatusCode(err error) int {
+	var kerr *model.KamaitachiError
+	if errors.As(err, &kerr) {
+		return kerr.Code
+	}
+	return 500
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"KamaitachiGo/internal/cache/snapshot"
+	"KamaitachiGo/internal/model"
+	"KamaitachiGo/internal/service"
+	"KamaitachiGo/pkg/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HandoffHandler 节点间缓存交接接口：网关在成员变化、切换路由前通知新的归属节点，
+// 由其从原归属节点拉取迁移的 StockDataMap 条目（数据格式与快照文件相同）
+type HandoffHandler struct {
+	manager *snapshot.Manager
+	client  *http.Client // 不设整体超时，拉取的截止时间由交接请求决定
+	token   string       // 节点间内部接口的共享令牌，拉取时携带
+
+	mu        sync.Mutex
+	exporting int       // 正在进行的导出数
+	exported  time.Time // 最近一次导出结束的时间
+}
+
+// NewHandoffHandler 创建缓存交接处理器，token 为节点间内部接口的共享令牌
+func NewHandoffHandler(manager *snapshot.Manager, token string) *HandoffHandler {
+	return &HandoffHandler{
+		manager: manager,
+		client:  &http.Client{},
+		token:   token,
+	}
+}
+
+// Export 以快照格式导出按请求中的路由归属于 target 的缓存条目
+// POST /kamaitachi/internal/v1/cache/export
+func (h *HandoffHandler) Export(c *gin.Context) {
+	h.mu.Lock()
+	h.exporting++
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		h.exporting--
+		h.exported = time.Now()
+		h.mu.Unlock()
+	}()
+
+	var req model.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.error(c, 400, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	owned, err := ownedBy(&req.Ring, req.Target)
+	if err != nil {
+		h.error(c, 400, err)
+		return
+	}
+
+	start := time.Now()
+	c.Header("Content-Type", "application/octet-stream")
+	c.Status(http.StatusOK)
+	count, err := h.manager.Export(c.Writer, owned)
+	if err != nil {
+		// 响应已开始发送，接收方会因缺少结束块而识别出数据不完整
+		logrus.Errorf("[Handoff] Failed to export entries to %s: %v", req.Target, err)
+		return
+	}
+	logrus.Infof("[Handoff] Exported %d entries to %s in %v", count, req.Target, time.Since(start).Round(time.Millisecond))
+}
+
+// Handoff 从各来源节点拉取按请求中的路由归属于本节点的条目；拉取失败的来源记入结果的 missing，
+// 这部分数据不做补救（本地快照只含本节点自己的数据），切换路由后按需从数据库加载。
+// 来源必须是变化前后路由中的节点，来源返回的条目中不归属于本节点的被丢弃
+// POST /kamaitachi/internal/v1/cache/handoff
+func (h *HandoffHandler) Handoff(c *gin.Context) {
+	var req model.HandoffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.error(c, 400, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	owned, err := ownedBy(&req.Ring, req.Target)
+	if err != nil {
+		h.error(c, 400, err)
+		return
+	}
+	for _, source := range req.Sources {
+		if source == req.Target || !req.Member(source) {
+			h.error(c, 400, fmt.Errorf("source %s is not a member of the ring", source))
+			return
+		}
+	}
+
+	// 网关超时断开连接或超过请求中的剩余时间后，停止拉取和导入，已导入的条目保留
+	ctx := c.Request.Context()
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Millisecond)
+		defer cancel()
+	}
+
+	result := &model.HandoffResult{Target: req.Target, Sources: make(map[string]string, len(req.Sources))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	pulled := 0
+	for _, source := range req.Sources {
+		wg.Add(1)
+		go func(source string) {
+			defer wg.Done()
+			count, err := h.pull(ctx, source, &model.ExportRequest{Ring: req.Ring, Target: req.Target}, owned)
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Imported += count
+			if err != nil {
+				result.Sources[source] = fmt.Sprintf("%d entries, error: %v", count, err)
+				result.Missing = append(result.Missing, source)
+				logrus.Warnf("[Handoff] Failed to pull entries from %s: %v", source, err)
+				return
+			}
+			pulled++
+			result.Sources[source] = strconv.Itoa(count) + " entries"
+		}(source)
+	}
+	wg.Wait()
+
+	if len(result.Missing) > 0 {
+		sort.Strings(result.Missing)
+		logrus.Warnf("[Handoff] Imported %d entries from %d/%d nodes, entries owned by %v were not handed off and will be loaded on demand",
+			result.Imported, pulled, len(req.Sources), result.Missing)
+	} else {
+		logrus.Infof("[Handoff] Imported %d entries from %d/%d nodes", result.Imported, pulled, len(req.Sources))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "success",
+		"data":        result,
+	})
+}
+
+// pull 从来源节点拉取条目，只导入 owned 接受的部分；ctx 结束时停止
+func (h *HandoffHandler) pull(ctx context.Context, source string, req *model.ExportRequest, owned func(key string) bool) (int, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+source+model.ExportPath, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(model.InternalTokenHeader, h.token)
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/octet-stream" {
+		return 0, fmt.Errorf("unexpected export response (status %d)", resp.StatusCode)
+	}
+	return h.manager.Import(ctx, resp.Body, owned)
+}
+
+// ownedBy 按路由描述建出路由，返回判断缓存Key是否归属于 target 的函数
+func ownedBy(ring *model.RingSpec, target string) (func(key string) bool, error) {
+	if target == "" || len(ring.Nodes) == 0 {
+		return nil, fmt.Errorf("target and ring nodes are required")
+	}
+	router, err := ring.Router()
+	if err != nil {
+		return nil, err
+	}
+	return func(key string) bool {
+		return router.Get(service.CacheRouteKey(key)) == target
+	}, nil
+}
+
+// Drain 节点注销后等待其他节点拉取完本节点的数据：自 since 之后有导出完成、且已空闲 quiet 时返回 true；
+// 超过 timeout 仍未满足时返回 false（网关未启用交接、交接超时或本节点没有需要迁移的数据）
+func (h *HandoffHandler) Drain(since time.Time, timeout, quiet time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		h.mu.Lock()
+		drained := h.exporting == 0 && h.exported.After(since) && time.Since(h.exported) >= quiet
+		h.mu.Unlock()
+		if drained {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		<-ticker.C
+	}
+}
+
+// error 返回错误响应
+func (h *HandoffHandler) error(c *gin.Context, code int, err error) {
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": code,
+		"status_msg":  err.Error(),
+	})
+}
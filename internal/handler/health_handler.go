@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+
+	"KamaitachiGo/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessCheck 就绪检查项，Check 返回 nil 表示通过
+type ReadinessCheck struct {
+	Name  string
+	Check func() error
+}
+
+// HealthHandler 健康检查处理器：存活检查只表示进程可以响应，就绪检查全部通过后节点才应接收流量
+type HealthHandler struct {
+	service      *service.FinanceService
+	checks       []ReadinessCheck
+	registration func() map[string]interface{}
+}
+
+// NewHealthHandler 创建健康检查处理器
+func NewHealthHandler(service *service.FinanceService, checks ...ReadinessCheck) *HealthHandler {
+	return &HealthHandler{
+		service: service,
+		checks:  checks,
+	}
+}
+
+// SetRegistrationStatus 设置服务注册状态来源，健康检查接口附带展示；注册状态不影响就绪检查，
+// 租约丢失时网关会随注册信息的删除移除本节点，重新注册后再次经过就绪检查加入
+func (h *HealthHandler) SetRegistrationStatus(status func() map[string]interface{}) {
+	h.registration = status
+}
+
+// runChecks 执行所有就绪检查，返回是否全部通过和每项的结果
+func (h *HealthHandler) runChecks() (bool, map[string]string) {
+	ready := true
+	results := make(map[string]string, len(h.checks))
+	for _, check := range h.checks {
+		if err := check.Check(); err != nil {
+			ready = false
+			results[check.Name] = err.Error()
+		} else {
+			results[check.Name] = "ok"
+		}
+	}
+	return ready, results
+}
+
+// Health 健康检查，附带就绪检查结果、缓存预热进度和服务注册状态
+// GET /health
+func (h *HealthHandler) Health(c *gin.Context) {
+	ready, checks := h.runChecks()
+	body := gin.H{
+		"status": "ok",
+		"ready":  ready,
+		"checks": checks,
+		"warmup": h.service.WarmupStatus(),
+	}
+	if h.registration != nil {
+		body["registration"] = h.registration()
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// Live 存活检查
+// GET /health/live
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "alive",
+	})
+}
+
+// Ready 就绪检查，未就绪时返回 503，网关据此决定是否将节点加入哈希环
+// GET /health/ready
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ready, checks := h.runChecks()
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not_ready",
+			"checks": checks,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ready",
+		"checks": checks,
+	})
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"KamaitachiGo/internal/model"
 	"KamaitachiGo/internal/service"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -42,6 +43,11 @@ func (h *SelectionHandler) SelectionSnapshot(c *gin.Context) {
 	response, err := h.selectionService.SelectionSnapshot(&request)
 	if err != nil {
 		logrus.Errorf("Failed to query selection snapshot: %v", err)
+		var kerr *model.KamaitachiError
+		if errors.As(err, &kerr) && kerr.Code != 500 {
+			c.JSON(http.StatusOK, model.NewErrorSelectionResponse(kerr.Code, kerr.Message))
+			return
+		}
 		c.JSON(http.StatusOK, model.NewErrorSelectionResponse(500, "server error"))
 		return
 	}
@@ -70,6 +76,11 @@ func (h *SelectionHandler) SelectionPeriod(c *gin.Context) {
 	response, err := h.selectionService.SelectionPeriod(&request)
 	if err != nil {
 		logrus.Errorf("Failed to query selection period: %v", err)
+		var kerr *model.KamaitachiError
+		if errors.As(err, &kerr) && kerr.Code != 500 {
+			c.JSON(http.StatusOK, model.NewErrorSelectionResponse(kerr.Code, kerr.Message))
+			return
+		}
 		c.JSON(http.StatusOK, model.NewErrorSelectionResponse(500, "server error"))
 		return
 	}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"KamaitachiGo/internal/cache/snapshot"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SnapshotHandler 缓存快照管理接口
+type SnapshotHandler struct {
+	manager *snapshot.Manager
+}
+
+// NewSnapshotHandler 创建缓存快照管理处理器
+func NewSnapshotHandler(manager *snapshot.Manager) *SnapshotHandler {
+	return &SnapshotHandler{
+		manager: manager,
+	}
+}
+
+// List 列出快照信息和所有快照代
+// GET /kamaitachi/api/data/v1/cache/snapshots
+func (h *SnapshotHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "success",
+		"data":        h.manager.GetSnapshotInfo(),
+	})
+}
+
+// Save 立即保存一代快照，可通过 ?id= 指定编号（网关协调备份时各节点使用同一编号）
+// POST /kamaitachi/api/data/v1/cache/snapshots
+func (h *SnapshotHandler) Save(c *gin.Context) {
+	gen, err := h.manager.SaveGeneration(c.Query("id"))
+	if err != nil {
+		logrus.Errorf("Failed to save snapshot: %v", err)
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "success",
+		"data":        gen,
+	})
+}
+
+// Load 加载指定的一代快照并合并到当前缓存，id 为 latest 时加载最新可用的一代
+// POST /kamaitachi/api/data/v1/cache/snapshots/:id/load
+func (h *SnapshotHandler) Load(c *gin.Context) {
+	id := c.Param("id")
+	var count int
+	var err error
+	if id == "latest" {
+		count, err = h.manager.Load()
+	} else {
+		count, err = h.manager.LoadGeneration(id)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to load snapshot %s: %v", id, err)
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "success",
+		"data": gin.H{
+			"id":       id,
+			"restored": count,
+		},
+	})
+}
+
+// Delete 删除指定的一代快照
+// DELETE /kamaitachi/api/data/v1/cache/snapshots/:id
+func (h *SnapshotHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.manager.DeleteGeneration(id); err != nil {
+		logrus.Errorf("Failed to delete snapshot %s: %v", id, err)
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": 0,
+		"status_msg":  "success",
+	})
+}
+
+// error 按错误类型返回对应的业务状态码
+func (h *SnapshotHandler) error(c *gin.Context, err error) {
+	code := 500
+	switch {
+	case errors.Is(err, snapshot.ErrInvalidGeneration):
+		code = 400
+	case errors.Is(err, snapshot.ErrGenerationNotFound):
+		code = 404
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status_code": code,
+		"status_msg":  err.Error(),
+	})
+}
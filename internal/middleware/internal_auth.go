@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"KamaitachiGo/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalAuth 节点间内部接口的鉴权：请求头 model.InternalTokenHeader 须与配置的共享令牌一致，
+// 未配置令牌时拒绝所有请求，避免内部接口被当作对外接口调用
+func InternalAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader(model.InternalTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status_code": 403,
+				"status_msg":  "forbidden: internal endpoint",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -47,6 +47,37 @@ func (o *Order) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// DefaultIndicatorIDs 请求未指定 ids 时使用的默认指标
+var DefaultIndicatorIDs = []string{"operating_income", "parent_holder_net_profit"}
+
+// RouteKey 请求的路由Key，网关转发、节点预热和缓存交接判断归属都使用这一规则：
+// 主题池请求（topic 非空）由节点忽略 subjects 整体查询并缓存在主题名下，因此取主题名；
+// 否则取第一个subject。两者都没有时返回空串，由调用方自行选择
+func RouteKey(topic string, subjects []string) string {
+	if topic != "" {
+		return topic
+	}
+	if len(subjects) > 0 {
+		return subjects[0]
+	}
+	return ""
+}
+
+// SplitList 解析逗号分隔的列表（subjects、ids），去除空白与重复项
+func SplitList(list string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+	for _, part := range strings.Split(list, ",") {
+		item := strings.TrimSpace(part)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return result
+}
+
 // SnapshotRequest 快照查询请求
 type SnapshotRequest struct {
 	IDs       string `json:"ids"`       // "operating_income,parent_holder_net_profit"
@@ -58,6 +89,22 @@ type SnapshotRequest struct {
 	Offset    int    `json:"offset"`    // 分页偏移
 	Limit     int    `json:"limit"`     // 返回数量
 	Timestamp int64  `json:"timestamp"` // 0=最新
+
+	// SortMeta 网关分发的子请求设置，要求节点在每条记录中附带元数据排序值（SnapshotRecord.SortMeta），供网关全局归并排序
+	SortMeta bool `json:"sort_meta,omitempty"`
+}
+
+// ApplyDefaults 填充排序字段、排序方向与返回数量的默认值
+func (r *SnapshotRequest) ApplyDefaults() {
+	if r.Field == "" {
+		r.Field = "operating_income"
+	}
+	if r.Order == 0 {
+		r.Order = -1
+	}
+	if r.Limit == 0 {
+		r.Limit = 10
+	}
 }
 
 // SnapshotResponse 快照查询响应
@@ -71,6 +118,56 @@ type SnapshotResponse struct {
 type SnapshotRecord struct {
 	Subject *SubjectInfo           `json:"subject"`
 	Data    map[string]interface{} `json:"data"`
+
+	// 以下字段仅用于服务端按元数据排序，不输出
+	ReportDate  int64  `json:"-"`
+	EndDate     string `json:"-"`
+	DeclareDate int64  `json:"-"`
+
+	// SortMeta 元数据排序值，只在请求设置了 sort_meta 时输出，网关读取后还原到上面的字段并在响应中去除
+	SortMeta *SnapshotSortMeta `json:"sort_meta,omitempty"`
+}
+
+// SnapshotSortMeta 快照记录的元数据排序值
+type SnapshotSortMeta struct {
+	ReportDate  int64  `json:"report_date"`
+	EndDate     string `json:"end_date,omitempty"`
+	DeclareDate int64  `json:"declare_date,omitempty"`
+}
+
+// MetaSortField 快照查询的元数据排序字段（不属于指标）
+type MetaSortField struct {
+	Name   string // 排序字段名
+	Column string // finance_data 中对应的列
+	Record bool   // 不在快照响应中输出，网关按它排序时需要节点通过 sort_meta 附带排序值
+}
+
+// MetaSortFields 快照查询支持的元数据排序字段，网关、服务层与仓储层的字段集合都由它派生
+var MetaSortFields = []MetaSortField{
+	{Name: "subject", Column: "subject_key"},
+	{Name: "name", Column: "stock_name"},
+	{Name: "report_date", Column: "report_date", Record: true},
+	{Name: "end_date", Column: "end_date", Record: true},
+	{Name: "declare_date", Column: "declare_date", Record: true},
+}
+
+// LookupMetaSortField 按名称查找元数据排序字段
+func LookupMetaSortField(name string) (MetaSortField, bool) {
+	for _, field := range MetaSortFields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return MetaSortField{}, false
+}
+
+// MetaSortFieldNames 返回元数据排序字段名
+func MetaSortFieldNames() []string {
+	names := make([]string, len(MetaSortFields))
+	for i, field := range MetaSortFields {
+		names[i] = field.Name
+	}
+	return names
 }
 
 // PeriodRequest 区间查询请求
@@ -97,13 +194,59 @@ type PeriodRecord struct {
 
 // PeriodDataItem 区间数据项
 type PeriodDataItem struct {
-	EndDate               string  `json:"end_date"`
-	Period                string  `json:"period"`
-	DeclareDate           string  `json:"declare_date"`
-	Year                  string  `json:"year"`
-	ParentHolderNetProfit float64 `json:"parent_holder_net_profit"`
-	OperatingIncome       float64 `json:"operating_income"`
-	Combine               string  `json:"combine"`
+	EndDate     string                 `json:"end_date"`
+	Period      string                 `json:"period"`
+	DeclareDate string                 `json:"declare_date"`
+	Year        string                 `json:"year"`
+	Combine     string                 `json:"combine"`
+	Values      map[string]interface{} `json:"-"` // 请求的指标值，序列化时平铺到顶层
+	ReportDate  int64                  `json:"-"` // 报告期时间戳，用于区间缓存，不输出
+}
+
+// periodDataItemFields PeriodDataItem 的固定字段，用于区分平铺的指标值
+type periodDataItemFields struct {
+	EndDate     string `json:"end_date"`
+	Period      string `json:"period"`
+	DeclareDate string `json:"declare_date"`
+	Year        string `json:"year"`
+	Combine     string `json:"combine"`
+}
+
+// MarshalJSON 将固定字段与指标值输出为同一层级的JSON对象
+func (p PeriodDataItem) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(p.Values)+5)
+	for id, v := range p.Values {
+		out[id] = v
+	}
+	out["end_date"] = p.EndDate
+	out["period"] = p.Period
+	out["declare_date"] = p.DeclareDate
+	out["year"] = p.Year
+	out["combine"] = p.Combine
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 解析固定字段，其余字段作为指标值
+func (p *PeriodDataItem) UnmarshalJSON(b []byte) error {
+	var fields periodDataItemFields
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	for _, key := range []string{"end_date", "period", "declare_date", "year", "combine"} {
+		delete(all, key)
+	}
+
+	p.EndDate = fields.EndDate
+	p.Period = fields.Period
+	p.DeclareDate = fields.DeclareDate
+	p.Year = fields.Year
+	p.Combine = fields.Combine
+	p.Values = all
+	return nil
 }
 
 // SubjectInfo 证券信息
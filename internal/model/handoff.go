@@ -0,0 +1,66 @@
+package model
+
+import "KamaitachiGo/pkg/hash"
+
+// 缓存交接接口（Slave），属于节点间内部接口，不在对外的 API 分组中，请求须携带 InternalTokenHeader
+const (
+	HandoffPath = "/kamaitachi/internal/v1/cache/handoff"
+	ExportPath  = "/kamaitachi/internal/v1/cache/export"
+
+	// InternalTokenHeader 节点间内部接口的共享令牌请求头，值为配置中的 internal_token
+	InternalTokenHeader = "X-Kamaitachi-Internal-Token"
+)
+
+// RingSpec 路由描述：策略、虚拟节点倍数和节点权重，网关与 Slave 据此建出相同的路由
+type RingSpec struct {
+	Strategy string         `json:"strategy"`
+	Replicas int            `json:"replicas"`
+	Nodes    map[string]int `json:"nodes"` // 节点地址到权重
+}
+
+// Router 按描述建出路由
+func (s *RingSpec) Router() (hash.Router, error) {
+	router, err := hash.NewRouter(s.Strategy, s.Replicas)
+	if err != nil {
+		return nil, err
+	}
+	for node, weight := range s.Nodes {
+		router.AddWeighted(node, weight)
+	}
+	return router, nil
+}
+
+// ExportRequest 导出请求：返回按 Ring 路由归属于 Target 的缓存条目
+type ExportRequest struct {
+	Ring   RingSpec `json:"ring"`
+	Target string   `json:"target"`
+}
+
+// HandoffRequest 交接请求：Target 按变化后的路由 Ring 从 Sources 拉取归属于自己的缓存条目，
+// 不可用的来源记入结果的 Missing，这部分数据切换路由后按需从数据库加载。
+// Sources 必须是变化前（Previous）或变化后（Ring.Nodes）参与路由的节点，节点退出时来源只在 Previous 中
+type HandoffRequest struct {
+	Ring     RingSpec       `json:"ring"`
+	Previous map[string]int `json:"previous"` // 变化前参与路由的节点及权重
+	Target   string         `json:"target"`
+	Sources  []string       `json:"sources"`
+	Timeout  int64          `json:"timeout_ms,omitempty"` // 交接的剩余时间（毫秒），超过后节点停止拉取和导入；用相对时间避免节点间时钟偏差
+}
+
+// Member 节点是否在变化前或变化后的路由中
+func (r *HandoffRequest) Member(node string) bool {
+	if _, ok := r.Ring.Nodes[node]; ok {
+		return true
+	}
+	_, ok := r.Previous[node]
+	return ok
+}
+
+// HandoffResult 一个节点的交接结果
+type HandoffResult struct {
+	Target   string            `json:"target"`
+	Imported int               `json:"imported"`          // 从来源节点拉取并恢复的条目数
+	Sources  map[string]string `json:"sources,omitempty"` // 来源节点到结果（条目数或错误）
+	Missing  []string          `json:"missing,omitempty"` // 拉取失败的来源节点，其数据未交接
+	Error    string            `json:"error,omitempty"`
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortKey 排序键
+type SortKey struct {
+	Field string `json:"field"` // 排序字段
+	Desc  bool   `json:"desc"`  // 是否倒序
+}
+
+// String 返回 "field asc|desc" 形式
+func (k SortKey) String() string {
+	if k.Desc {
+		return k.Field + " desc"
+	}
+	return k.Field + " asc"
+}
+
+// ParseSortKeys 解析排序规格，如 "operating_income desc, subject asc"
+// 未写方向的字段使用 defaultOrder（-1=倒序，其余为正序）
+func ParseSortKeys(spec string, defaultOrder int) ([]SortKey, error) {
+	keys := make([]SortKey, 0)
+	for _, part := range strings.Split(spec, ",") {
+		tokens := strings.Fields(part)
+		if len(tokens) == 0 {
+			continue
+		}
+		if len(tokens) > 2 {
+			return nil, ErrInvalidParameter(fmt.Sprintf("invalid sort key: %q", strings.TrimSpace(part)))
+		}
+
+		key := SortKey{Field: tokens[0], Desc: defaultOrder == -1}
+		if len(tokens) == 2 {
+			switch strings.ToLower(tokens[1]) {
+			case "desc", "-1":
+				key.Desc = true
+			case "asc", "1":
+				key.Desc = false
+			default:
+				return nil, ErrInvalidParameter(fmt.Sprintf("invalid sort direction %q for field %s", tokens[1], tokens[0]))
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// FormatSortKeys 将排序键格式化为规范字符串，用于生成缓存Key
+func FormatSortKeys(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ErrUnknownSortField 未知排序字段错误，附带允许的字段列表
+func ErrUnknownSortField(field string, allowed []string) error {
+	return &KamaitachiError{
+		Code:    400,
+		Message: fmt.Sprintf("unknown sort field: %s (allowed: %s)", field, strings.Join(allowed, ",")),
+	}
+}
+
+// SortValue 取快照记录中排序字段的值，缺失时返回 nil
+// 报告期与公告日期按数值比较，没有公告日期视为缺失
+func (r *SnapshotRecord) SortValue(field string) interface{} {
+	switch field {
+	case "subject":
+		return r.Subject.Subject
+	case "name":
+		return r.Subject.Name
+	case "report_date":
+		return float64(r.ReportDate)
+	case "end_date":
+		return r.EndDate
+	case "declare_date":
+		if r.DeclareDate <= 0 {
+			return nil
+		}
+		return float64(r.DeclareDate)
+	}
+	if v, ok := r.Data[field]; ok {
+		return v
+	}
+	return nil
+}
+
+// CompareSortValues 比较两个排序值：nil 最小（与 SQLite 升序时 NULL 在前一致），字符串按字典序，数值按大小
+func CompareSortValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if sa, ok := a.(string); ok {
+		sb, _ := b.(string)
+		return strings.Compare(sa, sb)
+	}
+	fa, _ := a.(float64)
+	fb, _ := b.(float64)
+	switch {
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	}
+	return 0
+}
+
+// LessSnapshot 判断快照记录 a 是否应排在 b 之前，语义与SQL的 ORDER BY 一致，最后按 subject 升序保证分页结果稳定
+// 节点内排序与网关多路归并共用，保证分发查询与单节点查询的结果顺序相同
+func LessSnapshot(a, b *SnapshotRecord, sorts []SortKey) bool {
+	for _, key := range sorts {
+		c := CompareSortValues(a.SortValue(key.Field), b.SortValue(key.Field))
+		if c != 0 {
+			if key.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+	}
+	return a.Subject.Subject < b.Subject.Subject
+}
+
+// SortSnapshotRecords 按排序键对快照记录排序
+func SortSnapshotRecords(records []*SnapshotRecord, sorts []SortKey) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return LessSnapshot(records[i], records[j], sorts)
+	})
+}
+
+// PaginateSnapshot 对排序后的快照记录分页，limit<=0 时返回 offset 之后的全部结果
+func PaginateSnapshot(records []*SnapshotRecord, offset, limit int) []*SnapshotRecord {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(records) {
+		return []*SnapshotRecord{}
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(records) {
+		end = len(records)
+	}
+	return records[offset:end]
+}
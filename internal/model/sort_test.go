@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// 排序规格解析与快照分页的测试
+
+func TestParseSortKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         string
+		defaultOrder int
+		want         []SortKey
+		wantErr      bool
+	}{
+		{name: "empty", spec: "", want: []SortKey{}},
+		{name: "only separators", spec: " , ,", want: []SortKey{}},
+		{name: "default descending", spec: "operating_income", defaultOrder: -1, want: []SortKey{{Field: "operating_income", Desc: true}}},
+		{name: "default ascending", spec: "operating_income", defaultOrder: 1, want: []SortKey{{Field: "operating_income"}}},
+		{
+			name:         "explicit directions override the default",
+			spec:         "operating_income DESC, subject asc",
+			defaultOrder: 1,
+			want:         []SortKey{{Field: "operating_income", Desc: true}, {Field: "subject"}},
+		},
+		{
+			name:         "numeric directions",
+			spec:         "report_date -1,subject 1",
+			defaultOrder: -1,
+			want:         []SortKey{{Field: "report_date", Desc: true}, {Field: "subject"}},
+		},
+		{name: "too many tokens", spec: "operating_income desc nulls", wantErr: true},
+		{name: "missing separator", spec: "operating_income desc subject", wantErr: true},
+		{name: "unknown direction", spec: "operating_income down", wantErr: true},
+		{name: "bad direction in later key", spec: "subject asc, report_date 0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseSortKeys(tt.spec, tt.defaultOrder)
+			if tt.wantErr {
+				var kerr *KamaitachiError
+				if !errors.As(err, &kerr) || kerr.Code != 400 {
+					t.Fatalf("ParseSortKeys(%q) = %v, %v; want an invalid parameter error", tt.spec, keys, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSortKeys(%q): %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Fatalf("ParseSortKeys(%q) = %v, want %v", tt.spec, keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginateSnapshot(t *testing.T) {
+	records := make([]*SnapshotRecord, 5)
+	for i := range records {
+		records[i] = &SnapshotRecord{Subject: &SubjectInfo{Subject: string(rune('a' + i))}}
+	}
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		want          string
+	}{
+		{name: "first page", offset: 0, limit: 2, want: "ab"},
+		{name: "middle page", offset: 2, limit: 2, want: "cd"},
+		{name: "last partial page", offset: 4, limit: 2, want: "e"},
+		{name: "limit exceeds total", offset: 1, limit: 100, want: "bcde"},
+		{name: "no limit", offset: 3, limit: 0, want: "de"},
+		{name: "negative limit", offset: 0, limit: -1, want: "abcde"},
+		{name: "negative offset", offset: -3, limit: 2, want: "ab"},
+		{name: "offset at the end", offset: 5, limit: 2, want: ""},
+		{name: "offset past the end", offset: 9, limit: 2, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := PaginateSnapshot(records, tt.offset, tt.limit)
+			if page == nil {
+				t.Fatalf("PaginateSnapshot(%d, %d) returned nil", tt.offset, tt.limit)
+			}
+			got := ""
+			for _, record := range page {
+				got += record.Subject.Subject
+			}
+			if got != tt.want {
+				t.Fatalf("PaginateSnapshot(%d, %d) = %q, want %q", tt.offset, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
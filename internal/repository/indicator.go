@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"KamaitachiGo/internal/model"
+)
+
+// Indicator 指标定义：对外指标ID到存储指标（或派生表达式）的映射
+type Indicator struct {
+	ID      string                     // 对外指标ID，如 "operating_income"
+	Columns []string                   // 依赖的存储指标：宽表列名或窄表中的 indicator_id
+	Derive  func(refs []string) string // 派生表达式，参数为 Columns 对应的SQL引用；nil 表示直接取 Columns[0]
+}
+
+// expr 根据存储指标的SQL引用生成指标表达式
+func (ind *Indicator) expr(refs []string) string {
+	if ind.Derive == nil {
+		return refs[0]
+	}
+	return ind.Derive(refs)
+}
+
+// indicatorRegistry 代码中声明的指标；窄表中登记的指标无需在此声明
+var indicatorRegistry = map[string]*Indicator{}
+
+func init() {
+	// 存储指标
+	for _, column := range []string{
+		"operating_income",
+		"total_operating_cost",
+		"operating_profit",
+		"total_profit",
+		"net_profit",
+		"parent_holder_net_profit",
+	} {
+		registerIndicator(&Indicator{ID: column, Columns: []string{column}})
+	}
+
+	// 派生指标
+	registerIndicator(&Indicator{
+		ID:      "gross_profit",
+		Columns: []string{"operating_income", "total_operating_cost"},
+		Derive: func(refs []string) string {
+			return fmt.Sprintf("(%s - %s)", refs[0], refs[1])
+		},
+	})
+	registerIndicator(&Indicator{
+		ID:      "net_profit_margin",
+		Columns: []string{"parent_holder_net_profit", "operating_income"},
+		Derive: func(refs []string) string {
+			return fmt.Sprintf("(%s / NULLIF(%s, 0))", refs[0], refs[1])
+		},
+	})
+}
+
+// registerIndicator 注册指标
+func registerIndicator(ind *Indicator) {
+	indicatorRegistry[ind.ID] = ind
+}
+
+// LookupIndicator 按ID查找代码中声明的指标定义
+func LookupIndicator(id string) (*Indicator, bool) {
+	ind, ok := indicatorRegistry[id]
+	return ind, ok
+}
+
+// IndicatorIDs 返回当前数据库可用的指标ID（已排序）
+func (r *SQLiteRepository) IndicatorIDs() []string {
+	seen := make(map[string]bool)
+	for id, ind := range indicatorRegistry {
+		if len(r.missingColumns(ind)) == 0 {
+			seen[id] = true
+		}
+	}
+	_, catalog := r.schema()
+	for id := range catalog {
+		seen[id] = true
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ParseIndicatorIDs 解析逗号分隔的指标ID列表，去除空白与重复项，为空时返回默认指标
+func ParseIndicatorIDs(ids string) []string {
+	result := model.SplitList(ids)
+	if len(result) == 0 {
+		result = append(result, model.DefaultIndicatorIDs...)
+	}
+	return result
+}
+
+// ResolveIndicators 将指标ID解析为指标定义，未知或当前数据库不支持的指标逐个报错
+func (r *SQLiteRepository) ResolveIndicators(ids []string) ([]*Indicator, error) {
+	indicators := make([]*Indicator, 0, len(ids))
+	var problems []string
+	for _, id := range ids {
+		ind, ok := r.lookupIndicator(id)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown indicator id: %s", id))
+			continue
+		}
+		if missing := r.missingColumns(ind); len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("indicator %s not available (missing columns: %s)", id, strings.Join(missing, ",")))
+			continue
+		}
+		indicators = append(indicators, ind)
+	}
+	if len(problems) > 0 {
+		return nil, model.ErrInvalidParameter(strings.Join(problems, "; "))
+	}
+	return indicators, nil
+}
+
+// lookupIndicator 先查代码中声明的指标，再查窄表指标目录
+func (r *SQLiteRepository) lookupIndicator(id string) (*Indicator, bool) {
+	if ind, ok := LookupIndicator(id); ok {
+		return ind, true
+	}
+	if _, catalog := r.schema(); catalog[id] {
+		return &Indicator{ID: id, Columns: []string{id}}, true
+	}
+	return nil, false
+}
+
+// missingColumns 返回指标依赖但当前数据库中不存在的存储指标
+func (r *SQLiteRepository) missingColumns(ind *Indicator) []string {
+	columns, catalog := r.schema()
+	if columns == nil && catalog == nil {
+		return nil
+	}
+	var missing []string
+	for _, column := range ind.Columns {
+		if !catalog[column] && !columns[column] {
+			missing = append(missing, column)
+		}
+	}
+	return missing
+}
+
+// columnRef 返回存储指标在快照/区间查询中的SQL引用（finance_data 别名为 f1）
+// 窄表登记的指标优先，通过 (subject_key, report_date, indicator_id) 主键逐行取值；
+// 宽表同时存在同名列时，窄表中没有值的行回退到宽表列（两种布局导入的数据可以混合存在）
+func (r *SQLiteRepository) columnRef(column string) string {
+	columns, catalog := r.schema()
+	if !catalog[column] {
+		return "f1." + column
+	}
+	narrow := fmt.Sprintf(`(SELECT value FROM finance_indicator
+				WHERE subject_key = f1.subject_key AND report_date = f1.report_date AND indicator_id = '%s')`,
+		strings.ReplaceAll(column, "'", "''"))
+	if columns[column] {
+		return fmt.Sprintf("COALESCE(%s, f1.%s)", narrow, column)
+	}
+	return narrow
+}
+
+// indicatorExpr 返回指标的SQL表达式
+func (r *SQLiteRepository) indicatorExpr(ind *Indicator) string {
+	refs := make([]string, len(ind.Columns))
+	for i, column := range ind.Columns {
+		refs[i] = r.columnRef(column)
+	}
+	return ind.expr(refs)
+}
+
+// indicatorSelectList 生成指标投影列表，列别名为 ind_<序号>
+func (r *SQLiteRepository) indicatorSelectList(indicators []*Indicator) string {
+	parts := make([]string, len(indicators))
+	for i, ind := range indicators {
+		parts[i] = fmt.Sprintf("%s AS ind_%d", r.indicatorExpr(ind), i)
+	}
+	return strings.Join(parts, ",\n\t\t\t")
+}
@@ -10,12 +10,12 @@ import (
 
 // MemoryRepository 内存存储仓库
 type MemoryRepository struct {
-	cache *lru.Cache
+	cache lru.Store
 	mu    sync.RWMutex
 }
 
 // NewMemoryRepository 创建内存仓库
-func NewMemoryRepository(cache *lru.Cache) *MemoryRepository {
+func NewMemoryRepository(cache lru.Store) *MemoryRepository {
 	return &MemoryRepository{
 		cache: cache,
 	}
@@ -1,18 +1,28 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"KamaitachiGo/internal/model"
 
+	"github.com/sirupsen/logrus"
 	_ "modernc.org/sqlite"
 )
 
 type SQLiteRepository struct {
 	db *sql.DB
+
+	// 表结构与指标目录，重新加载时整体替换，不原地修改
+	mu      sync.RWMutex
+	columns map[string]bool // finance_data 宽表中已存在的列
+	catalog map[string]bool // finance_indicator 窄表中登记的指标（来自 indicator_catalog）
+
+	stopCh chan struct{} // 关闭后台重新加载协程
 }
 
 func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
@@ -29,18 +39,169 @@ func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(time.Hour)
 
-	return &SQLiteRepository{db: db}, nil
+	repo := &SQLiteRepository{db: db}
+	if err := repo.ReloadSchema(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// ReloadSchema 重新读取宽表列与窄表指标目录，导入新的指标或列后无需重启即可查询
+func (r *SQLiteRepository) ReloadSchema() error {
+	if err := r.loadColumns(); err != nil {
+		return err
+	}
+	return r.loadCatalog()
+}
+
+// StartSchemaReload 启动后台协程，按 interval 定期重新读取表结构与指标目录
+func (r *SQLiteRepository) StartSchemaReload(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	r.StopSchemaReload()
+
+	stopCh := make(chan struct{})
+	r.mu.Lock()
+	r.stopCh = stopCh
+	r.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.ReloadSchema(); err != nil {
+					logrus.Warnf("Failed to reload indicator catalog: %v", err)
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// StopSchemaReload 停止后台重新加载协程
+func (r *SQLiteRepository) StopSchemaReload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopCh != nil {
+		close(r.stopCh)
+		r.stopCh = nil
+	}
+}
+
+// schema 返回当前的宽表列与指标目录，返回的集合不会再被修改
+func (r *SQLiteRepository) schema() (columns, catalog map[string]bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.columns, r.catalog
+}
+
+// hasColumn 宽表中是否存在该列
+func (r *SQLiteRepository) hasColumn(name string) bool {
+	columns, _ := r.schema()
+	return columns[name]
+}
+
+// loadColumns 读取 finance_data 表结构，用于判断指标是否可用
+func (r *SQLiteRepository) loadColumns() error {
+	rows, err := r.db.Query("PRAGMA table_info(finance_data)")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 表不存在时不做限制，由查询本身返回错误
+	if len(columns) == 0 {
+		columns = nil
+	}
+	r.mu.Lock()
+	r.columns = columns
+	r.mu.Unlock()
+	return nil
+}
+
+// loadCatalog 读取窄表指标目录，目录中的指标无需修改代码即可查询
+func (r *SQLiteRepository) loadCatalog() error {
+	var name string
+	err := r.db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='indicator_catalog'").Scan(&name)
+	if err == sql.ErrNoRows {
+		r.mu.Lock()
+		r.catalog = nil
+		r.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.Query("SELECT indicator_id FROM indicator_catalog")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	catalog := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		catalog[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.catalog = catalog
+	r.mu.Unlock()
+	return nil
 }
 
 func (r *SQLiteRepository) Close() error {
+	r.StopSchemaReload()
 	return r.db.Close()
 }
 
-// QuerySnapshot 快照查询
-func (r *SQLiteRepository) QuerySnapshot(subjects []string, field string, order int, offset, limit int) ([]*model.SnapshotRecord, error) {
+// Ping 检查数据库是否可用：能够在超时时间内读取 finance_data 表（表为空也视为可用）
+func (r *SQLiteRepository) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var one int
+	err := r.db.QueryRowContext(ctx, "SELECT 1 FROM finance_data LIMIT 1").Scan(&one)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// QuerySnapshot 批量查询各subject的最新报告（不排序、不分页），asOf>0 时返回截至该时间点已披露的最新报告
+// 结果按 subject 缓存后由服务层统一排序分页，没有数据的 subject 不返回记录
+func (r *SQLiteRepository) QuerySnapshot(subjects []string, indicators []*Indicator, asOf int64) ([]*model.SnapshotRecord, error) {
 	if len(subjects) == 0 {
 		return nil, fmt.Errorf("subjects cannot be empty")
 	}
+	if len(indicators) == 0 {
+		return nil, fmt.Errorf("indicators cannot be empty")
+	}
 
 	// 构建IN子句的占位符
 	placeholders := make([]string, len(subjects))
@@ -50,10 +211,8 @@ func (r *SQLiteRepository) QuerySnapshot(subjects []string, field string, order
 		args[i] = subject
 	}
 
-	orderClause := "DESC"
-	if order > 0 {
-		orderClause = "ASC"
-	}
+	asOfClause, asOfArgs := r.asOfCondition(asOf)
+	args = append(args, asOfArgs...)
 
 	// 使用子查询获取每个subject的最新数据
 	query := fmt.Sprintf(`
@@ -61,21 +220,19 @@ func (r *SQLiteRepository) QuerySnapshot(subjects []string, field string, order
 			f1.subject_key,
 			f1.stock_name,
 			f1.end_date,
-			f1.operating_income,
-			f1.parent_holder_net_profit,
-			f1.category
+			f1.category,
+			f1.report_date,
+			%s,
+			%s
 		FROM finance_data f1
 		INNER JOIN (
 			SELECT subject_key, MAX(report_date) as max_date
 			FROM finance_data
-			WHERE subject_key IN (%s)
+			WHERE subject_key IN (%s)%s
 			GROUP BY subject_key
 		) f2 ON f1.subject_key = f2.subject_key AND f1.report_date = f2.max_date
-		ORDER BY f1.%s %s
-		LIMIT ? OFFSET ?
-	`, strings.Join(placeholders, ","), field, orderClause)
-
-	args = append(args, limit, offset)
+		ORDER BY f1.subject_key
+	`, r.declareDateColumn(), r.indicatorSelectList(indicators), strings.Join(placeholders, ","), asOfClause)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
@@ -83,52 +240,17 @@ func (r *SQLiteRepository) QuerySnapshot(subjects []string, field string, order
 	}
 	defer rows.Close()
 
-	var records []*model.SnapshotRecord
-	for rows.Next() {
-		record := &model.SnapshotRecord{
-			Subject: &model.SubjectInfo{},
-			Data:    make(map[string]interface{}),
-		}
-
-		var subjectKey, endDate, category string
-		var stockName sql.NullString
-		var income, profit sql.NullFloat64
-
-		err := rows.Scan(&subjectKey, &stockName, &endDate, &income, &profit, &category)
-		if err != nil {
-			return nil, err
-		}
-
-		// 填充Subject信息
-		record.Subject.Subject = subjectKey
-		if stockName.Valid {
-			record.Subject.Name = stockName.String
-		} else {
-			record.Subject.Name = subjectKey
-		}
-		record.Subject.Status = "213001"
-		record.Subject.ListingDate = ""
-		record.Subject.Category = category
-
-		// 填充Data
-		if income.Valid {
-			record.Data["operating_income"] = income.Float64
-		}
-		if profit.Valid {
-			record.Data["parent_holder_net_profit"] = profit.Float64
-		}
-
-		records = append(records, record)
-	}
-
-	return records, nil
+	return scanSnapshotRows(rows, indicators)
 }
 
 // QueryPeriod 区间查询
-func (r *SQLiteRepository) QueryPeriod(subjects []string, fromDate, toDate int64) ([]*model.PeriodRecord, error) {
+func (r *SQLiteRepository) QueryPeriod(subjects []string, indicators []*Indicator, fromDate, toDate int64) ([]*model.PeriodRecord, error) {
 	if len(subjects) == 0 {
 		return nil, fmt.Errorf("subjects cannot be empty")
 	}
+	if len(indicators) == 0 {
+		return nil, fmt.Errorf("indicators cannot be empty")
+	}
 
 	placeholders := make([]string, len(subjects))
 	args := make([]interface{}, len(subjects)+2)
@@ -141,18 +263,19 @@ func (r *SQLiteRepository) QueryPeriod(subjects []string, fromDate, toDate int64
 
 	query := fmt.Sprintf(`
 		SELECT 
-			subject_key,
-			stock_name,
-			end_date,
-			period,
-			year,
-			operating_income,
-			parent_holder_net_profit
-		FROM finance_data
-		WHERE subject_key IN (%s)
-		  AND report_date BETWEEN ? AND ?
-		ORDER BY subject_key, report_date DESC
-	`, strings.Join(placeholders, ","))
+			f1.subject_key,
+			f1.stock_name,
+			f1.end_date,
+			f1.period,
+			f1.year,
+			f1.report_date,
+			%s,
+			%s
+		FROM finance_data f1
+		WHERE f1.subject_key IN (%s)
+		  AND f1.report_date BETWEEN ? AND ?
+		ORDER BY f1.subject_key, f1.report_date DESC
+	`, r.declareDateColumn(), r.indicatorSelectList(indicators), strings.Join(placeholders, ","))
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
@@ -162,14 +285,19 @@ func (r *SQLiteRepository) QueryPeriod(subjects []string, fromDate, toDate int64
 
 	// 按subject分组
 	recordMap := make(map[string]*model.PeriodRecord)
+	values := make([]sql.NullFloat64, len(indicators))
 
 	for rows.Next() {
 		var subjectKey, endDate, period, year string
 		var stockName sql.NullString
-		var income, profit sql.NullFloat64
+		var reportDate int64
+		var declareDate sql.NullInt64
 
-		err := rows.Scan(&subjectKey, &stockName, &endDate, &period, &year, &income, &profit)
-		if err != nil {
+		dest := []interface{}{&subjectKey, &stockName, &endDate, &period, &year, &reportDate, &declareDate}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 
@@ -196,20 +324,18 @@ func (r *SQLiteRepository) QueryPeriod(subjects []string, fromDate, toDate int64
 		dataItem := &model.PeriodDataItem{
 			EndDate:     endDate,
 			Period:      period,
-			DeclareDate: "",
+			DeclareDate: formatDeclareDate(declareDate),
 			Year:        year,
 			Combine:     fmt.Sprintf("%s:%s_%s", subjectKey, year, period),
-		}
-
-		if income.Valid {
-			dataItem.OperatingIncome = income.Float64
-		}
-		if profit.Valid {
-			dataItem.ParentHolderNetProfit = profit.Float64
+			Values:      indicatorValues(indicators, values),
+			ReportDate:  reportDate,
 		}
 
 		record.Data = append(record.Data, dataItem)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	// 转换为切片
 	records := make([]*model.PeriodRecord, 0, len(recordMap))
@@ -220,77 +346,162 @@ func (r *SQLiteRepository) QueryPeriod(subjects []string, fromDate, toDate int64
 	return records, nil
 }
 
-// QueryByTopic 主题池查询（全市场）
-func (r *SQLiteRepository) QueryByTopic(topic string, field string, order int, offset, limit int) ([]*model.SnapshotRecord, error) {
-	orderClause := "DESC"
-	if order > 0 {
-		orderClause = "ASC"
+// QueryByTopic 主题池查询（全市场），asOf 语义同 QuerySnapshot
+func (r *SQLiteRepository) QueryByTopic(topic string, indicators []*Indicator, sorts []model.SortKey, offset, limit int, asOf int64) ([]*model.SnapshotRecord, error) {
+	if len(indicators) == 0 {
+		return nil, fmt.Errorf("indicators cannot be empty")
+	}
+
+	orderBy, err := r.orderByClause(sorts)
+	if err != nil {
+		return nil, err
 	}
 
+	asOfClause, asOfArgs := r.asOfCondition(asOf)
+	args := append([]interface{}{topic}, asOfArgs...)
+	args = append(args, limit, offset)
+
 	query := fmt.Sprintf(`
 		SELECT 
 			f1.subject_key,
 			f1.stock_name,
 			f1.end_date,
-			f1.operating_income,
-			f1.parent_holder_net_profit,
-			f1.category
+			f1.category,
+			f1.report_date,
+			%s,
+			%s
 		FROM finance_data f1
 		INNER JOIN (
 			SELECT subject_key, MAX(report_date) as max_date
 			FROM finance_data
-			WHERE topic = ?
+			WHERE topic = ?%s
 			GROUP BY subject_key
 		) f2 ON f1.subject_key = f2.subject_key AND f1.report_date = f2.max_date
-		ORDER BY f1.%s %s
+		ORDER BY %s
 		LIMIT ? OFFSET ?
-	`, field, orderClause)
+	`, r.declareDateColumn(), r.indicatorSelectList(indicators), asOfClause, orderBy)
 
-	rows, err := r.db.Query(query, topic, limit, offset)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var records []*model.SnapshotRecord
-	for rows.Next() {
-		record := &model.SnapshotRecord{
-			Subject: &model.SubjectInfo{},
-			Data:    make(map[string]interface{}),
+	return scanSnapshotRows(rows, indicators)
+}
+
+// asOfCondition 生成时点查询条件：报告期不晚于 asOf，且有公告日期时公告日期不晚于 asOf（避免前视偏差）
+func (r *SQLiteRepository) asOfCondition(asOf int64) (string, []interface{}) {
+	if asOf <= 0 {
+		return "", nil
+	}
+	if r.hasColumn("declare_date") {
+		return " AND report_date <= ? AND (declare_date IS NULL OR declare_date <= ?)", []interface{}{asOf, asOf}
+	}
+	return " AND report_date <= ?", []interface{}{asOf}
+}
+
+// orderByClause 生成快照查询的排序子句
+// 只接受元数据字段和可用指标，SQL片段全部来自白名单，不拼接请求中的原始字符串
+func (r *SQLiteRepository) orderByClause(sorts []model.SortKey) (string, error) {
+	parts := make([]string, 0, len(sorts)+1)
+	for _, key := range sorts {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
 		}
 
+		if field, ok := model.LookupMetaSortField(key.Field); ok {
+			if key.Field == "declare_date" && !r.hasColumn("declare_date") {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("f1.%s %s", field.Column, direction))
+			continue
+		}
+		ind, ok := r.lookupIndicator(key.Field)
+		if !ok || len(r.missingColumns(ind)) > 0 {
+			return "", model.ErrInvalidParameter(fmt.Sprintf("unknown sort field: %s", key.Field))
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", r.indicatorExpr(ind), direction))
+	}
+
+	// 以 subject 作为最终排序键，保证分页结果稳定
+	parts = append(parts, "f1.subject_key ASC")
+	return strings.Join(parts, ", "), nil
+}
+
+// declareDateColumn 返回公告日期列，旧数据库没有该列时返回 NULL
+func (r *SQLiteRepository) declareDateColumn() string {
+	if r.hasColumn("declare_date") {
+		return "f1.declare_date"
+	}
+	return "NULL"
+}
+
+// formatDeclareDate 将公告日期时间戳格式化为日期字符串
+func formatDeclareDate(declareDate sql.NullInt64) string {
+	if !declareDate.Valid || declareDate.Int64 <= 0 {
+		return ""
+	}
+	return time.Unix(declareDate.Int64, 0).Format("2006-01-02")
+}
+
+// scanSnapshotRows 读取快照查询结果，按请求的指标填充 Data
+func scanSnapshotRows(rows *sql.Rows, indicators []*Indicator) ([]*model.SnapshotRecord, error) {
+	var records []*model.SnapshotRecord
+	values := make([]sql.NullFloat64, len(indicators))
+
+	for rows.Next() {
 		var subjectKey, endDate, category string
 		var stockName sql.NullString
-		var income, profit sql.NullFloat64
+		var reportDate int64
+		var declareDate sql.NullInt64
 
-		err := rows.Scan(&subjectKey, &stockName, &endDate, &income, &profit, &category)
-		if err != nil {
+		dest := []interface{}{&subjectKey, &stockName, &endDate, &category, &reportDate, &declareDate}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 
-		record.Subject.Subject = subjectKey
+		record := &model.SnapshotRecord{
+			Subject: &model.SubjectInfo{
+				Subject:     subjectKey,
+				Name:        subjectKey,
+				Status:      "213001",
+				ListingDate: "",
+				Category:    category,
+			},
+			Data:        indicatorValues(indicators, values),
+			ReportDate:  reportDate,
+			EndDate:     endDate,
+			DeclareDate: declareDate.Int64,
+		}
 		if stockName.Valid {
 			record.Subject.Name = stockName.String
-		} else {
-			record.Subject.Name = subjectKey
-		}
-		record.Subject.Status = "213001"
-		record.Subject.ListingDate = ""
-		record.Subject.Category = category
-
-		if income.Valid {
-			record.Data["operating_income"] = income.Float64
-		}
-		if profit.Valid {
-			record.Data["parent_holder_net_profit"] = profit.Float64
 		}
 
 		records = append(records, record)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return records, nil
 }
 
+// indicatorValues 将扫描到的指标值按指标ID组装，NULL值不输出
+func indicatorValues(indicators []*Indicator, values []sql.NullFloat64) map[string]interface{} {
+	data := make(map[string]interface{}, len(indicators))
+	for i, ind := range indicators {
+		if values[i].Valid {
+			data[ind.ID] = values[i].Float64
+		}
+	}
+	return data
+}
+
 // GetStats 获取统计信息
 func (r *SQLiteRepository) GetStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -305,5 +516,8 @@ func (r *SQLiteRepository) GetStats() (map[string]interface{}, error) {
 	r.db.QueryRow("SELECT COUNT(DISTINCT stock_code) FROM finance_data").Scan(&stockCount)
 	stats["stock_count"] = stockCount
 
+	// 可用指标
+	stats["indicators"] = r.IndicatorIDs()
+
 	return stats, nil
 }
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"KamaitachiGo/internal/model"
+	"KamaitachiGo/pkg/json"
+)
+
+// AccessEntry 访问记录中的一项：单个subject的快照/区间查询，或一个主题池查询
+type AccessEntry struct {
+	Kind      string      `json:"kind"` // snapshot/period/topic
+	Subject   string      `json:"subject,omitempty"`
+	Topic     string      `json:"topic,omitempty"`
+	Subjects  string      `json:"subjects,omitempty"` // 主题池查询的原始 subjects，网关据此路由
+	IDs       string      `json:"ids"`
+	Field     string      `json:"field,omitempty"`
+	Order     model.Order `json:"order,omitempty"`
+	Offset    int         `json:"offset,omitempty"`
+	Limit     int         `json:"limit,omitempty"`
+	Timestamp int64       `json:"timestamp,omitempty"`
+	From      int64       `json:"from,omitempty"`
+	To        int64       `json:"to,omitempty"`
+	Count     int64       `json:"count"`
+
+	id string // key() 的缓存，加入访问记录时设置
+}
+
+// key 除访问次数外的所有字段组成的Key
+func (e *AccessEntry) key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s|%d|%d|%d|%d|%d|%d",
+		e.Kind, e.Subject, e.Topic, e.Subjects, e.IDs, e.Field, e.Order, e.Offset, e.Limit, e.Timestamp, e.From, e.To)
+}
+
+// AccessLog 记录外部请求访问最多的查询，退出时持久化前N项，下次启动据此预热
+// 多subject请求按subject拆开记录，预热时只查询本节点负责的subject
+type AccessLog struct {
+	mu      sync.Mutex
+	top     int
+	entries map[string]*AccessEntry
+	pruning atomic.Bool // 后台裁剪是否正在进行
+}
+
+// NewAccessLog 创建访问记录，top 为持久化和预热的条目数
+func NewAccessLog(top int) *AccessLog {
+	if top <= 0 {
+		top = 1000
+	}
+	return &AccessLog{
+		top:     top,
+		entries: make(map[string]*AccessEntry),
+	}
+}
+
+// LoadAccessLog 从文件恢复上次运行的访问记录，访问次数减半，使长期不再访问的查询逐渐被新的热点替换
+func LoadAccessLog(path string, top int) (*AccessLog, error) {
+	l := NewAccessLog(top)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	var entries []*AccessEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return l, fmt.Errorf("failed to unmarshal access log %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		entry.Count = (entry.Count + 1) / 2
+		entry.id = entry.key()
+		l.entries[entry.id] = entry
+	}
+	return l, nil
+}
+
+// RecordSnapshot 记录一次快照查询
+func (l *AccessLog) RecordSnapshot(req *model.SnapshotRequest) {
+	if l == nil {
+		return
+	}
+	if req.Topic != "" {
+		l.record(&AccessEntry{
+			Kind:      "topic",
+			Topic:     req.Topic,
+			Subjects:  req.Subjects,
+			IDs:       req.IDs,
+			Field:     req.Field,
+			Order:     req.Order,
+			Offset:    req.Offset,
+			Limit:     req.Limit,
+			Timestamp: req.Timestamp,
+		})
+		return
+	}
+	for _, subject := range model.SplitList(req.Subjects) {
+		l.record(&AccessEntry{Kind: "snapshot", Subject: subject, IDs: req.IDs, Timestamp: req.Timestamp})
+	}
+}
+
+// RecordPeriod 记录一次区间查询
+func (l *AccessLog) RecordPeriod(req *model.PeriodRequest) {
+	if l == nil {
+		return
+	}
+	for _, subject := range model.SplitList(req.Subjects) {
+		l.record(&AccessEntry{Kind: "period", Subject: subject, IDs: req.IDs, From: req.From, To: req.To})
+	}
+}
+
+// record 累加访问次数；条目数超过上限时在后台裁剪，请求路径上只做一次map查找
+func (l *AccessLog) record(entry *AccessEntry) {
+	key := entry.key()
+
+	l.mu.Lock()
+	if existing, ok := l.entries[key]; ok {
+		existing.Count++
+		l.mu.Unlock()
+		return
+	}
+	entry.id = key
+	entry.Count = 1
+	l.entries[key] = entry
+	over := len(l.entries) > 4*l.top
+	l.mu.Unlock()
+
+	if over && l.pruning.CompareAndSwap(false, true) {
+		go l.prune()
+	}
+}
+
+// prune 只保留访问最多的 2*top 项，排序在锁外进行
+// 排序期间新增的访问次数可能随被裁剪的条目丢失，对热点统计没有影响
+func (l *AccessLog) prune() {
+	defer l.pruning.Store(false)
+
+	entries := l.sorted()
+	if len(entries) <= 2*l.top {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, dropped := range entries[2*l.top:] {
+		delete(l.entries, dropped.id)
+	}
+}
+
+// sorted 复制当前的全部条目，在锁外按访问次数从多到少排序
+func (l *AccessLog) sorted() []*AccessEntry {
+	l.mu.Lock()
+	entries := make([]*AccessEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	l.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].id < entries[j].id
+	})
+	return entries
+}
+
+// Top 返回访问最多的前N项（副本）
+func (l *AccessLog) Top() []*AccessEntry {
+	entries := l.sorted()
+	if len(entries) > l.top {
+		entries = entries[:l.top]
+	}
+	return entries
+}
+
+// Save 将访问最多的前N项写入文件
+func (l *AccessLog) Save(path string) error {
+	entries := l.Top()
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, path)
+}
+
+// Spec 将访问记录转换为预热配置：相同指标、时点或区间的subject合并为一个请求
+func (l *AccessLog) Spec() *WarmupSpec {
+	spec := &WarmupSpec{}
+	snapshots := make(map[string]*model.SnapshotRequest)
+	periods := make(map[string]*model.PeriodRequest)
+	for _, entry := range l.Top() {
+		switch entry.Kind {
+		case "topic":
+			spec.Snapshots = append(spec.Snapshots, &model.SnapshotRequest{
+				IDs:       entry.IDs,
+				Subjects:  entry.Subjects,
+				Topic:     entry.Topic,
+				Field:     entry.Field,
+				Order:     entry.Order,
+				Offset:    entry.Offset,
+				Limit:     entry.Limit,
+				Timestamp: entry.Timestamp,
+			})
+		case "snapshot":
+			key := fmt.Sprintf("%s|%d", entry.IDs, entry.Timestamp)
+			if req, ok := snapshots[key]; ok {
+				req.Subjects += "," + entry.Subject
+				continue
+			}
+			req := &model.SnapshotRequest{IDs: entry.IDs, Subjects: entry.Subject, Timestamp: entry.Timestamp}
+			snapshots[key] = req
+			spec.Snapshots = append(spec.Snapshots, req)
+		case "period":
+			key := fmt.Sprintf("%s|%d|%d", entry.IDs, entry.From, entry.To)
+			if req, ok := periods[key]; ok {
+				req.Subjects += "," + entry.Subject
+				continue
+			}
+			req := &model.PeriodRequest{IDs: entry.IDs, Subjects: entry.Subject, From: entry.From, To: entry.To}
+			periods[key] = req
+			spec.Periods = append(spec.Periods, req)
+		}
+	}
+	return spec
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"unsafe"
+
+	"KamaitachiGo/internal/model"
+)
+
+// 以下估算按 64 位 Go 运行时的内存布局计算缓存数据的堆占用，用于 LRU 的容量统计
+const (
+	pointerSize     = int(unsafe.Sizeof(uintptr(0)))
+	stringHeader    = int(unsafe.Sizeof(""))
+	sliceHeader     = int(unsafe.Sizeof([]byte(nil)))
+	interfaceHeader = int(unsafe.Sizeof(interface{}(nil)))
+
+	// mapOverhead map 头部与初始桶的开销
+	mapOverhead = 48 + 8*(8+2*pointerSize)
+	// mapEntryOverhead 每个 map 元素除 key/value 之外的开销（tophash 与装载因子留出的空槽）
+	mapEntryOverhead = 8
+	// float64Box 放入 interface{} 的 float64 单独分配的堆空间
+	float64Box = 8
+)
+
+// sizeOfString 估算字符串的占用（不含字符串头）
+func sizeOfString(s string) int {
+	return len(s)
+}
+
+// sizeOfSubjectInfo 估算证券信息的占用
+func sizeOfSubjectInfo(info *model.SubjectInfo) int {
+	if info == nil {
+		return 0
+	}
+	return int(unsafe.Sizeof(*info)) +
+		sizeOfString(info.Subject) + sizeOfString(info.Name) + sizeOfString(info.Status) +
+		sizeOfString(info.ListingDate) + sizeOfString(info.Category)
+}
+
+// sizeOfValues 估算指标值 map 的占用
+func sizeOfValues(values map[string]interface{}) int {
+	if values == nil {
+		return 0
+	}
+	size := mapOverhead
+	for key, value := range values {
+		size += stringHeader + len(key) + interfaceHeader + mapEntryOverhead
+		switch v := value.(type) {
+		case float64:
+			size += float64Box
+		case string:
+			size += stringHeader + len(v)
+		}
+	}
+	return size
+}
+
+// sizeOfSnapshotRecord 估算快照记录的占用
+func sizeOfSnapshotRecord(record *model.SnapshotRecord) int {
+	return pointerSize + int(unsafe.Sizeof(*record)) +
+		sizeOfSubjectInfo(record.Subject) + sizeOfValues(record.Data) + sizeOfString(record.EndDate)
+}
+
+// sizeOfSnapshotRecords 估算快照记录列表的占用
+func sizeOfSnapshotRecords(records []*model.SnapshotRecord) int {
+	size := sliceHeader
+	for _, record := range records {
+		size += sizeOfSnapshotRecord(record)
+	}
+	return size
+}
+
+// sizeOfPeriodDataItem 估算区间数据项的占用
+func sizeOfPeriodDataItem(item *model.PeriodDataItem) int {
+	return pointerSize + int(unsafe.Sizeof(*item)) +
+		sizeOfString(item.EndDate) + sizeOfString(item.Period) + sizeOfString(item.DeclareDate) +
+		sizeOfString(item.Year) + sizeOfString(item.Combine) + sizeOfValues(item.Values)
+}
+
+// sizeOfPeriodRecord 估算区间记录的占用
+func sizeOfPeriodRecord(record *model.PeriodRecord) int {
+	size := pointerSize + int(unsafe.Sizeof(*record)) + sizeOfSubjectInfo(record.Subject)
+	for _, item := range record.Data {
+		size += sizeOfPeriodDataItem(item)
+	}
+	return size
+}
+
+// sizeOfMapKey 估算 StockDataMap 内部 map 中一个 key 的占用
+func sizeOfMapKey(key string) int {
+	return stringHeader + len(key) + mapEntryOverhead
+}
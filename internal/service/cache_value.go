@@ -1,6 +1,8 @@
 package service
 
 import (
+	"unsafe"
+
 	"KamaitachiGo/internal/model"
 	"KamaitachiGo/pkg/json"
 )
@@ -13,12 +15,26 @@ type CacheValue struct {
 
 // NewCacheValue 创建缓存值包装器
 func NewCacheValue(data interface{}) *CacheValue {
-	// 计算大小（简单估算）
-	jsonData, _ := json.Marshal(data)
 	return &CacheValue{
 		Data: data,
-		size: len(jsonData),
+		size: sizeOfCacheData(data),
+	}
+}
+
+// sizeOfCacheData 估算缓存数据的堆占用，未知类型按JSON序列化长度估算
+func sizeOfCacheData(data interface{}) int {
+	switch v := data.(type) {
+	case *model.SnapshotResponse:
+		return int(unsafe.Sizeof(*v)) + len(v.StatusMsg) + sizeOfSnapshotRecords(v.Data)
+	case *model.PeriodResponse:
+		size := int(unsafe.Sizeof(*v)) + len(v.StatusMsg) + sliceHeader
+		for _, record := range v.Data {
+			size += sizeOfPeriodRecord(record)
+		}
+		return size
 	}
+	jsonData, _ := json.Marshal(data)
+	return len(jsonData)
 }
 
 // Len 返回缓存值的大小
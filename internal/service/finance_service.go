@@ -4,107 +4,199 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"KamaitachiGo/internal/cache/batchflight"
 	"KamaitachiGo/internal/cache/lru"
 	"KamaitachiGo/internal/model"
 	"KamaitachiGo/internal/repository"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 )
 
 type FinanceService struct {
-	repo      *repository.SQLiteRepository
-	cache     *lru.Cache // 缓存 StockDataMap
-	cacheHits int64
-	cacheMiss int64
+	repo       *repository.SQLiteRepository
+	cache      lru.Store         // 缓存 StockDataMap
+	sortFields *SortFieldCatalog // 快照查询允许的排序字段
+	cacheHits  int64
+	cacheMiss  int64
+
+	createMu          sync.Mutex         // 串行化 StockDataMap 的新建
+	flights           singleflight.Group // 合并同一topic查询的并发加载
+	flightCount       int64              // 进行中的topic加载数
+	batches           batchflight.Group  // 合并同一subject（区间查询再加上缺口）的并发批量加载
+	coalescedRequests int64              // 等待过其他请求加载结果的请求数
+	coalescedKeys     int64              // 通过等待而非查询数据库得到的 subject/缺口 数
+
+	warmup    WarmupProgress // 预热进度
+	accessLog *AccessLog     // 外部请求的访问记录，用于下次启动时预热
 }
 
-// StockDataMap 结构用于存储某个股票的结构化数据，方便按指标或时间周期查询
-type StockDataMap struct {
-	Snapshots map[string][]*model.SnapshotRecord // Key: indicator, Value: list of snapshot records
-	Periods   map[string][]*model.PeriodRecord   // Key: "indicator_from_to", Value: list of period records
-	// 还可以增加一个时间戳，用于判断数据是否新鲜
-	LastUpdated time.Time
-}
-
-// Len 实现 lru.Value 接口
-func (s *StockDataMap) Len() int {
-	// TODO: 根据实际数据大小计算，这里只是一个示例
-	size := 0
-	for _, records := range s.Snapshots {
-		size += len(records) * 100 // 估算每个 SnapshotRecord 约100字节
-	}
-	for _, records := range s.Periods {
-		size += len(records) * 200 // 估算每个 PeriodRecord 约200字节
-	}
-	return size
-}
-
-func NewFinanceService(repo *repository.SQLiteRepository, cacheSize int64) *FinanceService {
+// NewFinanceService 创建财务数据服务，shards>1 时使用分片缓存，避免所有请求竞争同一把锁
+// policy 为缓存淘汰策略（lru/lfu/wtinylfu/arc），未知的策略回退为LRU
+func NewFinanceService(repo *repository.SQLiteRepository, cacheSize int64, shards int, policy string) *FinanceService {
 	// 使用传入的cacheSize参数，如果太小则设置默认值
 	if cacheSize < 100*1024*1024 { // 小于100MB
 		cacheSize = 500 * 1024 * 1024 // 默认500MB
 	}
 
-	logrus.Infof("Initializing FinanceService with cache size: %.2f MB", float64(cacheSize)/(1024*1024))
-
-	cache := lru.NewCache(cacheSize, func(key string, value lru.Value) {
+	onEvicted := func(key string, value lru.Value) {
 		logrus.Debugf("Cache evicted: key %s, value of type %T", key, value)
-	})
+	}
+	cache, err := lru.New(cacheSize, shards, policy, onEvicted)
+	if err != nil {
+		logrus.Warnf("Invalid cache eviction policy %q, falling back to %s: %v", policy, lru.PolicyLRU, err)
+		cache, _ = lru.New(cacheSize, shards, lru.PolicyLRU, onEvicted)
+	}
+
+	logrus.Infof("Initializing FinanceService with cache size: %.2f MB, shards: %d, eviction policy: %s",
+		float64(cacheSize)/(1024*1024), shards, cache.Stats().Policy)
 
 	return &FinanceService{
-		repo:      repo,
-		cache:     cache,
-		cacheHits: 0,
-		cacheMiss: 0,
+		repo:       repo,
+		cache:      cache,
+		sortFields: NewSortFieldCatalog(model.MetaSortFieldNames(), repo.IndicatorIDs),
+		cacheHits:  0,
+		cacheMiss:  0,
 	}
 }
 
 func (s *FinanceService) QuerySnapshot(req *model.SnapshotRequest) (*model.SnapshotResponse, error) {
-	if req.Subjects == "" {
-		return nil, fmt.Errorf("subjects is required for snapshot query")
+	if req.Subjects == "" && req.Topic == "" {
+		return nil, fmt.Errorf("subjects or topic is required for snapshot query")
+	}
+	if req.Timestamp < 0 {
+		return nil, model.ErrInvalidParameter("timestamp must be 0 (latest) or a positive unix timestamp")
 	}
 
-	stockID := strings.Split(req.Subjects, ",")[0] // 使用第一个subject作为 StockDataMap 的主缓存Key
-	innerKey := generateSnapshotInnerKey(req)      // 生成 StockDataMap 内部的Key，代表精确的查询参数组合
+	ids := repository.ParseIndicatorIDs(req.IDs)
 
-	// 尝试从缓存中获取 StockDataMap (外层缓存查询)
-	if cachedStockData, ok := s.cache.Get(stockID); ok {
-		if stockDataMap, ok := cachedStockData.(*StockDataMap); ok {
-			// 在 StockDataMap 内部查找精确的快照请求 (内层缓存查询)
-			if records, found := stockDataMap.Snapshots[innerKey]; found {
-				atomic.AddInt64(&s.cacheHits, 1) // 仅在内部完全命中时，才增加命中计数
-				return &model.SnapshotResponse{
-					StatusCode: 0,
-					StatusMsg:  "success",
-					Data:       records,
-				}, nil
-			} else {
-				// 部分命中：StockDataMap存在，但请求的精确数据不在，也算作一次Miss
-				atomic.AddInt64(&s.cacheMiss, 1)
-				// 继续执行数据库查询，并更新 StockDataMap
+	// 校验排序字段，只允许白名单中的字段
+	sorts, err := s.sortFields.Parse(req.Field, int(req.Order))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Topic != "" {
+		return s.queryTopicSnapshot(req, ids, sorts)
+	}
+
+	// 排序用到但未请求的指标需要一并取回，排序后再剔除
+	fetchIDs := snapshotFetchIDs(ids, sorts)
+
+	// 解析请求的指标，未知指标直接返回参数错误
+	indicators, err := s.repo.ResolveIndicators(fetchIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := model.SplitList(req.Subjects)
+	innerKey := generateSnapshotInnerKey(fetchIDs, req.Timestamp) // StockDataMap 内部的Key，与排序、分页无关
+
+	// 按subject逐个查找缓存，记录缺失的subject
+	cached := make(map[string][]*model.SnapshotRecord, len(subjects))
+	missing := make([]string, 0)
+	for _, subject := range subjects {
+		if stockDataMap := s.getStockDataMap(subject); stockDataMap != nil {
+			if records, found := stockDataMap.Snapshot(innerKey); found {
+				cached[subject] = records
+				continue
 			}
 		}
+		missing = append(missing, subject)
+	}
+
+	if len(missing) == 0 {
+		atomic.AddInt64(&s.cacheHits, 1) // 仅在所有subject都命中时，才增加命中计数
 	} else {
-		atomic.AddInt64(&s.cacheMiss, 1) // 完全未命中
+		atomic.AddInt64(&s.cacheMiss, 1)
+		logrus.Debugf("Snapshot cache miss: %d/%d subjects, fetching %v", len(missing), len(subjects), missing)
+
+		// 一次批量查询取回所有缺失的subject，其他请求正在加载的subject等待其结果
+		fetched, err := s.loadSnapshots(missing, indicators, innerKey, req.Timestamp)
+		if err != nil {
+			return &model.SnapshotResponse{
+				StatusCode: 500,
+				StatusMsg:  fmt.Sprintf("query error: %v", err),
+				Data:       nil,
+			}, nil
+		}
+		for subject, records := range fetched {
+			cached[subject] = records
+		}
 	}
 
-	// 从仓库查询数据
-	var records []*model.SnapshotRecord
-	var err error
-	// 原始请求的subjects可能包含多个，repo层需要处理
-	subjects := strings.Split(req.Subjects, ",")
+	// 由各subject的结果组装响应，再统一排序分页
+	records := make([]*model.SnapshotRecord, 0, len(subjects))
+	for _, subject := range subjects {
+		records = append(records, cached[subject]...)
+	}
+	model.SortSnapshotRecords(records, sorts)
+	records = model.PaginateSnapshot(records, req.Offset, req.Limit)
+	if len(fetchIDs) > len(ids) {
+		records = projectSnapshot(records, ids)
+	}
+	if req.SortMeta {
+		records = withSortMeta(records)
+	}
 
-	if req.Topic != "" {
-		// 全市场查询 (此逻辑目前不与StockDataMap精确绑定，可根据业务需求扩展)
-		records, err = s.repo.QueryByTopic(req.Topic, req.Field, int(req.Order), req.Offset, req.Limit)
-	} else {
-		// 指定证券查询，支持多subject
-		records, err = s.repo.QuerySnapshot(subjects, req.Field, int(req.Order), req.Offset, req.Limit)
+	response := &model.SnapshotResponse{
+		StatusCode: 0,
+		StatusMsg:  "success",
+		Data:       records,
 	}
 
+	return response, nil
+}
+
+// queryTopicSnapshot 主题池（全市场）快照查询，结果不属于单个subject，整体缓存在主题对应的 StockDataMap 中
+// （Key 为 topicCachePrefix+主题）。缓存按 subject 拆分之前，主题池结果挂在请求中第一个 subject 的条目下；
+// 拆分后 subject 条目只保存该 subject 自己的记录，主题池结果需要单独的条目，否则每次都要全市场扫描排序
+func (s *FinanceService) queryTopicSnapshot(req *model.SnapshotRequest, ids []string, sorts []model.SortKey) (*model.SnapshotResponse, error) {
+	indicators, err := s.repo.ResolveIndicators(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := topicCachePrefix + req.Topic
+	innerKey := generateTopicInnerKey(req, ids, sorts)
+
+	if stockDataMap := s.getStockDataMap(cacheKey); stockDataMap != nil {
+		if records, found := stockDataMap.Snapshot(innerKey); found {
+			atomic.AddInt64(&s.cacheHits, 1)
+			return &model.SnapshotResponse{
+				StatusCode: 0,
+				StatusMsg:  "success",
+				Data:       records,
+			}, nil
+		}
+	}
+	atomic.AddInt64(&s.cacheMiss, 1)
+
+	// 相同topic查询的并发请求只查询一次数据库
+	loaded := false
+	val, err, shared := s.flights.Do(flightKey(cacheKey, innerKey), func() (interface{}, error) {
+		loaded = true
+		atomic.AddInt64(&s.flightCount, 1)
+		defer atomic.AddInt64(&s.flightCount, -1)
+
+		records, err := s.repo.QueryByTopic(req.Topic, indicators, sorts, req.Offset, req.Limit, req.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		s.updateStockDataMap(cacheKey, func(stockDataMap *StockDataMap) {
+			stockDataMap.SetSnapshot(innerKey, records)
+		})
+		return records, nil
+	})
+	// shared 对领导者同样为 true，只统计等待他人结果的请求
+	if shared && !loaded {
+		atomic.AddInt64(&s.coalescedRequests, 1)
+		atomic.AddInt64(&s.coalescedKeys, 1)
+	}
 	if err != nil {
 		return &model.SnapshotResponse{
 			StatusCode: 500,
@@ -112,112 +204,264 @@ func (s *FinanceService) QuerySnapshot(req *model.SnapshotRequest) (*model.Snaps
 			Data:       nil,
 		}, nil
 	}
+	records := val.([]*model.SnapshotRecord)
 
-	// 获取或创建 StockDataMap
-	var stockDataMap *StockDataMap
-	if cachedStockData, ok := s.cache.Get(stockID); ok {
-		// 如果已存在StockDataMap，则获取并更新
-		stockDataMap = cachedStockData.(*StockDataMap)
-	} else {
-		// 如果不存在，则创建新的StockDataMap
-		stockDataMap = &StockDataMap{
-			Snapshots: make(map[string][]*model.SnapshotRecord),
-			Periods:   make(map[string][]*model.PeriodRecord),
+	return &model.SnapshotResponse{
+		StatusCode: 0,
+		StatusMsg:  "success",
+		Data:       records,
+	}, nil
+}
+
+// getStockDataMap 获取缓存中的 StockDataMap，不存在时返回 nil
+func (s *FinanceService) getStockDataMap(key string) *StockDataMap {
+	if cachedStockData, ok := s.cache.Get(key); ok {
+		if stockDataMap, ok := cachedStockData.(*StockDataMap); ok {
+			return stockDataMap
 		}
 	}
+	return nil
+}
 
-	// 更新 StockDataMap，并刷新外层缓存的LRU状态
-	stockDataMap.Snapshots[innerKey] = records
-	stockDataMap.LastUpdated = time.Now() // 记录更新时间
-	s.cache.Add(stockID, stockDataMap)    // 重新添加到缓存，更新LRU顺序和可能的大小
+// updateStockDataMap 获取或创建 StockDataMap 并更新
+// 已缓存的 StockDataMap 原地修改（StockDataMap 内部加锁），大小变化由其上报给缓存；新建的写入缓存。
+// 新建时持有 createMu 并再次检查，避免两个请求同时新建、后写入的覆盖先写入的数据
+func (s *FinanceService) updateStockDataMap(key string, update func(stockDataMap *StockDataMap)) {
+	if stockDataMap := s.getStockDataMap(key); stockDataMap != nil {
+		update(stockDataMap)
+		return
+	}
 
-	response := &model.SnapshotResponse{
-		StatusCode: 0,
-		StatusMsg:  "success",
-		Data:       records,
+	s.createMu.Lock()
+	defer s.createMu.Unlock()
+	if stockDataMap := s.getStockDataMap(key); stockDataMap != nil {
+		update(stockDataMap)
+		return
 	}
+	stockDataMap := NewStockDataMap()
+	update(stockDataMap)
+	s.cache.Add(key, stockDataMap)
+}
 
-	return response, nil
+// flightKey 生成合并并发加载使用的Key：subject（或topic缓存Key）加上 StockDataMap 内部的Key
+func flightKey(key, innerKey string) string {
+	return key + "|" + innerKey
+}
+
+// cachedSnapshot 从缓存中读取单个subject的快照结果
+func (s *FinanceService) cachedSnapshot(subject, innerKey string) ([]*model.SnapshotRecord, bool) {
+	if stockDataMap := s.getStockDataMap(subject); stockDataMap != nil {
+		if records, found := stockDataMap.Snapshot(innerKey); found {
+			return records, true
+		}
+	}
+	return nil, false
+}
+
+// loadSnapshots 加载缓存中缺失的subject快照并写入缓存
+// 同一 subject+innerKey 的并发加载只执行一次：本请求领导的subject合并为一次批量查询，
+// 其他请求正在加载的subject等待其结果。领导者在等待他人之前先完成自己的加载，避免互相等待
+func (s *FinanceService) loadSnapshots(subjects []string, indicators []*repository.Indicator, innerKey string, timestamp int64) (map[string][]*model.SnapshotRecord, error) {
+	result := make(map[string][]*model.SnapshotRecord, len(subjects))
+	leading := make(map[string]*batchflight.Call)
+	waiting := make(map[string]*batchflight.Call)
+	for _, subject := range subjects {
+		call, leader := s.batches.Begin(flightKey(subject, innerKey))
+		if !leader {
+			waiting[subject] = call
+			continue
+		}
+		// 上一个领导者可能刚刚写入缓存
+		if records, ok := s.cachedSnapshot(subject, innerKey); ok {
+			s.batches.Finish(flightKey(subject, innerKey), call, records, nil)
+			result[subject] = records
+			continue
+		}
+		leading[subject] = call
+	}
+	// 异常退出时唤醒等待者
+	defer func() {
+		for subject, call := range leading {
+			s.batches.Finish(flightKey(subject, innerKey), call, nil, batchflight.ErrAborted)
+		}
+	}()
+
+	if len(leading) > 0 {
+		query := make([]string, 0, len(leading))
+		for _, subject := range subjects {
+			if _, ok := leading[subject]; ok {
+				query = append(query, subject)
+			}
+		}
+		fetched, err := s.repo.QuerySnapshot(query, indicators, timestamp)
+		if err != nil {
+			for subject, call := range leading {
+				s.batches.Finish(flightKey(subject, innerKey), call, nil, err)
+				delete(leading, subject)
+			}
+			return nil, err
+		}
+
+		bySubject := make(map[string][]*model.SnapshotRecord, len(query))
+		for _, record := range fetched {
+			bySubject[record.Subject.Subject] = append(bySubject[record.Subject.Subject], record)
+		}
+		// 没有数据的subject也写入空结果，避免重复查询数据库
+		for _, subject := range query {
+			records := bySubject[subject]
+			if records == nil {
+				records = []*model.SnapshotRecord{}
+			}
+			result[subject] = records
+			s.updateStockDataMap(subject, func(stockDataMap *StockDataMap) {
+				stockDataMap.SetSnapshot(innerKey, records)
+			})
+			s.batches.Finish(flightKey(subject, innerKey), leading[subject], records, nil)
+			delete(leading, subject)
+		}
+	}
+
+	if len(waiting) > 0 {
+		atomic.AddInt64(&s.coalescedRequests, 1)
+		atomic.AddInt64(&s.coalescedKeys, int64(len(waiting)))
+		logrus.Debugf("Snapshot load coalesced: %d subjects loaded by concurrent requests", len(waiting))
+		for subject, call := range waiting {
+			val, err := call.Wait()
+			if err != nil {
+				return nil, err
+			}
+			result[subject] = val.([]*model.SnapshotRecord)
+		}
+	}
+	return result, nil
+}
+
+// snapshotFetchIDs 返回需要从数据库取回的指标：请求的指标加上排序用到的指标
+func snapshotFetchIDs(ids []string, sorts []model.SortKey) []string {
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+	fetchIDs := append([]string(nil), ids...)
+	for _, key := range sorts {
+		if _, meta := model.LookupMetaSortField(key.Field); meta || requested[key.Field] {
+			continue
+		}
+		requested[key.Field] = true
+		fetchIDs = append(fetchIDs, key.Field)
+	}
+	return fetchIDs
+}
+
+// projectSnapshot 只保留请求的指标，返回新的记录，不修改缓存中的数据
+func projectSnapshot(records []*model.SnapshotRecord, ids []string) []*model.SnapshotRecord {
+	result := make([]*model.SnapshotRecord, len(records))
+	for i, record := range records {
+		projected := *record
+		projected.Data = make(map[string]interface{}, len(ids))
+		for _, id := range ids {
+			if v, ok := record.Data[id]; ok {
+				projected.Data[id] = v
+			}
+		}
+		result[i] = &projected
+	}
+	return result
 }
 
+// withSortMeta 附带元数据排序值，返回新的记录，不修改缓存中的数据
+func withSortMeta(records []*model.SnapshotRecord) []*model.SnapshotRecord {
+	result := make([]*model.SnapshotRecord, len(records))
+	for i, record := range records {
+		withMeta := *record
+		withMeta.SortMeta = &model.SnapshotSortMeta{
+			ReportDate:  record.ReportDate,
+			EndDate:     record.EndDate,
+			DeclareDate: record.DeclareDate,
+		}
+		result[i] = &withMeta
+	}
+	return result
+}
 
+// generateSnapshotInnerKey 生成单个subject快照在 StockDataMap 内部的 Key
+func generateSnapshotInnerKey(ids []string, timestamp int64) string {
+	// 对IDs进行排序，确保不同顺序的相同内容能生成相同的Key
+	sortedIDs := append([]string(nil), ids...)
+	sort.Strings(sortedIDs)
 
-// generateSnapshotInnerKey 为 SnapshotRequest 生成 StockDataMap 内部的 Key
-func generateSnapshotInnerKey(req *model.SnapshotRequest) string {
-	// 对IDs和Subjects进行排序，确保不同顺序的相同内容能生成相同的Key
-	ids := strings.Split(req.IDs, ",")
-	subjects := strings.Split(req.Subjects, ",")
-	sort.Strings(ids)
-	sort.Strings(subjects)
-	sortedIDs := strings.Join(ids, ",")
-	sortedSubjects := strings.Join(subjects, "_")
+	// 时点查询的 as-of 时间参与Key，0 表示最新
+	return fmt.Sprintf("snap_%s_%d", strings.Join(sortedIDs, ","), timestamp)
+}
 
-	return fmt.Sprintf("snap_%s_%s_%d_%d_%d_%s", sortedIDs, req.Field, int(req.Order), req.Offset, req.Limit, sortedSubjects)
+// generateTopicInnerKey 生成主题池快照在 StockDataMap 内部的 Key，包含排序与分页参数
+func generateTopicInnerKey(req *model.SnapshotRequest, ids []string, sorts []model.SortKey) string {
+	sortedIDs := append([]string(nil), ids...)
+	sort.Strings(sortedIDs)
+	return fmt.Sprintf("topic_%s_%s_%d_%d_%d", strings.Join(sortedIDs, ","), model.FormatSortKeys(sorts), req.Offset, req.Limit, req.Timestamp)
 }
 
 // QueryPeriod 区间查询
+// 每个subject的区间数据按指标组合缓存为一条序列，请求区间被已覆盖范围包含时直接从内存返回，
+// 否则只查询未覆盖的缺口，合并进序列后再返回
 func (s *FinanceService) QueryPeriod(req *model.PeriodRequest) (*model.PeriodResponse, error) {
 	if req.Subjects == "" {
 		return nil, fmt.Errorf("subjects is required for period query")
 	}
 
-	stockID := strings.Split(req.Subjects, ",")[0] // 使用第一个subject作为缓存的stockID
-	innerKey := generatePeriodInnerKey(req)
+	ids := repository.ParseIndicatorIDs(req.IDs)
+	indicators, err := s.repo.ResolveIndicators(ids)
+	if err != nil {
+		return nil, err
+	}
 
-	// 尝试从缓存中获取 StockDataMap
-	if cachedStockData, ok := s.cache.Get(stockID); ok {
-		if stockDataMap, ok := cachedStockData.(*StockDataMap); ok {
-			// 在 StockDataMap 中查找精确的区间请求
-			if records, found := stockDataMap.Periods[innerKey]; found {
-				atomic.AddInt64(&s.cacheHits, 1) // 仅在内部完全命中时，才增加命中计数
-				logrus.Debugf("Cache hit (inner): %s - %s", stockID, innerKey)
-				return &model.PeriodResponse{
-					StatusCode: 0,
-					StatusMsg:  "success",
-					Data:       records,
-				}, nil
-			} else {
-				// 部分命中：StockDataMap存在，但请求的精确数据不在，也算作一次Miss
-				atomic.AddInt64(&s.cacheMiss, 1)
-				logrus.Debugf("Cache partial hit: %s - %s. Fetching missing data.", stockID, innerKey)
-				// 继续执行数据库查询，并更新 StockDataMap
+	subjects := model.SplitList(req.Subjects)
+	innerKey := generatePeriodInnerKey(ids)
+
+	// 按subject查找缓存的序列，计算未覆盖的缺口；相同缺口的subject合并为一次查询
+	series := make(map[string]*PeriodSeries, len(subjects))
+	gapSubjects := make(map[Interval][]string)
+	gaps := make([]Interval, 0)
+	for _, subject := range subjects {
+		// 复制一份序列再合并缺口数据（Merge 不修改原有切片），并发读取缓存的请求不受影响
+		cached := &PeriodSeries{}
+		if stockDataMap := s.getStockDataMap(subject); stockDataMap != nil {
+			if existing, ok := stockDataMap.Period(innerKey); ok {
+				cached = existing
 			}
 		}
-	} else {
-		atomic.AddInt64(&s.cacheMiss, 1) // 完全未命中
-		logrus.Debugf("Cache miss (full): %s. Fetching from repo.", stockID)
-	}
-
-	// 从仓库查询数据
-	var records []*model.PeriodRecord
-	var err error
-	subjects := strings.Split(req.Subjects, ",") // 原始请求的subjects
-
-	records, err = s.repo.QueryPeriod(subjects, req.From, req.To)
+		series[subject] = cached
 
-	if err != nil {
-		return &model.PeriodResponse{
-			StatusCode: 500,
-			StatusMsg:  fmt.Sprintf("query error: %v", err),
-			Data:       nil,
-		}, nil
+		for _, gap := range cached.Gaps(req.From, req.To) {
+			if _, ok := gapSubjects[gap]; !ok {
+				gaps = append(gaps, gap)
+			}
+			gapSubjects[gap] = append(gapSubjects[gap], subject)
+		}
 	}
 
-	// 获取或创建 StockDataMap
-	var stockDataMap *StockDataMap
-	if cachedStockData, ok := s.cache.Get(stockID); ok {
-		stockDataMap = cachedStockData.(*StockDataMap)
+	if len(gaps) == 0 {
+		atomic.AddInt64(&s.cacheHits, 1) // 仅在所有subject的请求区间都已覆盖时，才增加命中计数
+		logrus.Debugf("Cache hit (inner): %v - %s [%d, %d]", subjects, innerKey, req.From, req.To)
 	} else {
-		stockDataMap = &StockDataMap{
-			Snapshots: make(map[string][]*model.SnapshotRecord),
-			Periods:   make(map[string][]*model.PeriodRecord),
+		atomic.AddInt64(&s.cacheMiss, 1)
+
+		if err := s.loadPeriodGaps(series, gaps, gapSubjects, indicators, innerKey); err != nil {
+			return &model.PeriodResponse{
+				StatusCode: 500,
+				StatusMsg:  fmt.Sprintf("query error: %v", err),
+				Data:       nil,
+			}, nil
 		}
 	}
 
-	// 更新 StockDataMap
-	stockDataMap.Periods[innerKey] = records
-	stockDataMap.LastUpdated = time.Now()
-	s.cache.Add(stockID, stockDataMap) // 重新添加到缓存，更新LRU
+	// 按请求中的subject顺序组装响应
+	records := make([]*model.PeriodRecord, 0, len(subjects))
+	for _, subject := range subjects {
+		if record := series[subject].Slice(req.From, req.To); record != nil {
+			records = append(records, record)
+		}
+	}
 
 	response := &model.PeriodResponse{
 		StatusCode: 0,
@@ -228,86 +472,156 @@ func (s *FinanceService) QueryPeriod(req *model.PeriodRequest) (*model.PeriodRes
 	return response, nil
 }
 
-// generatePeriodInnerKey 为 PeriodRequest 生成 StockDataMap 内部的 Key
-func generatePeriodInnerKey(req *model.PeriodRequest) string {
-	// 对IDs和Subjects进行排序，确保不同顺序的相同内容能生成相同的Key
-	ids := strings.Split(req.IDs, ",")
-	subjects := strings.Split(req.Subjects, ",")
-	sort.Strings(ids)
-	sort.Strings(subjects)
-	sortedIDs := strings.Join(ids, ",")
-	sortedSubjects := strings.Join(subjects, "_")
-
-	return fmt.Sprintf("period_%s_%d_%d_%s", sortedIDs, req.From, req.To, sortedSubjects)
+// periodFlight 一个subject在一个缺口上的加载
+type periodFlight struct {
+	gap     Interval
+	subject string
+	call    *batchflight.Call
 }
 
-// WarmupCache 预热缓存
-func (s *FinanceService) WarmupCache() error {
-	logrus.Info("Starting cache warmup...")
-
-	warmupCount := 0
+// loadPeriodGaps 查询各缺口的区间数据，合并进 series 并写入缓存
+// 同一 subject+innerKey+缺口 的并发加载只执行一次；本请求领导的加载先全部查询、合并并写入缓存，
+// 再等待其他请求正在进行的加载，避免互相等待。查询失败时不写入缓存，避免留下不完整的覆盖范围
+func (s *FinanceService) loadPeriodGaps(series map[string]*PeriodSeries, gaps []Interval, gapSubjects map[Interval][]string, indicators []*repository.Indicator, innerKey string) error {
+	gapKey := func(subject string, gap Interval) string {
+		return flightKey(subject, fmt.Sprintf("%s[%d,%d]", innerKey, gap.From, gap.To))
+	}
 
-	// 1. 预热全市场最新数据（营业收入排名）
-	req1 := &model.SnapshotRequest{
-		IDs:    "operating_income,parent_holder_net_profit",
-		Topic:  "stock_a_listing_pool",
-		Field:  "operating_income",
-		Order:  -1,
-		Offset: 0,
-		Limit:  50,
+	leading := make(map[Interval][]*periodFlight, len(gaps))
+	waiting := make([]*periodFlight, 0)
+	for _, gap := range gaps {
+		for _, subject := range gapSubjects[gap] {
+			call, leader := s.batches.Begin(gapKey(subject, gap))
+			flight := &periodFlight{gap: gap, subject: subject, call: call}
+			if leader {
+				leading[gap] = append(leading[gap], flight)
+			} else {
+				waiting = append(waiting, flight)
+			}
+		}
 	}
-	if _, err := s.QuerySnapshot(req1); err == nil {
-		warmupCount++
+	// 异常退出或查询失败时唤醒等待者
+	var loadErr error = batchflight.ErrAborted
+	defer func() {
+		for gap, flights := range leading {
+			for _, flight := range flights {
+				s.batches.Finish(gapKey(flight.subject, gap), flight.call, nil, loadErr)
+			}
+		}
+	}()
+
+	// 先查询全部缺口，全部成功后再合并
+	fetched := make(map[Interval]map[string]*model.PeriodRecord, len(leading))
+	for _, gap := range gaps {
+		flights := leading[gap]
+		if len(flights) == 0 {
+			continue
+		}
+		subjects := make([]string, len(flights))
+		for i, flight := range flights {
+			subjects[i] = flight.subject
+		}
+		logrus.Debugf("Period cache gap: %s [%d, %d] for %v", innerKey, gap.From, gap.To, subjects)
+		records, err := s.repo.QueryPeriod(subjects, indicators, gap.From, gap.To)
+		if err != nil {
+			loadErr = err
+			return err
+		}
+		bySubject := make(map[string]*model.PeriodRecord, len(records))
+		for _, record := range records {
+			bySubject[record.Subject.Subject] = record
+		}
+		fetched[gap] = bySubject
 	}
 
-	// 2. 预热全市场最新数据（净利润排名）
-	req2 := &model.SnapshotRequest{
-		IDs:    "operating_income,parent_holder_net_profit",
-		Topic:  "stock_a_listing_pool",
-		Field:  "parent_holder_net_profit",
-		Order:  -1,
-		Offset: 0,
-		Limit:  50,
+	updated := make(map[string]bool)
+	for gap, bySubject := range fetched {
+		for _, flight := range leading[gap] {
+			series[flight.subject].Merge(gap, bySubject[flight.subject])
+			updated[flight.subject] = true
+		}
 	}
-	if _, err := s.QuerySnapshot(req2); err == nil {
-		warmupCount++
+	s.storePeriods(series, updated, innerKey)
+	for gap, bySubject := range fetched {
+		for _, flight := range leading[gap] {
+			s.batches.Finish(gapKey(flight.subject, gap), flight.call, bySubject[flight.subject], nil)
+		}
+		delete(leading, gap)
 	}
 
-	// 3. 预热常用证券的快照
-	commonStocks := []string{
-		"33:00000009", "33:00082582", "33:01000729",
-		"33:02600053", "33:02600171", "33:03131331",
+	if len(waiting) == 0 {
+		return nil
 	}
-	for _, stock := range commonStocks {
-		req := &model.SnapshotRequest{
-			IDs:      "operating_income,parent_holder_net_profit",
-			Subjects: stock,
-			Field:    "operating_income",
-			Order:    -1,
-			Limit:    1,
-		}
-		if _, err := s.QuerySnapshot(req); err == nil {
-			warmupCount++
+	atomic.AddInt64(&s.coalescedRequests, 1)
+	atomic.AddInt64(&s.coalescedKeys, int64(len(waiting)))
+	logrus.Debugf("Period load coalesced: %d subject gaps loaded by concurrent requests", len(waiting))
+
+	// 等待者的数据已由领导者写入缓存；同时领导过其他缺口的subject需要把完整序列再写一次，避免互相覆盖
+	for _, flight := range waiting {
+		val, err := flight.call.Wait()
+		if err != nil {
+			return err
 		}
+		record, _ := val.(*model.PeriodRecord)
+		series[flight.subject].Merge(flight.gap, record)
 	}
+	s.storePeriods(series, updated, innerKey)
+	return nil
+}
 
-	// 4. 预热常用证券的区间数据
-	for _, stock := range commonStocks[:3] { // 只预热前3个
-		req := &model.PeriodRequest{
-			IDs:      "operating_income,parent_holder_net_profit",
-			Subjects: stock,
-			From:     1577836800, // 2020-01-01
-			To:       1735660800, // 2025-01-01
-		}
-		if _, err := s.QueryPeriod(req); err == nil {
-			warmupCount++
-		}
+// storePeriods 将 subjects 中各subject的区间序列写入缓存
+func (s *FinanceService) storePeriods(series map[string]*PeriodSeries, subjects map[string]bool, innerKey string) {
+	for subject := range subjects {
+		merged := series[subject]
+		s.updateStockDataMap(subject, func(stockDataMap *StockDataMap) {
+			stockDataMap.SetPeriods(innerKey, merged)
+		})
 	}
+}
+
+// generatePeriodInnerKey 生成单个subject区间序列在 StockDataMap 内部的 Key，与时间范围无关
+func generatePeriodInnerKey(ids []string) string {
+	// 对IDs进行排序，确保不同顺序的相同内容能生成相同的Key
+	sortedIDs := append([]string(nil), ids...)
+	sort.Strings(sortedIDs)
+
+	return fmt.Sprintf("period_%s", strings.Join(sortedIDs, ","))
+}
+
+// ConfigureCacheExpiration 设置缓存的默认过期时间与滑动过期，并启动后台过期清理
+// ttl<=0 表示永不过期；开启滑动过期后频繁访问的热点数据会一直保留，冷数据到期后被清理
+func (s *FinanceService) ConfigureCacheExpiration(ttl time.Duration, sliding bool, janitorInterval time.Duration) {
+	s.cache.SetDefaultTTL(ttl)
+	s.cache.SetSlidingExpiration(sliding)
+	s.cache.StartJanitor(janitorInterval)
+	logrus.Infof("FinanceService cache expiration: ttl=%v, sliding=%v, janitor interval=%v", ttl, sliding, janitorInterval)
+}
 
-	logrus.Infof("Cache warmup completed: %d queries, %d entries in cache", warmupCount, s.cache.Len())
+// EnablePolicyComparison 为缓存挂载各淘汰策略的影子缓存，在真实流量下对比命中率，结果通过 GetCacheStats 返回
+// 需在 ConfigureCacheExpiration 之前调用，policies 为空时不做任何处理
+func (s *FinanceService) EnablePolicyComparison(policies []string) error {
+	if len(policies) == 0 {
+		return nil
+	}
+	comparing, err := lru.NewComparingStore(s.cache, s.cache.MaxBytes(), policies)
+	if err != nil {
+		return err
+	}
+	s.cache = comparing
+	logrus.Infof("FinanceService cache policy comparison enabled: %v", comparing.Policies())
 	return nil
 }
 
+// Cache 返回存放 StockDataMap 的缓存，用于快照持久化（配合 StockDataMapCodec）
+func (s *FinanceService) Cache() lru.Store {
+	return s.cache
+}
+
+// Close 停止缓存的后台清理
+func (s *FinanceService) Close() {
+	s.cache.StopJanitor()
+}
+
 // GetCacheStats 获取缓存统计
 func (s *FinanceService) GetCacheStats() map[string]interface{} {
 	hits := atomic.LoadInt64(&s.cacheHits)
@@ -321,16 +635,51 @@ func (s *FinanceService) GetCacheStats() map[string]interface{} {
 
 	// logrus.Debugf("GetCacheStats: hits=%d, miss=%d, total=%d, hitRate=%.2f", hits, miss, total, hitRate)
 
-	return map[string]interface{}{
-		"entries":  s.cache.Len(),
-		"hits":     hits,
-		"misses":   miss,
-		"hit_rate": hitRate, // Return float64 directly
+	cacheStats := s.cache.Stats()
+
+	stats := map[string]interface{}{
+		"entries":     cacheStats.Entries,
+		"hits":        hits,
+		"misses":      miss,
+		"hit_rate":    hitRate, // Return float64 directly
+		"used_bytes":  cacheStats.UsedBytes,
+		"max_bytes":   cacheStats.MaxBytes,
+		"shards":      cacheStats.Shards,
+		"evictions":   cacheStats.Evictions,
+		"expirations": cacheStats.Expirations,
+		"policy":      cacheStats.Policy,
+
+		"coalesced_requests": atomic.LoadInt64(&s.coalescedRequests),
+		"coalesced_keys":     atomic.LoadInt64(&s.coalescedKeys),
+		"inflight_loads":     s.batches.InFlight() + int(atomic.LoadInt64(&s.flightCount)),
 	}
+
+	// 各淘汰策略在同一份流量下的命中率（按缓存条目统计，与上面按请求统计的 hit_rate 口径不同）
+	if comparing, ok := s.cache.(*lru.ComparingStore); ok {
+		comparison := make(map[string]interface{})
+		for name, shadow := range comparing.ShadowStats() {
+			comparison[name] = map[string]interface{}{
+				"entries":    shadow.Entries,
+				"hits":       shadow.Hits,
+				"misses":     shadow.Misses,
+				"hit_rate":   shadow.HitRate(),
+				"evictions":  shadow.Evictions,
+				"used_bytes": shadow.UsedBytes,
+			}
+		}
+		stats["policy_comparison"] = comparison
+	}
+	return stats
 }
 
-// ResetCacheStats 重置缓存统计
+// ResetCacheStats 重置缓存统计，同时重新读取表结构与指标目录，使新导入的指标立即可查
 func (s *FinanceService) ResetCacheStats() {
 	atomic.StoreInt64(&s.cacheHits, 0)
 	atomic.StoreInt64(&s.cacheMiss, 0)
+	atomic.StoreInt64(&s.coalescedRequests, 0)
+	atomic.StoreInt64(&s.coalescedKeys, 0)
+
+	if err := s.repo.ReloadSchema(); err != nil {
+		logrus.Warnf("Failed to reload indicator catalog: %v", err)
+	}
 }
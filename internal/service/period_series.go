@@ -0,0 +1,116 @@
+package service
+
+import (
+	"sort"
+	"unsafe"
+
+	"KamaitachiGo/internal/model"
+)
+
+// Interval 报告期时间区间 [From, To]，两端均包含
+type Interval struct {
+	From int64
+	To   int64
+}
+
+// PeriodSeries 某个subject在一组指标下已缓存的区间数据
+// Coverage 记录已经查询过数据库的时间区间，区间内没有数据也算已覆盖
+type PeriodSeries struct {
+	Subject  *model.SubjectInfo      // 证券信息，数据库中还没有该subject的数据时为 nil
+	Items    []*model.PeriodDataItem // 按报告期倒序
+	Coverage []Interval              // 有序、互不重叠且不相邻
+}
+
+// Gaps 返回 [from, to] 中尚未覆盖的区间
+func (p *PeriodSeries) Gaps(from, to int64) []Interval {
+	gaps := make([]Interval, 0)
+	if from > to {
+		return gaps
+	}
+	cursor := from
+	for _, covered := range p.Coverage {
+		if covered.To < cursor {
+			continue
+		}
+		if covered.From > to {
+			break
+		}
+		if covered.From > cursor {
+			gaps = append(gaps, Interval{From: cursor, To: covered.From - 1})
+		}
+		if covered.To >= to {
+			return gaps
+		}
+		cursor = covered.To + 1
+	}
+	return append(gaps, Interval{From: cursor, To: to})
+}
+
+// Merge 将某个缺口区间的查询结果并入序列，record 为该区间的查询结果（无数据时为 nil）
+func (p *PeriodSeries) Merge(gap Interval, record *model.PeriodRecord) {
+	if record != nil {
+		if p.Subject == nil {
+			p.Subject = record.Subject
+		}
+		// 生成新的切片，已返回给调用方的旧切片不受影响
+		items := make([]*model.PeriodDataItem, 0, len(p.Items)+len(record.Data))
+		items = append(items, p.Items...)
+		items = append(items, record.Data...)
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].ReportDate > items[j].ReportDate
+		})
+		p.Items = items
+	}
+	p.cover(gap)
+}
+
+// cover 将区间加入覆盖范围，合并重叠或相邻的区间
+func (p *PeriodSeries) cover(gap Interval) {
+	coverage := append(append(make([]Interval, 0, len(p.Coverage)+1), p.Coverage...), gap)
+	sort.Slice(coverage, func(i, j int) bool {
+		return coverage[i].From < coverage[j].From
+	})
+
+	merged := coverage[:1]
+	for _, next := range coverage[1:] {
+		last := &merged[len(merged)-1]
+		if next.From <= last.To+1 {
+			if next.To > last.To {
+				last.To = next.To
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+	p.Coverage = merged
+}
+
+// Slice 从序列中取出 [from, to] 区间的数据，区间内没有数据时返回 nil
+func (p *PeriodSeries) Slice(from, to int64) *model.PeriodRecord {
+	if p.Subject == nil {
+		return nil
+	}
+	items := make([]*model.PeriodDataItem, 0)
+	for _, item := range p.Items {
+		if item.ReportDate >= from && item.ReportDate <= to {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return &model.PeriodRecord{
+		Subject: p.Subject,
+		Data:    items,
+	}
+}
+
+// Len 估算序列占用的字节数
+func (p *PeriodSeries) Len() int {
+	size := pointerSize + int(unsafe.Sizeof(*p)) + sizeOfSubjectInfo(p.Subject) +
+		len(p.Coverage)*int(unsafe.Sizeof(Interval{}))
+	for _, item := range p.Items {
+		size += sizeOfPeriodDataItem(item)
+	}
+	return size
+}
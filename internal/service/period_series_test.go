@@ -0,0 +1,146 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"KamaitachiGo/internal/model"
+)
+
+// PeriodSeries 的区间计算测试：缺口只包含未覆盖的部分，合并后的覆盖范围有序且互不重叠、不相邻。
+
+func TestPeriodSeriesGaps(t *testing.T) {
+	coverage := []Interval{{From: 10, To: 19}, {From: 30, To: 39}, {From: 50, To: 50}}
+
+	tests := []struct {
+		name     string
+		coverage []Interval
+		from, to int64
+		want     []Interval
+	}{
+		{name: "empty series", from: 1, to: 5, want: []Interval{{From: 1, To: 5}}},
+		{name: "inverted range", coverage: coverage, from: 20, to: 10, want: []Interval{}},
+		{name: "fully covered", coverage: coverage, from: 12, to: 18, want: []Interval{}},
+		{name: "exactly covered", coverage: coverage, from: 10, to: 19, want: []Interval{}},
+		{name: "before all coverage", coverage: coverage, from: 1, to: 5, want: []Interval{{From: 1, To: 5}}},
+		{name: "after all coverage", coverage: coverage, from: 60, to: 70, want: []Interval{{From: 60, To: 70}}},
+		{name: "overlaps the start", coverage: coverage, from: 5, to: 15, want: []Interval{{From: 5, To: 9}}},
+		{name: "overlaps the end", coverage: coverage, from: 15, to: 25, want: []Interval{{From: 20, To: 25}}},
+		{name: "between two intervals", coverage: coverage, from: 20, to: 29, want: []Interval{{From: 20, To: 29}}},
+		{
+			name:     "spans several intervals",
+			coverage: coverage,
+			from:     0,
+			to:       100,
+			want:     []Interval{{From: 0, To: 9}, {From: 20, To: 29}, {From: 40, To: 49}, {From: 51, To: 100}},
+		},
+		{
+			name:     "starts inside and ends inside",
+			coverage: coverage,
+			from:     15,
+			to:       35,
+			want:     []Interval{{From: 20, To: 29}},
+		},
+		{name: "single point gap", coverage: []Interval{{From: 0, To: 4}, {From: 6, To: 9}}, from: 0, to: 9, want: []Interval{{From: 5, To: 5}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := &PeriodSeries{Coverage: tt.coverage}
+			if got := series.Gaps(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Gaps(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodSeriesMergeCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		merges []Interval
+		want   []Interval
+	}{
+		{name: "single", merges: []Interval{{From: 10, To: 20}}, want: []Interval{{From: 10, To: 20}}},
+		{name: "disjoint out of order", merges: []Interval{{From: 30, To: 40}, {From: 10, To: 20}}, want: []Interval{{From: 10, To: 20}, {From: 30, To: 40}}},
+		{name: "adjacent", merges: []Interval{{From: 10, To: 20}, {From: 21, To: 30}}, want: []Interval{{From: 10, To: 30}}},
+		{name: "overlapping", merges: []Interval{{From: 10, To: 20}, {From: 15, To: 25}}, want: []Interval{{From: 10, To: 25}}},
+		{name: "contained", merges: []Interval{{From: 10, To: 40}, {From: 15, To: 25}}, want: []Interval{{From: 10, To: 40}}},
+		{
+			name:   "fills the gap between two intervals",
+			merges: []Interval{{From: 10, To: 20}, {From: 30, To: 40}, {From: 21, To: 29}},
+			want:   []Interval{{From: 10, To: 40}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := &PeriodSeries{}
+			for _, gap := range tt.merges {
+				series.Merge(gap, nil)
+			}
+			if !reflect.DeepEqual(series.Coverage, tt.want) {
+				t.Fatalf("coverage is %v, want %v", series.Coverage, tt.want)
+			}
+			// 合并过的区间不再有缺口
+			for _, gap := range tt.merges {
+				if gaps := series.Gaps(gap.From, gap.To); len(gaps) != 0 {
+					t.Errorf("Gaps(%d, %d) = %v after merging it", gap.From, gap.To, gaps)
+				}
+			}
+		})
+	}
+}
+
+func TestPeriodSeriesFillGaps(t *testing.T) {
+	subject := "33:000001"
+	series := &PeriodSeries{}
+
+	// 依次查询几个有重叠的区间，每次只合并缺口的查询结果
+	queries := []struct{ from, to int }{{2019, 2020}, {2022, 2022}, {2018, 2023}, {2020, 2021}}
+	for _, q := range queries {
+		from, to := yearRange(q.from, q.to)
+		for _, gap := range series.Gaps(from, to) {
+			series.Merge(gap, fakePeriodRecord(subject, gap))
+		}
+	}
+
+	from, to := yearRange(2018, 2023)
+	if gaps := series.Gaps(from, to); len(gaps) != 0 {
+		t.Fatalf("series still has gaps %v", gaps)
+	}
+	if len(series.Coverage) != 1 {
+		t.Fatalf("coverage is %v, want a single interval", series.Coverage)
+	}
+
+	tests := []struct {
+		name     string
+		from, to int
+		want     []string
+	}{
+		{name: "full range", from: 2018, to: 2023, want: []string{"2023", "2022", "2021", "2020", "2019", "2018"}},
+		{name: "inner range", from: 2020, to: 2021, want: []string{"2021", "2020"}},
+		{name: "outside coverage", from: 2010, to: 2012},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := yearRange(tt.from, tt.to)
+			record := series.Slice(from, to)
+			var got []string
+			if record != nil {
+				got = periodYears(record)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Slice(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+// periodYears 返回区间记录中各数据项的年份
+func periodYears(record *model.PeriodRecord) []string {
+	years := make([]string, len(record.Data))
+	for i, item := range record.Data {
+		years[i] = item.Year
+	}
+	return years
+}
@@ -34,6 +34,14 @@ func (s *selectionServiceImpl) SelectionSnapshot(request *model.SelectionSnapsho
 	// 解析指标ID列表
 	indicatorIDs := parseIndicators(request.IDs)
 
+	// 校验排序字段：元数据字段或本次请求的指标
+	sorts, err := NewSortFieldCatalog(selectionMetaSortFields, func() []string {
+		return indicatorIDs
+	}).Parse(request.Field, request.Order)
+	if err != nil {
+		return nil, err
+	}
+
 	// 根据选股策略查询匹配的股票（模拟实现）
 	subjects := s.findMatchingSubjects(&request.Selection)
 
@@ -74,8 +82,8 @@ func (s *selectionServiceImpl) SelectionSnapshot(request *model.SelectionSnapsho
 	}
 
 	// 排序
-	if request.Field != "" {
-		s.sortSnapshotResults(results, request.Field, request.Order)
+	if len(sorts) > 0 {
+		s.sortSnapshotResults(results, sorts)
 	}
 
 	// 分页
@@ -93,6 +101,14 @@ func (s *selectionServiceImpl) SelectionPeriod(request *model.SelectionPeriodReq
 	// 解析指标ID列表
 	indicatorIDs := parseIndicators(request.IDs)
 
+	// 校验排序字段：区间数据只携带归母净利润与营业收入两个指标
+	sorts, err := NewSortFieldCatalog(selectionMetaSortFields, func() []string {
+		return periodSortIndicators(indicatorIDs)
+	}).Parse(request.Field, request.Order)
+	if err != nil {
+		return nil, err
+	}
+
 	// 根据选股策略查询匹配的股票
 	subjects := s.findMatchingSubjects(&request.Selection)
 
@@ -143,9 +159,9 @@ func (s *selectionServiceImpl) SelectionPeriod(request *model.SelectionPeriodReq
 	}
 
 	// 对每个日期分组进行排序
-	if request.Field != "" {
+	if len(sorts) > 0 {
 		for date := range dateGroupedResults {
-			s.sortPeriodResults(dateGroupedResults[date], request.Field, request.Order)
+			s.sortPeriodResults(dateGroupedResults[date], sorts)
 		}
 	}
 
@@ -185,37 +201,91 @@ func (s *selectionServiceImpl) getLatestTimestamp(data map[int64][]interface{})
 	return latest
 }
 
-// sortSnapshotResults 对快照结果排序
-func (s *selectionServiceImpl) sortSnapshotResults(results []model.SelectionSnapshotDataItem, field string, order int) {
-	sort.Slice(results, func(i, j int) bool {
-		vi := s.getNumericValue(results[i].Data[field])
-		vj := s.getNumericValue(results[j].Data[field])
-
-		if order == -1 {
-			return vi > vj // 倒序
+// sortSnapshotResults 对快照结果按多个排序键排序
+func (s *selectionServiceImpl) sortSnapshotResults(results []model.SelectionSnapshotDataItem, sorts []model.SortKey) {
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, key := range sorts {
+			vi := snapshotSortValue(results[i], key.Field)
+			vj := snapshotSortValue(results[j], key.Field)
+			if c := s.compareValues(vi, vj); c != 0 {
+				if key.Desc {
+					return c > 0 // 倒序
+				}
+				return c < 0 // 正序
+			}
 		}
-		return vi < vj // 正序
+		return false
 	})
 }
 
-// sortPeriodResults 对区间结果排序
-func (s *selectionServiceImpl) sortPeriodResults(results []model.SelectionPeriodDataItem, field string, order int) {
-	sort.Slice(results, func(i, j int) bool {
-		var vi, vj float64
-
-		if field == "parent_holder_net_profit" {
-			vi = s.getNumericValue(results[i].Data.ParentHolderNetProfit)
-			vj = s.getNumericValue(results[j].Data.ParentHolderNetProfit)
-		} else if field == "operating_income" {
-			vi = s.getNumericValue(results[i].Data.OperatingIncome)
-			vj = s.getNumericValue(results[j].Data.OperatingIncome)
+// sortPeriodResults 对区间结果按多个排序键排序
+func (s *selectionServiceImpl) sortPeriodResults(results []model.SelectionPeriodDataItem, sorts []model.SortKey) {
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, key := range sorts {
+			vi := periodSortValue(results[i], key.Field)
+			vj := periodSortValue(results[j], key.Field)
+			if c := s.compareValues(vi, vj); c != 0 {
+				if key.Desc {
+					return c > 0 // 倒序
+				}
+				return c < 0 // 正序
+			}
 		}
+		return false
+	})
+}
 
-		if order == -1 {
-			return vi > vj // 倒序
+// snapshotSortValue 取快照结果中排序字段的值
+func snapshotSortValue(item model.SelectionSnapshotDataItem, field string) interface{} {
+	switch field {
+	case "subject":
+		return item.Subject.Subject
+	case "name":
+		return item.Subject.Name
+	}
+	return item.Data[field]
+}
+
+// periodSortValue 取区间结果中排序字段的值
+func periodSortValue(item model.SelectionPeriodDataItem, field string) interface{} {
+	switch field {
+	case "subject":
+		return item.Subject.Subject
+	case "name":
+		return item.Subject.Name
+	case "parent_holder_net_profit":
+		return item.Data.ParentHolderNetProfit
+	case "operating_income":
+		return item.Data.OperatingIncome
+	}
+	return nil
+}
+
+// periodSortIndicators 区间查询中可用于排序的指标
+func periodSortIndicators(indicatorIDs []string) []string {
+	result := make([]string, 0, len(indicatorIDs))
+	for _, id := range indicatorIDs {
+		if id == "parent_holder_net_profit" || id == "operating_income" {
+			result = append(result, id)
 		}
-		return vi < vj // 正序
-	})
+	}
+	return result
+}
+
+// compareValues 比较两个排序值：字符串按字典序，其余按数值
+func (s *selectionServiceImpl) compareValues(a, b interface{}) int {
+	if sa, ok := a.(string); ok {
+		sb, _ := b.(string)
+		return strings.Compare(sa, sb)
+	}
+	va, vb := s.getNumericValue(a), s.getNumericValue(b)
+	switch {
+	case va < vb:
+		return -1
+	case va > vb:
+		return 1
+	}
+	return 0
 }
 
 // paginateSnapshotResults 对快照结果分页
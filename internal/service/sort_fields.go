@@ -0,0 +1,65 @@
+package service
+
+import (
+	"sort"
+
+	"KamaitachiGo/internal/model"
+)
+
+// SortFieldCatalog 排序字段白名单：元数据字段 + 指标字段
+// 快照查询与选股查询共用，只有通过校验的字段才会进入排序（包括SQL的 ORDER BY）
+type SortFieldCatalog struct {
+	meta       map[string]bool
+	indicators func() []string
+}
+
+// selectionMetaSortFields 选股查询支持的元数据排序字段
+var selectionMetaSortFields = []string{"subject", "name"}
+
+// NewSortFieldCatalog 创建排序字段目录，indicators 返回当前可排序的指标ID
+func NewSortFieldCatalog(meta []string, indicators func() []string) *SortFieldCatalog {
+	c := &SortFieldCatalog{
+		meta:       make(map[string]bool, len(meta)),
+		indicators: indicators,
+	}
+	for _, field := range meta {
+		c.meta[field] = true
+	}
+	return c
+}
+
+// Parse 解析并校验排序规格，未知字段返回 400 错误
+func (c *SortFieldCatalog) Parse(spec string, defaultOrder int) ([]model.SortKey, error) {
+	keys, err := model.ParseSortKeys(spec, defaultOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	var indicatorSet map[string]bool
+	for _, key := range keys {
+		if c.meta[key.Field] {
+			continue
+		}
+		if indicatorSet == nil {
+			indicatorSet = make(map[string]bool)
+			for _, id := range c.indicators() {
+				indicatorSet[id] = true
+			}
+		}
+		if !indicatorSet[key.Field] {
+			return nil, model.ErrUnknownSortField(key.Field, c.Allowed())
+		}
+	}
+	return keys, nil
+}
+
+// Allowed 返回全部允许的排序字段（已排序）
+func (c *SortFieldCatalog) Allowed() []string {
+	fields := make([]string, 0, len(c.meta))
+	for field := range c.meta {
+		fields = append(fields, field)
+	}
+	fields = append(fields, c.indicators()...)
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+
+	"KamaitachiGo/internal/model"
+)
+
+// StockDataMap 结构用于存储某个股票的结构化数据，方便按指标或时间周期查询
+// 并发安全：同一个 StockDataMap 可能同时被命中缓存的请求读取、被加载数据的请求写入，内部由读写锁保护。
+// 写入的快照记录和区间序列视为只读，更新时整体替换（区间序列由 PeriodSeries.Merge 生成新副本），
+// 读取方拿到的数据不会在之后被修改。大小随修改增量维护，并通过 lru.ResizeNotifier 上报给缓存
+type StockDataMap struct {
+	mu          sync.RWMutex
+	snapshots   map[string][]*model.SnapshotRecord // Key: "snap_indicators_timestamp", Value: list of snapshot records
+	periods     map[string]*PeriodSeries           // Key: "period_indicators", Value: 按时间区间覆盖的区间数据序列
+	lastUpdated time.Time                          // 最近一次写入的时间，用于判断数据是否新鲜
+
+	size       int               // 当前估算的占用字节数
+	resizeHook func(delta int64) // 缓存注册的大小上报回调
+}
+
+// NewStockDataMap 创建空的 StockDataMap
+func NewStockDataMap() *StockDataMap {
+	s := &StockDataMap{
+		snapshots: make(map[string][]*model.SnapshotRecord),
+		periods:   make(map[string]*PeriodSeries),
+	}
+	s.size = s.computeSize()
+	return s
+}
+
+// Len 实现 lru.Value 接口，返回估算的堆占用字节数
+func (s *StockDataMap) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size
+}
+
+// SetResizeHook 实现 lru.ResizeNotifier 接口
+func (s *StockDataMap) SetResizeHook(hook func(delta int64)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resizeHook = hook
+}
+
+// Snapshot 读取快照数据，返回的记录只读
+func (s *StockDataMap) Snapshot(key string) ([]*model.SnapshotRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	records, ok := s.snapshots[key]
+	return records, ok
+}
+
+// Period 读取区间序列，返回副本，调用方可以在副本上 Merge 而不影响缓存中的数据
+func (s *StockDataMap) Period(key string) (*PeriodSeries, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	series, ok := s.periods[key]
+	if !ok {
+		return nil, false
+	}
+	copied := *series
+	return &copied, true
+}
+
+// LastUpdated 返回最近一次写入的时间
+func (s *StockDataMap) LastUpdated() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastUpdated
+}
+
+// SetSnapshot 写入快照数据并上报大小变化，records 写入后不能再被修改
+func (s *StockDataMap) SetSnapshot(key string, records []*model.SnapshotRecord) {
+	s.mu.Lock()
+	delta := sizeOfSnapshotRecords(records)
+	if old, ok := s.snapshots[key]; ok {
+		delta -= sizeOfSnapshotRecords(old)
+	} else {
+		delta += sizeOfMapKey(key)
+	}
+	s.snapshots[key] = records
+	hook := s.touch(delta)
+	s.mu.Unlock()
+
+	s.report(hook, delta)
+}
+
+// SetPeriods 写入区间序列并上报大小变化，series 写入后不能再被修改
+func (s *StockDataMap) SetPeriods(key string, series *PeriodSeries) {
+	s.mu.Lock()
+	delta := series.Len()
+	if old, ok := s.periods[key]; ok {
+		delta -= old.Len()
+	} else {
+		delta += sizeOfMapKey(key)
+	}
+	s.periods[key] = series
+	hook := s.touch(delta)
+	s.mu.Unlock()
+
+	s.report(hook, delta)
+}
+
+// touch 记录更新时间和大小变化，返回需要通知的回调，调用方需持有写锁
+func (s *StockDataMap) touch(delta int) func(delta int64) {
+	s.lastUpdated = time.Now()
+	s.size += delta
+	return s.resizeHook
+}
+
+// report 向缓存上报大小变化
+// 回调会获取缓存锁，而缓存持有锁时会调用 Len/SetResizeHook，因此必须在释放 s.mu 之后调用，避免死锁
+func (s *StockDataMap) report(hook func(delta int64), delta int) {
+	if delta != 0 && hook != nil {
+		hook(int64(delta))
+	}
+}
+
+// computeSize 完整计算占用字节数，用于新建或从快照恢复时初始化
+func (s *StockDataMap) computeSize() int {
+	size := int(unsafe.Sizeof(*s)) + 2*mapOverhead
+	for key, records := range s.snapshots {
+		size += sizeOfMapKey(key) + sizeOfSnapshotRecords(records)
+	}
+	for key, series := range s.periods {
+		size += sizeOfMapKey(key) + series.Len()
+	}
+	return size
+}
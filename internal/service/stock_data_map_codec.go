@@ -0,0 +1,173 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"KamaitachiGo/internal/cache/lru"
+	"KamaitachiGo/internal/model"
+	"KamaitachiGo/pkg/json"
+)
+
+// stockDataMapCodecVersion StockDataMap 快照编码的版本，下面的持久化结构变化时递增
+const stockDataMapCodecVersion = 1
+
+// StockDataMapCodec StockDataMap 的快照编解码器（实现 snapshot.Codec）
+// 模型中不输出到接口的字段（排序用的报告期、区间序列的报告期等）也需要保存，因此使用单独的持久化结构
+type StockDataMapCodec struct{}
+
+// persistedStockDataMap StockDataMap 的持久化结构
+type persistedStockDataMap struct {
+	Snapshots   map[string][]*persistedSnapshotRecord `json:"snapshots"`
+	Periods     map[string]*persistedPeriodSeries     `json:"periods"`
+	LastUpdated int64                                 `json:"last_updated"` // Unix 毫秒
+}
+
+// persistedSnapshotRecord 快照记录的持久化结构
+type persistedSnapshotRecord struct {
+	Subject     *model.SubjectInfo     `json:"subject"`
+	Data        map[string]interface{} `json:"data"`
+	ReportDate  int64                  `json:"report_date"`
+	EndDate     string                 `json:"end_date"`
+	DeclareDate int64                  `json:"declare_date"`
+}
+
+// persistedPeriodSeries 区间序列的持久化结构
+type persistedPeriodSeries struct {
+	Subject  *model.SubjectInfo     `json:"subject"`
+	Items    []*persistedPeriodItem `json:"items"`
+	Coverage [][2]int64             `json:"coverage"`
+}
+
+// persistedPeriodItem 区间数据项的持久化结构
+type persistedPeriodItem struct {
+	EndDate     string                 `json:"end_date"`
+	Period      string                 `json:"period"`
+	DeclareDate string                 `json:"declare_date"`
+	Year        string                 `json:"year"`
+	Combine     string                 `json:"combine"`
+	Values      map[string]interface{} `json:"values"`
+	ReportDate  int64                  `json:"report_date"`
+}
+
+// Type 实现 snapshot.Codec 接口
+func (StockDataMapCodec) Type() string {
+	return "stock_data_map"
+}
+
+// Version 实现 snapshot.Codec 接口
+func (StockDataMapCodec) Version() int {
+	return stockDataMapCodecVersion
+}
+
+// Accepts 实现 snapshot.Codec 接口
+func (StockDataMapCodec) Accepts(value lru.Value) bool {
+	_, ok := value.(*StockDataMap)
+	return ok
+}
+
+// Encode 实现 snapshot.Codec 接口
+func (StockDataMapCodec) Encode(value lru.Value) ([]byte, error) {
+	s, ok := value.(*StockDataMap)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type %T", value)
+	}
+
+	s.mu.RLock()
+	persisted := &persistedStockDataMap{
+		Snapshots:   make(map[string][]*persistedSnapshotRecord, len(s.snapshots)),
+		Periods:     make(map[string]*persistedPeriodSeries, len(s.periods)),
+		LastUpdated: s.lastUpdated.UnixNano() / int64(time.Millisecond),
+	}
+	for key, records := range s.snapshots {
+		items := make([]*persistedSnapshotRecord, len(records))
+		for i, record := range records {
+			items[i] = &persistedSnapshotRecord{
+				Subject:     record.Subject,
+				Data:        record.Data,
+				ReportDate:  record.ReportDate,
+				EndDate:     record.EndDate,
+				DeclareDate: record.DeclareDate,
+			}
+		}
+		persisted.Snapshots[key] = items
+	}
+	for key, series := range s.periods {
+		p := &persistedPeriodSeries{
+			Subject:  series.Subject,
+			Items:    make([]*persistedPeriodItem, len(series.Items)),
+			Coverage: make([][2]int64, len(series.Coverage)),
+		}
+		for i, item := range series.Items {
+			p.Items[i] = &persistedPeriodItem{
+				EndDate:     item.EndDate,
+				Period:      item.Period,
+				DeclareDate: item.DeclareDate,
+				Year:        item.Year,
+				Combine:     item.Combine,
+				Values:      item.Values,
+				ReportDate:  item.ReportDate,
+			}
+		}
+		for i, interval := range series.Coverage {
+			p.Coverage[i] = [2]int64{interval.From, interval.To}
+		}
+		persisted.Periods[key] = p
+	}
+	s.mu.RUnlock()
+
+	// 记录和序列写入后只读，释放锁之后再序列化
+	return json.Marshal(persisted)
+}
+
+// Decode 实现 snapshot.Codec 接口
+func (StockDataMapCodec) Decode(version int, data []byte) (lru.Value, error) {
+	if version != stockDataMapCodecVersion {
+		return nil, fmt.Errorf("unsupported stock data map version %d", version)
+	}
+
+	var persisted persistedStockDataMap
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return nil, err
+	}
+
+	s := NewStockDataMap()
+	for key, items := range persisted.Snapshots {
+		records := make([]*model.SnapshotRecord, len(items))
+		for i, item := range items {
+			records[i] = &model.SnapshotRecord{
+				Subject:     item.Subject,
+				Data:        item.Data,
+				ReportDate:  item.ReportDate,
+				EndDate:     item.EndDate,
+				DeclareDate: item.DeclareDate,
+			}
+		}
+		s.snapshots[key] = records
+	}
+	for key, p := range persisted.Periods {
+		series := &PeriodSeries{
+			Subject:  p.Subject,
+			Items:    make([]*model.PeriodDataItem, len(p.Items)),
+			Coverage: make([]Interval, len(p.Coverage)),
+		}
+		for i, item := range p.Items {
+			series.Items[i] = &model.PeriodDataItem{
+				EndDate:     item.EndDate,
+				Period:      item.Period,
+				DeclareDate: item.DeclareDate,
+				Year:        item.Year,
+				Combine:     item.Combine,
+				Values:      item.Values,
+				ReportDate:  item.ReportDate,
+			}
+		}
+		for i, interval := range p.Coverage {
+			series.Coverage[i] = Interval{From: interval[0], To: interval[1]}
+		}
+		s.periods[key] = series
+	}
+	s.lastUpdated = time.Unix(0, persisted.LastUpdated*int64(time.Millisecond))
+	s.size = s.computeSize()
+	return s, nil
+}
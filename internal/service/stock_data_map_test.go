@@ -0,0 +1,291 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"KamaitachiGo/internal/cache/lru"
+	"KamaitachiGo/internal/model"
+	"KamaitachiGo/internal/repository"
+)
+
+// StockDataMap 与 FinanceService 的并发测试，数据竞争需配合 race detector 发现：
+//
+//	go test -race ./internal/service/
+//
+// TestStockDataMapConcurrentAccess 并发读写同一批 StockDataMap（同时在容量很小的缓存中增删，触发大小上报与淘汰）；
+// TestFinanceServiceConcurrentQueries 对同一个subject并发发起快照和区间查询，并将每个区间结果与直接查询数据库的结果比对。
+
+const (
+	fixtureStartYear = 2018
+	fixtureEndYear   = 2023
+	fixtureSubject   = "33:100000"
+)
+
+// yearRange 返回 [from, to] 年份对应的时间戳区间
+func yearRange(from, to int) (int64, int64) {
+	return time.Date(from, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), time.Date(to, 12, 31, 23, 59, 59, 0, time.UTC).Unix()
+}
+
+// newFixtureDB 在临时目录中创建宽表数据库：每个subject在 fixtureStartYear 至 fixtureEndYear 每年有中报和年报
+func newFixtureDB(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "finance.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE finance_data (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			stock_code TEXT NOT NULL,
+			market_code TEXT NOT NULL,
+			subject_key TEXT NOT NULL,
+			stock_name TEXT,
+			report_date INTEGER NOT NULL,
+			declare_date INTEGER,
+			end_date TEXT,
+			year TEXT,
+			period TEXT,
+			operating_income REAL,
+			total_operating_cost REAL,
+			operating_profit REAL,
+			total_profit REAL,
+			net_profit REAL,
+			parent_holder_net_profit REAL,
+			category TEXT DEFAULT 'stock',
+			topic TEXT DEFAULT 'stock_a_listing_pool'
+		);
+		CREATE INDEX idx_subject_date ON finance_data(subject_key, report_date DESC);
+	`)
+	if err != nil {
+		t.Fatalf("create fixture schema: %v", err)
+	}
+
+	insert := `INSERT INTO finance_data (stock_code, market_code, subject_key, stock_name, report_date, declare_date,
+		end_date, year, period, operating_income, parent_holder_net_profit) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for i := 0; i < 5; i++ {
+		code := fmt.Sprintf("%06d", 100000+i)
+		subject := "33:" + code
+		for year := fixtureStartYear; year <= fixtureEndYear; year++ {
+			for _, reportDate := range []time.Time{
+				time.Date(year, 6, 30, 0, 0, 0, 0, time.UTC),
+				time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC),
+			} {
+				declareDate := reportDate.AddDate(0, 2, 0)
+				_, err := db.Exec(insert, code, "33", subject, "名称"+subject, reportDate.Unix(), declareDate.Unix(),
+					reportDate.Format("2006-01-02"), fmt.Sprintf("%d", year), reportDate.Format("0102"),
+					float64(year*100+int(reportDate.Month())), float64(year+i))
+				if err != nil {
+					t.Fatalf("insert fixture row: %v", err)
+				}
+			}
+		}
+	}
+	return path
+}
+
+// fakePeriodRecord 模拟查询缺口区间的结果：区间内每年一条年报数据
+func fakePeriodRecord(subject string, gap Interval) *model.PeriodRecord {
+	record := &model.PeriodRecord{Subject: &model.SubjectInfo{Subject: subject}}
+	for y := time.Unix(gap.To, 0).UTC().Year(); y >= time.Unix(gap.From, 0).UTC().Year(); y-- {
+		reportDate := time.Date(y, 12, 31, 0, 0, 0, 0, time.UTC).Unix()
+		if reportDate < gap.From || reportDate > gap.To {
+			continue
+		}
+		record.Data = append(record.Data, &model.PeriodDataItem{
+			Year:       fmt.Sprintf("%d", y),
+			EndDate:    fmt.Sprintf("%d-12-31", y),
+			ReportDate: reportDate,
+			Values:     map[string]interface{}{"operating_income": float64(y)},
+		})
+	}
+	return record
+}
+
+func TestStockDataMapConcurrentAccess(t *testing.T) {
+	const workers, ops = 16, 2000
+
+	keys := []string{"33:000001", "33:000002", "33:000003", "33:000004"}
+	// 容量只能容纳少量条目，读写过程中条目不断被淘汰和重新加入
+	cache := lru.NewShardedCache(16*1024, 2, nil)
+	var createMu sync.Mutex
+	getOrCreate := func(key string) *StockDataMap {
+		if value, ok := cache.Get(key); ok {
+			return value.(*StockDataMap)
+		}
+		createMu.Lock()
+		defer createMu.Unlock()
+		if value, ok := cache.Get(key); ok {
+			return value.(*StockDataMap)
+		}
+		m := NewStockDataMap()
+		cache.Add(key, m)
+		return m
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < ops; i++ {
+				key := keys[r.Intn(len(keys))]
+				m := getOrCreate(key)
+				innerKey := fmt.Sprintf("snap_operating_income_%d", r.Intn(4))
+				periodKey := fmt.Sprintf("period_%d", r.Intn(2))
+
+				switch r.Intn(6) {
+				case 0:
+					records := make([]*model.SnapshotRecord, 1+r.Intn(3))
+					for i := range records {
+						records[i] = &model.SnapshotRecord{
+							Subject: &model.SubjectInfo{Subject: key},
+							Data:    map[string]interface{}{"operating_income": r.Float64()},
+						}
+					}
+					m.SetSnapshot(innerKey, records)
+				case 1:
+					if records, ok := m.Snapshot(innerKey); ok {
+						for _, record := range records {
+							if record.Subject.Subject != key {
+								t.Errorf("snapshot of %s contains %s", key, record.Subject.Subject)
+							}
+							_ = record.Data["operating_income"]
+						}
+					}
+				case 2:
+					// 与 FinanceService 相同：在副本上合并缺口后整体写回
+					series, ok := m.Period(periodKey)
+					if !ok {
+						series = &PeriodSeries{}
+					}
+					from := 2015 + r.Intn(8)
+					to := from + r.Intn(4)
+					fromTs, toTs := yearRange(from, to)
+					for _, gap := range series.Gaps(fromTs, toTs) {
+						series.Merge(gap, fakePeriodRecord(key, gap))
+					}
+					m.SetPeriods(periodKey, series)
+				case 3:
+					if series, ok := m.Period(periodKey); ok {
+						fromTs, toTs := yearRange(2015, 2025)
+						if record := series.Slice(fromTs, toTs); record != nil {
+							for i := 1; i < len(record.Data); i++ {
+								if record.Data[i-1].ReportDate <= record.Data[i].ReportDate {
+									t.Errorf("period series of %s is not sorted", key)
+									break
+								}
+							}
+						}
+					}
+				case 4:
+					if m.Len() <= 0 {
+						t.Errorf("size of %s is %d", key, m.Len())
+					}
+					_ = m.LastUpdated()
+				case 5:
+					if r.Intn(10) == 0 {
+						cache.Remove(key)
+					}
+				}
+			}
+		}(int64(w) + 1)
+	}
+	wg.Wait()
+
+	if stats := cache.Stats(); stats.UsedBytes < 0 {
+		t.Errorf("cache used bytes is negative: %d", stats.UsedBytes)
+	}
+}
+
+func TestFinanceServiceConcurrentQueries(t *testing.T) {
+	const workers, queries = 16, 100
+
+	repo, err := repository.NewSQLiteRepository(newFixtureDB(t))
+	if err != nil {
+		t.Fatalf("open repository: %v", err)
+	}
+	defer repo.Close()
+
+	idSets := []string{"operating_income", "parent_holder_net_profit", "operating_income,parent_holder_net_profit"}
+
+	// 每个指标组合、年份区间直接查询数据库得到的期望条数
+	expected := make(map[string]int)
+	for _, ids := range idSets {
+		indicators, err := repo.ResolveIndicators(strings.Split(ids, ","))
+		if err != nil {
+			t.Fatalf("resolve indicators %s: %v", ids, err)
+		}
+		for from := fixtureStartYear - 1; from <= fixtureEndYear+1; from++ {
+			for to := from; to <= fixtureEndYear+1; to++ {
+				fromTs, toTs := yearRange(from, to)
+				records, err := repo.QueryPeriod([]string{fixtureSubject}, indicators, fromTs, toTs)
+				if err != nil {
+					t.Fatalf("query period: %v", err)
+				}
+				count := 0
+				for _, record := range records {
+					count += len(record.Data)
+				}
+				expected[fmt.Sprintf("%s|%d|%d", ids, from, to)] = count
+			}
+		}
+	}
+	// 夹具每年两期，确认比对基准本身不是空结果
+	full := expected[fmt.Sprintf("%s|%d|%d", idSets[0], fixtureStartYear, fixtureEndYear)]
+	if want := 2 * (fixtureEndYear - fixtureStartYear + 1); full != want {
+		t.Fatalf("fixture returned %d period items, want %d", full, want)
+	}
+
+	svc := NewFinanceService(repo, 0, 16, lru.PolicyLRU)
+	defer svc.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < queries; i++ {
+				ids := idSets[r.Intn(len(idSets))]
+				if r.Intn(2) == 0 {
+					resp, err := svc.QuerySnapshot(&model.SnapshotRequest{Subjects: fixtureSubject, IDs: ids, Timestamp: int64(r.Intn(2)) * time.Now().Unix()})
+					if err != nil || resp.StatusCode != 0 {
+						t.Errorf("snapshot %s: %v %+v", ids, err, resp)
+					} else if len(resp.Data) != 1 || resp.Data[0].Subject.Subject != fixtureSubject {
+						t.Errorf("snapshot %s returned %d records", ids, len(resp.Data))
+					}
+					continue
+				}
+
+				from := fixtureStartYear - 1 + r.Intn(fixtureEndYear-fixtureStartYear+3)
+				to := from + r.Intn(fixtureEndYear+2-from)
+				fromTs, toTs := yearRange(from, to)
+				resp, err := svc.QueryPeriod(&model.PeriodRequest{Subjects: fixtureSubject, IDs: ids, From: fromTs, To: toTs})
+				if err != nil || resp.StatusCode != 0 {
+					t.Errorf("period %s [%d, %d]: %v %+v", ids, from, to, err, resp)
+					continue
+				}
+				count := 0
+				for _, record := range resp.Data {
+					count += len(record.Data)
+				}
+				if want := expected[fmt.Sprintf("%s|%d|%d", ids, from, to)]; count != want {
+					t.Errorf("period %s [%d, %d] returned %d items, want %d", ids, from, to, count, want)
+				}
+			}
+		}(int64(w) + 1)
+	}
+	wg.Wait()
+}
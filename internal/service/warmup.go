@@ -0,0 +1,286 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"KamaitachiGo/internal/model"
+	"KamaitachiGo/pkg/json"
+
+	"github.com/sirupsen/logrus"
+)
+
+// warmupBatchSize 预热时单个请求包含的最大subject数
+const warmupBatchSize = 100
+
+// WarmupSpec 预热配置：需要预先查询的快照（含主题池）与区间请求，字段与接口请求相同
+type WarmupSpec struct {
+	Snapshots []*model.SnapshotRequest `json:"snapshots"`
+	Periods   []*model.PeriodRequest   `json:"periods"`
+}
+
+// LoadWarmupSpec 从 JSON 文件读取预热配置
+func LoadWarmupSpec(path string) (*WarmupSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec WarmupSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal warmup spec %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// Merge 追加另一份预热配置中的请求
+func (w *WarmupSpec) Merge(other *WarmupSpec) {
+	if other == nil {
+		return
+	}
+	w.Snapshots = append(w.Snapshots, other.Snapshots...)
+	w.Periods = append(w.Periods, other.Periods...)
+}
+
+// warmupTask 一个预热请求
+type warmupTask struct {
+	snapshot *model.SnapshotRequest
+	period   *model.PeriodRequest
+}
+
+// WarmupProgress 预热进度
+type WarmupProgress struct {
+	mu         sync.Mutex
+	state      string // idle/running/done
+	total      int
+	completed  int
+	failed     int
+	skipped    int // 不属于本节点而跳过的subject（及主题池请求）数
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// start 开始预热
+func (p *WarmupProgress) start(total, skipped int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = "running"
+	p.total = total
+	p.completed = 0
+	p.failed = 0
+	p.skipped = skipped
+	p.startedAt = time.Now()
+	p.finishedAt = time.Time{}
+}
+
+// done 记录一个预热请求完成
+func (p *WarmupProgress) done(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed++
+	if !ok {
+		p.failed++
+	}
+}
+
+// finish 预热结束
+func (p *WarmupProgress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = "done"
+	p.finishedAt = time.Now()
+}
+
+// Done 预热是否已完成
+func (p *WarmupProgress) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state == "done"
+}
+
+// Status 返回预热进度，供健康检查展示
+func (p *WarmupProgress) Status() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.state
+	if state == "" {
+		state = "idle"
+	}
+	percent := 100.0
+	if p.total > 0 {
+		percent = float64(p.completed) / float64(p.total) * 100
+	}
+	status := map[string]interface{}{
+		"state":     state,
+		"total":     p.total,
+		"completed": p.completed,
+		"failed":    p.failed,
+		"skipped":   p.skipped,
+		"percent":   fmt.Sprintf("%.1f%%", percent),
+	}
+	if !p.startedAt.IsZero() {
+		status["started_at"] = p.startedAt.Format("2006-01-02 15:04:05")
+		end := p.finishedAt
+		if end.IsZero() {
+			end = time.Now()
+		}
+		status["elapsed"] = end.Sub(p.startedAt).Round(time.Millisecond).String()
+	}
+	return status
+}
+
+// Warmup 按预热配置查询并缓存数据，owns 判断路由Key（subject，主题池请求为主题名，见 model.RouteKey）
+// 是否由本节点负责，为 nil 时全部预热；workers 为并发请求数
+func (s *FinanceService) Warmup(spec *WarmupSpec, owns func(routeKey string) bool, workers int) error {
+	if spec == nil {
+		spec = &WarmupSpec{}
+	}
+	if owns == nil {
+		owns = func(string) bool { return true }
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// 只保留本节点负责的subject，并按批拆分
+	tasks := make([]warmupTask, 0)
+	skipped := 0
+	for _, req := range spec.Snapshots {
+		if req.Topic != "" {
+			if !owns(model.RouteKey(req.Topic, nil)) {
+				skipped++
+				continue
+			}
+			copied := *req
+			copied.ApplyDefaults()
+			tasks = append(tasks, warmupTask{snapshot: &copied})
+			continue
+		}
+		owned, notOwned := splitOwned(req.Subjects, owns)
+		skipped += notOwned
+		for _, batch := range owned {
+			copied := *req
+			copied.Subjects = batch
+			copied.ApplyDefaults()
+			tasks = append(tasks, warmupTask{snapshot: &copied})
+		}
+	}
+	for _, req := range spec.Periods {
+		owned, notOwned := splitOwned(req.Subjects, owns)
+		skipped += notOwned
+		for _, batch := range owned {
+			copied := *req
+			copied.Subjects = batch
+			tasks = append(tasks, warmupTask{period: &copied})
+		}
+	}
+
+	logrus.Infof("Starting cache warmup: %d queries, %d workers, %d subjects/topics owned by other nodes skipped", len(tasks), workers, skipped)
+	s.warmup.start(len(tasks), skipped)
+
+	taskCh := make(chan warmupTask)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskCh {
+				s.warmup.done(s.runWarmupTask(task))
+			}
+		}()
+	}
+	for _, task := range tasks {
+		taskCh <- task
+	}
+	close(taskCh)
+	wg.Wait()
+	s.warmup.finish()
+
+	status := s.warmup.Status()
+	logrus.Infof("Cache warmup completed: %d queries (%d failed) in %s, %d entries in cache",
+		len(tasks), status["failed"], status["elapsed"], s.cache.Len())
+	if failed := status["failed"].(int); failed > 0 {
+		return fmt.Errorf("%d of %d warmup queries failed", failed, len(tasks))
+	}
+	return nil
+}
+
+// runWarmupTask 执行一个预热请求，返回是否成功
+func (s *FinanceService) runWarmupTask(task warmupTask) bool {
+	if task.snapshot != nil {
+		resp, err := s.QuerySnapshot(task.snapshot)
+		if err == nil && resp.StatusCode != 0 {
+			err = errors.New(resp.StatusMsg)
+		}
+		if err != nil {
+			logrus.Warnf("Warmup snapshot query failed (ids=%s, subjects=%s, topic=%s): %v", task.snapshot.IDs, task.snapshot.Subjects, task.snapshot.Topic, err)
+			return false
+		}
+		return true
+	}
+	resp, err := s.QueryPeriod(task.period)
+	if err == nil && resp.StatusCode != 0 {
+		err = errors.New(resp.StatusMsg)
+	}
+	if err != nil {
+		logrus.Warnf("Warmup period query failed (ids=%s, subjects=%s): %v", task.period.IDs, task.period.Subjects, err)
+		return false
+	}
+	return true
+}
+
+// topicCachePrefix 主题池查询结果的缓存Key前缀，subject 的缓存Key即 subject 本身
+const topicCachePrefix = "topic:"
+
+// CacheRouteKey 缓存Key对应的路由Key，用于节点间交接缓存时判断条目归属：
+// subject 的缓存Key即路由Key，主题池缓存Key取主题名，与网关转发主题池请求的 model.RouteKey 一致
+func CacheRouteKey(key string) string {
+	return strings.TrimPrefix(key, topicCachePrefix)
+}
+
+// splitOwned 过滤出本节点负责的subject并按批拼接，返回批次和跳过的subject数
+func splitOwned(subjects string, owns func(string) bool) ([]string, int) {
+	owned := make([]string, 0)
+	skipped := 0
+	for _, subject := range model.SplitList(subjects) {
+		if owns(subject) {
+			owned = append(owned, subject)
+		} else {
+			skipped++
+		}
+	}
+
+	batches := make([]string, 0, (len(owned)+warmupBatchSize-1)/warmupBatchSize)
+	for start := 0; start < len(owned); start += warmupBatchSize {
+		end := start + warmupBatchSize
+		if end > len(owned) {
+			end = len(owned)
+		}
+		batches = append(batches, strings.Join(owned[start:end], ","))
+	}
+	return batches, skipped
+}
+
+// WarmupStatus 返回预热进度
+func (s *FinanceService) WarmupStatus() map[string]interface{} {
+	return s.warmup.Status()
+}
+
+// WarmupDone 预热是否已完成
+func (s *FinanceService) WarmupDone() bool {
+	return s.warmup.Done()
+}
+
+// EnableAccessLog 开启访问记录，log 可由 LoadAccessLog 从上次运行的记录恢复
+func (s *FinanceService) EnableAccessLog(log *AccessLog) {
+	s.accessLog = log
+}
+
+// AccessLog 返回访问记录，未开启时为 nil（nil 上的记录方法不做任何处理）
+func (s *FinanceService) AccessLog() *AccessLog {
+	return s.accessLog
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/go-ini/ini"
 	"github.com/sirupsen/logrus"
 )
@@ -11,6 +14,9 @@ type Config struct {
 	Cache    CacheConfig    `ini:"cache"`
 	Etcd     EtcdConfig     `ini:"etcd"`
 	Database DatabaseConfig `ini:"database"`
+	Warmup   WarmupConfig   `ini:"warmup"`
+	Gateway  GatewayConfig  `ini:"gateway"`
+	Routing  RoutingConfig  `ini:"routing"`
 }
 
 // ServerConfig 服务器配置
@@ -19,20 +25,145 @@ type ServerConfig struct {
 	Mode        string `ini:"mode"`         // 运行模式: master/slave/gateway
 	ServiceName string `ini:"service_name"` // 服务名称
 	ServiceAddr string `ini:"service_addr"` // 服务地址
+	Weight      int    `ini:"weight"`       // 注册到etcd的相对权重，哈希环上的虚拟节点数按权重倍增，0 使用默认1
+	Zone        string `ini:"zone"`         // 注册到etcd的可用区/机房
+
+	InternalToken string `ini:"internal_token"` // 节点间内部接口（缓存交接）的共享令牌，网关与各节点须一致；为空时内部接口拒绝所有请求
+	DrainTimeout  int    `ini:"drain_timeout"`  // 退出时注销后继续服务、等待网关交接完成的最长时间（秒），0 使用默认30秒，-1 不等待
+}
+
+// DrainWait 返回退出时等待网关交接完成的最长时间，0 表示不等待
+func (s *ServerConfig) DrainWait() time.Duration {
+	switch {
+	case s.DrainTimeout < 0:
+		return 0
+	case s.DrainTimeout == 0:
+		return 30 * time.Second
+	}
+	return time.Duration(s.DrainTimeout) * time.Second
 }
 
 // CacheConfig 缓存配置
 type CacheConfig struct {
-	MaxBytes     int64  `ini:"max_bytes"`     // 最大缓存字节数
-	SnapshotPath string `ini:"snapshot_path"` // 快照文件路径
-	SnapshotInterval int `ini:"snapshot_interval"` // 快照间隔（分钟）
+	MaxBytes            int64  `ini:"max_bytes"`            // 最大缓存字节数
+	SnapshotPath        string `ini:"snapshot_path"`        // 快照文件路径
+	SnapshotInterval    int    `ini:"snapshot_interval"`    // 快照间隔（分钟）
+	SnapshotCompression string `ini:"snapshot_compression"` // 快照数据块压缩算法: none/zstd/lz4，空为none
+	SnapshotRetain      int    `ini:"snapshot_retain"`      // 保留的快照代数，0 使用默认3
+	DefaultTTL          int    `ini:"default_ttl"`          // 条目默认过期时间（秒），0 使用默认1小时，-1 永不过期
+	SlidingExpiration   bool   `ini:"sliding_expiration"`   // 滑动过期：命中时重新计算过期时间
+	JanitorInterval     int    `ini:"janitor_interval"`     // 过期数据清理间隔（秒），0 使用默认60秒
+	Shards              int    `ini:"shards"`               // LRU分片数，0 使用默认16，1 表示不分片
+	EvictionPolicy      string `ini:"eviction_policy"`      // 淘汰策略: lru/lfu/wtinylfu/arc，空为lru
+	ComparePolicies     string `ini:"compare_policies"`     // 需要对比命中率的淘汰策略，逗号分隔，空表示不对比
+}
+
+// TTL 返回条目默认过期时间，0 表示永不过期
+func (c *CacheConfig) TTL() time.Duration {
+	switch {
+	case c.DefaultTTL < 0:
+		return 0
+	case c.DefaultTTL == 0:
+		return time.Hour
+	}
+	return time.Duration(c.DefaultTTL) * time.Second
+}
+
+// ShardCount 返回LRU分片数
+func (c *CacheConfig) ShardCount() int {
+	if c.Shards <= 0 {
+		return 16
+	}
+	return c.Shards
+}
+
+// ComparePolicyList 返回需要对比命中率的淘汰策略列表
+func (c *CacheConfig) ComparePolicyList() []string {
+	policies := make([]string, 0)
+	for _, policy := range strings.Split(c.ComparePolicies, ",") {
+		if policy = strings.TrimSpace(policy); policy != "" {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// JanitorPeriod 返回过期数据清理间隔
+func (c *CacheConfig) JanitorPeriod() time.Duration {
+	if c.JanitorInterval <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.JanitorInterval) * time.Second
+}
+
+// WarmupConfig 缓存预热配置
+type WarmupConfig struct {
+	SpecPath      string `ini:"spec_path"`       // 预热配置文件（JSON：snapshots/periods 请求列表），空表示不使用
+	AccessLogPath string `ini:"access_log_path"` // 访问记录文件，退出时保存访问最多的查询，启动时据此预热，空表示不记录
+	AccessLogTop  int    `ini:"access_log_top"`  // 访问记录保存的条目数，0 使用默认1000
+	Concurrency   int    `ini:"concurrency"`     // 预热并发请求数，0 使用默认4
+}
+
+// Workers 返回预热并发请求数
+func (w *WarmupConfig) Workers() int {
+	if w.Concurrency <= 0 {
+		return 4
+	}
+	return w.Concurrency
+}
+
+// GatewayConfig 网关配置
+type GatewayConfig struct {
+	ProbeInterval      int `ini:"probe_interval"`       // 已加入节点的主动健康探测间隔（秒），0 使用默认5秒
+	ProbeFailures      int `ini:"probe_failures"`       // 连续探测失败多少次后摘除节点，0 使用默认2
+	ConsecutiveErrors  int `ini:"consecutive_errors"`   // 转发请求连续 5xx/超时多少次后摘除节点，0 使用默认5
+	BaseEjectionTime   int `ini:"base_ejection_time"`   // 首次摘除时长（秒），之后按摘除次数倍增，0 使用默认30秒
+	MaxEjectionTime    int `ini:"max_ejection_time"`    // 摘除时长上限（秒），0 使用默认300秒
+	MaxEjectionPercent int `ini:"max_ejection_percent"` // 最多同时摘除的节点百分比，0 使用默认50
+	ReadAttempts       int `ini:"read_attempts"`        // 读请求最多尝试的节点数（主节点+哈希环后继副本），0 使用默认2，1 表示不重试
+	ReadTimeout        int `ini:"read_timeout"`         // 读请求单次尝试的超时（秒），超时后换下一个副本，0 使用默认10秒
+
+	BoundedLoadFactor float64 `ini:"bounded_load_factor"` // 有界负载系数：节点在途请求数超过该倍数的平均值时读请求溢出到后继节点，<=1 表示不启用
+	HandoffTimeout    int     `ini:"handoff_timeout"`     // 成员变化时缓存交接的超时（秒），超时后照常切换路由，0 使用默认30秒，-1 表示不交接
+}
+
+// HandoffWait 返回缓存交接的超时，0 表示不交接
+func (g *GatewayConfig) HandoffWait() time.Duration {
+	switch {
+	case g.HandoffTimeout < 0:
+		return 0
+	case g.HandoffTimeout == 0:
+		return 30 * time.Second
+	}
+	return time.Duration(g.HandoffTimeout) * time.Second
+}
+
+// Attempts 返回读请求最多尝试的节点数
+func (g *GatewayConfig) Attempts() int {
+	if g.ReadAttempts <= 0 {
+		return 2
+	}
+	return g.ReadAttempts
+}
+
+// AttemptTimeout 返回读请求单次尝试的超时
+func (g *GatewayConfig) AttemptTimeout() time.Duration {
+	if g.ReadTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(g.ReadTimeout) * time.Second
+}
+
+// RoutingConfig 路由配置，网关与 Slave（计算预热归属）需保持一致
+type RoutingConfig struct {
+	Strategy string `ini:"strategy"` // 路由策略: ring/rendezvous/maglev，空为ring
 }
 
 // EtcdConfig etcd配置
 type EtcdConfig struct {
-	Endpoints  string `ini:"endpoints"`   // etcd地址列表，逗号分隔
-	Prefix     string `ini:"prefix"`      // 键前缀
-	TTL        int64  `ini:"ttl"`         // 租约TTL（秒）
+	Endpoints string `ini:"endpoints"` // etcd地址列表，逗号分隔
+	Prefix    string `ini:"prefix"`    // 键前缀
+	TTL       int64  `ini:"ttl"`       // 租约TTL（秒）
 }
 
 // DatabaseConfig 数据库配置
@@ -42,20 +173,33 @@ type DatabaseConfig struct {
 	Username string `ini:"username"`
 	Password string `ini:"password"`
 	Database string `ini:"database"`
-	MaxIdle  int    `ini:"max_idle"`  // 最大空闲连接数
-	MaxOpen  int    `ini:"max_open"`  // 最大打开连接数
+	MaxIdle  int    `ini:"max_idle"` // 最大空闲连接数
+	MaxOpen  int    `ini:"max_open"` // 最大打开连接数
+
+	CatalogReloadInterval int `ini:"catalog_reload_interval"` // 重新读取表结构与指标目录的间隔（秒），0 使用默认60秒，-1 不定期读取
+}
+
+// CatalogReloadPeriod 返回重新读取表结构与指标目录的间隔，0 表示不定期读取
+func (d *DatabaseConfig) CatalogReloadPeriod() time.Duration {
+	switch {
+	case d.CatalogReloadInterval < 0:
+		return 0
+	case d.CatalogReloadInterval == 0:
+		return time.Minute
+	}
+	return time.Duration(d.CatalogReloadInterval) * time.Second
 }
 
 // LoadConfig 加载配置文件
 func LoadConfig(filePath string) (*Config, error) {
 	cfg := &Config{}
-	
+
 	err := ini.MapTo(cfg, filePath)
 	if err != nil {
 		logrus.Errorf("Failed to load config file: %v", err)
 		return nil, err
 	}
-	
+
 	logrus.Infof("Config loaded successfully from: %s", filePath)
 	return cfg, nil
 }
@@ -64,4 +208,3 @@ func LoadConfig(filePath string) (*Config, error) {
 func (d *DatabaseConfig) GetDSN() string {
 	return d.Username + ":" + d.Password + "@tcp(" + d.Host + ")/" + d.Database + "?charset=utf8mb4&parseTime=True&loc=Local"
 }
-
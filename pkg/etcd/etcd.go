@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"KamaitachiGo/pkg/json"
+
 	"github.com/sirupsen/logrus"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
@@ -122,55 +124,58 @@ func (c *Client) WatchPrefix(prefix string, callback func(eventType string, key
 	}()
 }
 
-// Register 注册服务
-func (c *Client) Register(serviceName, serviceAddr string, ttl int64) error {
-	// 创建租约
-	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
-	defer cancel()
+// Register 注册服务，权重为1
+func (c *Client) Register(serviceName, serviceAddr string, ttl int64) (*Registration, error) {
+	return c.RegisterNode(serviceName, &NodeInfo{Address: serviceAddr, Weight: 1}, ttl)
+}
 
-	lease, err := c.cli.Grant(ctx, ttl)
+// RegisterNode 以JSON描述信息注册服务节点，网关据此按权重分配哈希环上的虚拟节点。
+// 返回的注册在后台维持租约，租约丢失后自动重新注册；首次注册失败时同时返回错误，注册仍会在后台按退避重试
+func (c *Client) RegisterNode(serviceName string, node *NodeInfo, ttl int64) (*Registration, error) {
+	value, err := json.Marshal(node)
 	if err != nil {
-		return fmt.Errorf("failed to create lease: %w", err)
+		return nil, fmt.Errorf("failed to marshal node descriptor: %w", err)
 	}
 
-	// 注册服务
-	key := fmt.Sprintf("/services/%s/%s", serviceName, serviceAddr)
-	_, err = c.cli.Put(context.Background(), key, serviceAddr, clientv3.WithLease(lease.ID))
-	if err != nil {
-		return fmt.Errorf("failed to register service: %w", err)
-	}
+	key := fmt.Sprintf("/services/%s/%s", serviceName, node.Address)
+	registration := c.newRegistration(key, string(value), ttl)
+	return registration, <-registration.first
+}
 
-	// 保持心跳
-	ch, err := c.cli.KeepAlive(context.Background(), lease.ID)
+// Discover 发现服务，返回节点地址
+func (c *Client) Discover(serviceName string) ([]string, error) {
+	nodes, err := c.DiscoverNodes(serviceName)
 	if err != nil {
-		return fmt.Errorf("failed to keep alive: %w", err)
+		return nil, err
 	}
 
-	// 消费心跳响应
-	go func() {
-		for range ch {
-			// 心跳响应
-		}
-	}()
+	services := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		services = append(services, node.Address)
+	}
 
-	logrus.Infof("[Etcd] Service registered: %s -> %s", key, serviceAddr)
-	return nil
+	return services, nil
 }
 
-// Discover 发现服务
-func (c *Client) Discover(serviceName string) ([]string, error) {
+// DiscoverNodes 发现服务，返回节点描述信息，无法解析的注册值跳过
+func (c *Client) DiscoverNodes(serviceName string) ([]*NodeInfo, error) {
 	prefix := fmt.Sprintf("/services/%s/", serviceName)
 	kvs, err := c.GetWithPrefix(prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	services := make([]string, 0, len(kvs))
-	for _, addr := range kvs {
-		services = append(services, addr)
+	nodes := make([]*NodeInfo, 0, len(kvs))
+	for key, value := range kvs {
+		node, err := ParseNodeInfo(value)
+		if err != nil {
+			logrus.Warnf("[Etcd] Skipping %s: %v", key, err)
+			continue
+		}
+		nodes = append(nodes, node)
 	}
 
-	return services, nil
+	return nodes, nil
 }
 
 // Close 关闭客户端
@@ -0,0 +1,40 @@
+package etcd
+
+import (
+	"fmt"
+	"strings"
+
+	"KamaitachiGo/pkg/json"
+)
+
+// NodeInfo 服务节点注册到etcd的描述信息，以JSON保存在 /services/<服务名>/<地址> 下
+type NodeInfo struct {
+	Address  string `json:"address"`            // 服务地址 host:port
+	Weight   int    `json:"weight"`             // 相对权重，哈希环上的虚拟节点数按权重倍增，<1 按1处理
+	Zone     string `json:"zone,omitempty"`     // 所在可用区/机房
+	Version  string `json:"version,omitempty"`  // 节点程序版本
+	Capacity int64  `json:"capacity,omitempty"` // 缓存容量（字节）
+}
+
+// ParseNodeInfo 解析注册值：JSON描述信息，或旧版本节点注册的纯地址（权重1）
+func ParseNodeInfo(value string) (*NodeInfo, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "{") {
+		if value == "" {
+			return nil, fmt.Errorf("empty node address")
+		}
+		return &NodeInfo{Address: value, Weight: 1}, nil
+	}
+
+	var node NodeInfo
+	if err := json.Unmarshal([]byte(value), &node); err != nil {
+		return nil, fmt.Errorf("invalid node descriptor %q: %w", value, err)
+	}
+	if node.Address == "" {
+		return nil, fmt.Errorf("node descriptor %q has no address", value)
+	}
+	if node.Weight < 1 {
+		node.Weight = 1
+	}
+	return &node, nil
+}
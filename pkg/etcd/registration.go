@@ -0,0 +1,259 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// minRetryBackoff 注册失败或租约丢失后首次重试的等待时间，之后每次翻倍
+	minRetryBackoff = time.Second
+	// maxRetryBackoff 重试等待时间上限
+	maxRetryBackoff = 30 * time.Second
+)
+
+// RegistrationState 服务注册状态
+type RegistrationState int
+
+const (
+	// StateRegistering 正在申请租约并写入注册信息（首次注册或租约丢失后重新注册）
+	StateRegistering RegistrationState = iota
+	// StateRegistered 已注册，租约心跳正常
+	StateRegistered
+	// StateLost 租约丢失（心跳通道关闭或注册失败），等待退避后重新注册
+	StateLost
+	// StateDeregistered 已主动注销，不再重新注册
+	StateDeregistered
+)
+
+// String 返回状态名称
+func (s RegistrationState) String() string {
+	switch s {
+	case StateRegistering:
+		return "registering"
+	case StateRegistered:
+		return "registered"
+	case StateLost:
+		return "lost"
+	case StateDeregistered:
+		return "deregistered"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// Registration 一个服务节点在etcd中的注册：后台维持租约心跳，etcd 短暂不可用或租约过期导致心跳通道关闭时，
+// 按指数退避重新申请租约并写入注册信息，直到 Deregister
+type Registration struct {
+	client *Client
+	key    string
+	value  string
+	ttl    int64
+
+	mu            sync.Mutex
+	state         RegistrationState
+	since         time.Time
+	leaseID       clientv3.LeaseID
+	lastError     string
+	registrations int // 成功注册的次数，大于1表示发生过重新注册
+	callbacks     []func(state RegistrationState, err error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	first  chan error // 首次注册的结果
+	done   chan struct{}
+}
+
+// newRegistration 创建注册并启动后台维持
+func (c *Client) newRegistration(key, value string, ttl int64) *Registration {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Registration{
+		client: c,
+		key:    key,
+		value:  value,
+		ttl:    ttl,
+		state:  StateRegistering,
+		since:  time.Now(),
+		ctx:    ctx,
+		cancel: cancel,
+		first:  make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// OnStateChange 注册状态变化回调，添加时立即以当前状态调用一次；回调在注册的后台协程中执行，不应阻塞
+func (r *Registration) OnStateChange(callback func(state RegistrationState, err error)) {
+	r.mu.Lock()
+	r.callbacks = append(r.callbacks, callback)
+	state := r.state
+	var err error
+	if r.lastError != "" && state != StateRegistered {
+		err = fmt.Errorf("%s", r.lastError)
+	}
+	r.mu.Unlock()
+
+	callback(state, err)
+}
+
+// State 返回当前注册状态
+func (r *Registration) State() RegistrationState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// Status 返回注册状态详情，供健康检查接口展示
+func (r *Registration) Status() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := map[string]interface{}{
+		"key":           r.key,
+		"state":         r.state.String(),
+		"since":         r.since.Format("2006-01-02 15:04:05"),
+		"registrations": r.registrations,
+		"last_error":    r.lastError,
+	}
+	if r.state == StateRegistered {
+		status["lease"] = fmt.Sprintf("%x", int64(r.leaseID))
+	}
+	return status
+}
+
+// Deregister 停止维持并撤销租约，注册信息随租约立即删除，网关无需等待租约过期即可感知节点下线
+func (r *Registration) Deregister() error {
+	r.mu.Lock()
+	if r.state == StateDeregistered {
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+
+	r.cancel()
+	<-r.done
+
+	r.mu.Lock()
+	leaseID := r.leaseID
+	r.leaseID = 0
+	r.mu.Unlock()
+
+	var err error
+	if leaseID != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), r.client.timeout)
+		defer cancel()
+		if _, revokeErr := r.client.cli.Revoke(ctx, leaseID); revokeErr != nil {
+			err = fmt.Errorf("failed to revoke lease for %s: %w", r.key, revokeErr)
+		}
+	}
+	if err != nil {
+		logrus.Warnf("[Etcd] %v, registration will expire with its lease", err)
+	} else {
+		logrus.Infof("[Etcd] Service deregistered: %s", r.key)
+	}
+	r.setState(StateDeregistered, err)
+	return err
+}
+
+// run 注册并维持租约，心跳通道关闭后退避重试，直到 Deregister
+func (r *Registration) run() {
+	defer close(r.done)
+
+	backoff := minRetryBackoff
+	for attempt := 0; ; attempt++ {
+		keepAlive, err := r.register()
+		if attempt == 0 {
+			r.first <- err
+		}
+		if err == nil {
+			backoff = minRetryBackoff
+			r.drain(keepAlive)
+			if r.ctx.Err() != nil {
+				return
+			}
+			err = fmt.Errorf("lease keepalive channel closed (etcd unreachable or lease expired)")
+		}
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		r.mu.Lock()
+		r.leaseID = 0
+		r.mu.Unlock()
+		r.setState(StateLost, err)
+		logrus.Warnf("[Etcd] Registration %s lost: %v, retrying in %v", r.key, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-r.ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+		r.setState(StateRegistering, nil)
+	}
+}
+
+// register 申请租约、写入注册信息并启动心跳
+func (r *Registration) register() (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.client.timeout)
+	defer cancel()
+
+	lease, err := r.client.cli.Grant(ctx, r.ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lease: %w", err)
+	}
+	if _, err := r.client.cli.Put(ctx, r.key, r.value, clientv3.WithLease(lease.ID)); err != nil {
+		return nil, fmt.Errorf("failed to register service: %w", err)
+	}
+	keepAlive, err := r.client.cli.KeepAlive(r.ctx, lease.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to keep alive: %w", err)
+	}
+
+	r.mu.Lock()
+	r.leaseID = lease.ID
+	r.registrations++
+	again := r.registrations > 1
+	r.mu.Unlock()
+
+	if again {
+		logrus.Infof("[Etcd] Service re-registered: %s -> %s", r.key, r.value)
+	} else {
+		logrus.Infof("[Etcd] Service registered: %s -> %s", r.key, r.value)
+	}
+	r.setState(StateRegistered, nil)
+	return keepAlive, nil
+}
+
+// drain 消费心跳响应，直到通道关闭（租约过期、etcd 不可用超过 TTL 或 Deregister）
+func (r *Registration) drain(keepAlive <-chan *clientv3.LeaseKeepAliveResponse) {
+	for range keepAlive {
+	}
+}
+
+// setState 更新状态并通知回调，状态未变化时不通知
+func (r *Registration) setState(state RegistrationState, err error) {
+	r.mu.Lock()
+	if err != nil {
+		r.lastError = err.Error()
+	}
+	if r.state == state {
+		r.mu.Unlock()
+		return
+	}
+	r.state = state
+	r.since = time.Now()
+	callbacks := make([]func(RegistrationState, error), len(r.callbacks))
+	copy(callbacks, r.callbacks)
+	r.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(state, err)
+	}
+}
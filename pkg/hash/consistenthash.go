@@ -7,6 +7,9 @@ import (
 	"sync"
 )
 
+// DefaultReplicas 默认的虚拟节点倍数，网关与各节点计算归属时需使用相同的值
+const DefaultReplicas = 150
+
 // Hash 哈希函数类型
 type Hash func(data []byte) uint32
 
@@ -16,6 +19,7 @@ type ConsistentHash struct {
 	replicas int               // 虚拟节点倍数
 	keys     []int             // 哈希环
 	hashMap  map[int]string    // 虚拟节点到真实节点的映射
+	weights  map[string]int    // 真实节点的权重，虚拟节点数为 replicas*权重
 	mu       sync.RWMutex
 }
 
@@ -25,6 +29,7 @@ func NewConsistentHash(replicas int, fn Hash) *ConsistentHash {
 		replicas: replicas,
 		hash:     fn,
 		hashMap:  make(map[int]string),
+		weights:  make(map[string]int),
 	}
 	if ch.hash == nil {
 		ch.hash = crc32.ChecksumIEEE
@@ -32,35 +37,82 @@ func NewConsistentHash(replicas int, fn Hash) *ConsistentHash {
 	return ch
 }
 
-// Add 添加节点
+// Add 添加节点，权重为1，已存在的节点保持不变
 func (ch *ConsistentHash) Add(keys ...string) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
-	
+
 	for _, key := range keys {
-		// 为每个真实节点创建多个虚拟节点
-		for i := 0; i < ch.replicas; i++ {
-			hash := int(ch.hash([]byte(strconv.Itoa(i) + key)))
-			ch.keys = append(ch.keys, hash)
-			ch.hashMap[hash] = key
+		if _, ok := ch.weights[key]; !ok {
+			ch.add(key, 1)
 		}
 	}
 	sort.Ints(ch.keys)
 }
 
+// AddWeighted 按权重添加节点，虚拟节点数为 replicas*weight（weight<1 按1处理）；
+// 节点已存在时更新其权重，权重1的虚拟节点与 Add 相同，权重变化时只有增减的虚拟节点上的Key迁移
+func (ch *ConsistentHash) AddWeighted(key string, weight int) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if _, ok := ch.weights[key]; ok {
+		ch.remove(key)
+	}
+	ch.add(key, weight)
+	sort.Ints(ch.keys)
+}
+
+// add 为真实节点创建虚拟节点，调用方需持有写锁并在之后排序
+func (ch *ConsistentHash) add(key string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	ch.weights[key] = weight
+	for i := 0; i < ch.replicas*weight; i++ {
+		hash := int(ch.hash([]byte(strconv.Itoa(i) + key)))
+		ch.keys = append(ch.keys, hash)
+		ch.hashMap[hash] = key
+	}
+}
+
 // Remove 移除节点
 func (ch *ConsistentHash) Remove(key string) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
-	
-	for i := 0; i < ch.replicas; i++ {
+
+	ch.remove(key)
+}
+
+// remove 移除真实节点的所有虚拟节点，调用方需持有写锁
+// 先删除映射再一次遍历过滤哈希环，避免逐个虚拟节点做切片删除
+func (ch *ConsistentHash) remove(key string) {
+	weight, ok := ch.weights[key]
+	if !ok {
+		return
+	}
+	delete(ch.weights, key)
+	for i := 0; i < ch.replicas*weight; i++ {
 		hash := int(ch.hash([]byte(strconv.Itoa(i) + key)))
-		idx := sort.SearchInts(ch.keys, hash)
-		if idx < len(ch.keys) && ch.keys[idx] == hash {
-			ch.keys = append(ch.keys[:idx], ch.keys[idx+1:]...)
+		if ch.hashMap[hash] == key {
+			delete(ch.hashMap, hash)
 		}
-		delete(ch.hashMap, hash)
 	}
+
+	keys := ch.keys[:0]
+	for _, hash := range ch.keys {
+		if _, ok := ch.hashMap[hash]; ok {
+			keys = append(keys, hash)
+		}
+	}
+	ch.keys = keys
+}
+
+// Weight 返回节点的权重，节点不在环中时返回0
+func (ch *ConsistentHash) Weight(key string) int {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.weights[key]
 }
 
 // Get 获取key对应的节点
@@ -82,6 +134,34 @@ func (ch *ConsistentHash) Get(key string) string {
 	return ch.hashMap[ch.keys[idx%len(ch.keys)]]
 }
 
+// GetN 获取key对应的最多n个不同节点：第一个与 Get 相同，其余为沿哈希环顺时针的后继节点，
+// 主节点不可用时可依次作为副本重试
+func (ch *ConsistentHash) GetN(key string, n int) []string {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	if len(ch.keys) == 0 || n <= 0 {
+		return nil
+	}
+
+	hash := int(ch.hash([]byte(key)))
+	idx := sort.Search(len(ch.keys), func(i int) bool {
+		return ch.keys[i] >= hash
+	})
+
+	n = min(n, len(ch.weights))
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for i := 0; i < len(ch.keys) && len(nodes) < n; i++ {
+		node := ch.hashMap[ch.keys[(idx+i)%len(ch.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
 // GetNodes 获取所有节点
 func (ch *ConsistentHash) GetNodes() []string {
 	ch.mu.RLock()
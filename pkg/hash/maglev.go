@@ -0,0 +1,154 @@
+package hash
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultMaglevTableSize Maglev 查找表默认大小，需为质数且远大于节点数
+const DefaultMaglevTableSize = 65537
+
+// Maglev Google Maglev 一致性哈希：每个节点按自身的偏移与步长生成表槽位的排列，轮流填充固定大小的查找表，
+// Get 只需一次查表。分布接近完全均匀，增删节点时迁移的Key略多于哈希环；每次成员变化重建查找表
+type Maglev struct {
+	mu      sync.RWMutex
+	size    uint64
+	weights map[string]int
+	nodes   []string // 有序节点列表，保证不同进程建出相同的查找表
+	table   []int    // 槽位到 nodes 下标
+}
+
+// NewMaglev 创建Maglev路由，size 为查找表大小（应为质数），<=0 时使用默认值
+func NewMaglev(size int) *Maglev {
+	if size <= 1 {
+		size = DefaultMaglevTableSize
+	}
+	return &Maglev{
+		size:    uint64(size),
+		weights: make(map[string]int),
+	}
+}
+
+// Add 添加节点，权重为1，已存在的节点保持不变
+func (m *Maglev) Add(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := m.weights[key]; !ok {
+			m.weights[key] = 1
+		}
+	}
+	m.rebuild()
+}
+
+// AddWeighted 按权重添加节点（weight<1 按1处理），节点已存在时更新其权重
+func (m *Maglev) AddWeighted(key string, weight int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.weights[key] = max(weight, 1)
+	m.rebuild()
+}
+
+// Remove 移除节点
+func (m *Maglev) Remove(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.weights, key)
+	m.rebuild()
+}
+
+// rebuild 重建查找表：每轮每个节点按权重依次取其排列中下一个空槽位，直到填满，调用方需持有写锁
+func (m *Maglev) rebuild() {
+	m.nodes = m.nodes[:0]
+	for node := range m.weights {
+		m.nodes = append(m.nodes, node)
+	}
+	sort.Strings(m.nodes)
+	if len(m.nodes) == 0 {
+		m.table = nil
+		return
+	}
+
+	offsets := make([]uint64, len(m.nodes))
+	skips := make([]uint64, len(m.nodes))
+	next := make([]uint64, len(m.nodes))
+	for i, node := range m.nodes {
+		offsets[i] = hash64("offset\x00"+node) % m.size
+		skips[i] = hash64("skip\x00"+node)%(m.size-1) + 1
+	}
+
+	table := make([]int, m.size)
+	for i := range table {
+		table[i] = -1
+	}
+	filled := uint64(0)
+	for filled < m.size {
+		for i, node := range m.nodes {
+			for w := 0; w < m.weights[node] && filled < m.size; w++ {
+				slot := (offsets[i] + next[i]*skips[i]) % m.size
+				for table[slot] >= 0 {
+					next[i]++
+					slot = (offsets[i] + next[i]*skips[i]) % m.size
+				}
+				table[slot] = i
+				next[i]++
+				filled++
+			}
+		}
+	}
+	m.table = table
+}
+
+// Weight 返回节点的权重，节点不存在时返回0
+func (m *Maglev) Weight(key string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.weights[key]
+}
+
+// Get 获取Key对应的节点
+func (m *Maglev) Get(key string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.table) == 0 {
+		return ""
+	}
+	return m.nodes[m.table[hash64(key)%m.size]]
+}
+
+// GetN 获取Key对应的最多n个不同节点：第一个为查表结果，其余为从该槽位起依次遇到的其他节点
+func (m *Maglev) GetN(key string, n int) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.table) == 0 || n <= 0 {
+		return nil
+	}
+
+	n = min(n, len(m.nodes))
+	result := make([]string, 0, n)
+	seen := make(map[int]bool, n)
+	start := hash64(key) % m.size
+	for i := uint64(0); i < m.size && len(result) < n; i++ {
+		idx := m.table[(start+i)%m.size]
+		if !seen[idx] {
+			seen[idx] = true
+			result = append(result, m.nodes[idx])
+		}
+	}
+	return result
+}
+
+// GetNodes 获取所有节点
+func (m *Maglev) GetNodes() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]string, len(m.nodes))
+	copy(result, m.nodes)
+	return result
+}
@@ -0,0 +1,119 @@
+package hash
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Rendezvous 最高随机权重哈希（HRW）：每个Key对所有节点计算得分，得分最高的节点负责该Key。
+// 增删节点时只有该节点负责的Key迁移，且不需要虚拟节点；Get 的开销与节点数成正比，适合节点数不多的集群
+type Rendezvous struct {
+	mu      sync.RWMutex
+	weights map[string]int
+	nodes   []string // 有序节点列表，保证遍历顺序确定
+}
+
+// NewRendezvous 创建HRW路由
+func NewRendezvous() *Rendezvous {
+	return &Rendezvous{weights: make(map[string]int)}
+}
+
+// Add 添加节点，权重为1，已存在的节点保持不变
+func (r *Rendezvous) Add(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := r.weights[key]; !ok {
+			r.weights[key] = 1
+		}
+	}
+	r.sortNodes()
+}
+
+// AddWeighted 按权重添加节点（weight<1 按1处理），节点已存在时更新其权重
+func (r *Rendezvous) AddWeighted(key string, weight int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.weights[key] = max(weight, 1)
+	r.sortNodes()
+}
+
+// Remove 移除节点
+func (r *Rendezvous) Remove(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.weights, key)
+	r.sortNodes()
+}
+
+// sortNodes 重建有序节点列表，调用方需持有写锁
+func (r *Rendezvous) sortNodes() {
+	r.nodes = r.nodes[:0]
+	for node := range r.weights {
+		r.nodes = append(r.nodes, node)
+	}
+	sort.Strings(r.nodes)
+}
+
+// Weight 返回节点的权重，节点不存在时返回0
+func (r *Rendezvous) Weight(key string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.weights[key]
+}
+
+// score 加权得分 -w/ln(u)，u 为 (0,1) 上由节点与Key决定的均匀分布值，
+// 各节点得分最高的概率与其权重成正比
+func (r *Rendezvous) score(node, key string) float64 {
+	u := (float64(hash64(node+"\x00"+key)>>11) + 0.5) / (1 << 53)
+	return -float64(r.weights[node]) / math.Log(u)
+}
+
+// Get 获取Key对应的节点
+func (r *Rendezvous) Get(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	best, bestScore := "", math.Inf(-1)
+	for _, node := range r.nodes {
+		if s := r.score(node, key); s > bestScore {
+			best, bestScore = node, s
+		}
+	}
+	return best
+}
+
+// GetN 获取Key对应的最多n个不同节点，按得分从高到低
+func (r *Rendezvous) GetN(key string, n int) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.nodes) == 0 || n <= 0 {
+		return nil
+	}
+
+	scores := make(map[string]float64, len(r.nodes))
+	nodes := make([]string, len(r.nodes))
+	copy(nodes, r.nodes)
+	for _, node := range nodes {
+		scores[node] = r.score(node, key)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return scores[nodes[i]] > scores[nodes[j]]
+	})
+	return nodes[:min(n, len(nodes))]
+}
+
+// GetNodes 获取所有节点
+func (r *Rendezvous) GetNodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]string, len(r.nodes))
+	copy(result, r.nodes)
+	return result
+}
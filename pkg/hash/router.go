@@ -0,0 +1,65 @@
+package hash
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// 路由策略名称，网关与各节点（计算预热归属）需使用相同的策略
+const (
+	StrategyRing       = "ring"       // 一致性哈希环（crc32 + 虚拟节点），默认
+	StrategyRendezvous = "rendezvous" // 最高随机权重哈希（HRW）
+	StrategyMaglev     = "maglev"     // Maglev 查找表
+)
+
+// Router 路由策略：将Key映射到节点，节点可带权重
+type Router interface {
+	// Add 添加节点，权重为1，已存在的节点保持不变
+	Add(keys ...string)
+	// AddWeighted 按权重添加节点，节点已存在时更新其权重
+	AddWeighted(key string, weight int)
+	// Remove 移除节点
+	Remove(key string)
+	// Weight 返回节点的权重，节点不存在时返回0
+	Weight(key string) int
+	// Get 获取Key对应的节点，没有节点时返回空字符串
+	Get(key string) string
+	// GetN 获取Key对应的最多n个不同节点，第一个与 Get 相同，其余依次作为副本
+	GetN(key string, n int) []string
+	// GetNodes 获取所有节点
+	GetNodes() []string
+}
+
+var (
+	_ Router = (*ConsistentHash)(nil)
+	_ Router = (*Rendezvous)(nil)
+	_ Router = (*Maglev)(nil)
+)
+
+// NewRouter 按策略名称创建路由，空名称使用一致性哈希环；replicas 为哈希环每单位权重的虚拟节点数
+func NewRouter(strategy string, replicas int) (Router, error) {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "", StrategyRing:
+		return NewConsistentHash(replicas, nil), nil
+	case StrategyRendezvous:
+		return NewRendezvous(), nil
+	case StrategyMaglev:
+		return NewMaglev(DefaultMaglevTableSize), nil
+	default:
+		return nil, fmt.Errorf("unknown routing strategy %q (supported: %s, %s, %s)", strategy, StrategyRing, StrategyRendezvous, StrategyMaglev)
+	}
+}
+
+// hash64 64位哈希：FNV-1a 后再做 splitmix64 混合，改善相似字符串（如 "33:000001"）的分布
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
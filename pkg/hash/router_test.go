@@ -0,0 +1,85 @@
+package hash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// 各路由策略 GetN 的测试：返回 min(n, 节点数) 个互不相同的节点，第一个与 Get 相同，权重不影响返回的节点数。
+
+func TestRouterGetNDistinct(t *testing.T) {
+	weights := map[string]int{"node-a": 1, "node-b": 2, "node-c": 3, "node-d": 1, "node-e": 5}
+
+	tests := []struct {
+		name   string
+		remove []string
+		ns     []int
+	}{
+		{name: "weighted nodes", ns: []int{1, 2, 3, 5, 6, 100, math.MaxInt}},
+		{name: "after removing nodes", remove: []string{"node-c", "node-e"}, ns: []int{1, 2, 3, 4, math.MaxInt}},
+		{name: "single node left", remove: []string{"node-a", "node-b", "node-c", "node-d"}, ns: []int{1, 2, math.MaxInt}},
+	}
+
+	for _, strategy := range []string{StrategyRing, StrategyRendezvous, StrategyMaglev} {
+		for _, tt := range tests {
+			t.Run(strategy+"/"+tt.name, func(t *testing.T) {
+				router, err := NewRouter(strategy, DefaultReplicas)
+				if err != nil {
+					t.Fatalf("create router: %v", err)
+				}
+				for node, weight := range weights {
+					router.AddWeighted(node, weight)
+				}
+				removed := make(map[string]bool)
+				for _, node := range tt.remove {
+					router.Remove(node)
+					removed[node] = true
+				}
+				total := len(weights) - len(tt.remove)
+
+				for i := 0; i < 200; i++ {
+					key := fmt.Sprintf("33:%06d", i)
+					primary := router.Get(key)
+					for _, n := range tt.ns {
+						nodes := router.GetN(key, n)
+						if want := min(n, total); len(nodes) != want {
+							t.Fatalf("GetN(%s, %d) returned %d nodes, want %d", key, n, len(nodes), want)
+						}
+						if nodes[0] != primary {
+							t.Fatalf("GetN(%s, %d)[0] = %s, Get = %s", key, n, nodes[0], primary)
+						}
+						seen := make(map[string]bool, len(nodes))
+						for _, node := range nodes {
+							if seen[node] {
+								t.Fatalf("GetN(%s, %d) = %v repeats %s", key, n, nodes, node)
+							}
+							if removed[node] || weights[node] == 0 {
+								t.Fatalf("GetN(%s, %d) = %v returned unknown node %s", key, n, nodes, node)
+							}
+							seen[node] = true
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRouterGetNEmpty(t *testing.T) {
+	for _, strategy := range []string{StrategyRing, StrategyRendezvous, StrategyMaglev} {
+		t.Run(strategy, func(t *testing.T) {
+			router, err := NewRouter(strategy, DefaultReplicas)
+			if err != nil {
+				t.Fatalf("create router: %v", err)
+			}
+			if nodes := router.GetN("33:000001", 3); len(nodes) != 0 {
+				t.Fatalf("empty router returned %v", nodes)
+			}
+			router.Add("node-a")
+			if nodes := router.GetN("33:000001", 0); len(nodes) != 0 {
+				t.Fatalf("GetN with n=0 returned %v", nodes)
+			}
+		})
+	}
+}
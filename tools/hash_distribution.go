@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"KamaitachiGo/pkg/hash"
+)
+
+// 路由策略分布分析：对比 ring/rendezvous/maglev 的负载均匀程度、查询开销，
+// 以及增删节点、调整权重时迁移的Key比例（理想值为变化节点应得的份额）
+func main() {
+	keys := flag.Int("keys", 200000, "Number of subject keys")
+	nodeCount := flag.Int("nodes", 5, "Number of nodes")
+	weightList := flag.String("weights", "", "Comma separated node weights, e.g. 1,1,2 (default all 1)")
+	strategyList := flag.String("strategies", "ring,rendezvous,maglev", "Comma separated routing strategies to compare")
+	replicas := flag.Int("replicas", hash.DefaultReplicas, "Virtual nodes per unit weight for the ring strategy")
+	flag.Parse()
+
+	nodes := make([]string, *nodeCount)
+	weights := make([]int, *nodeCount)
+	parsed := strings.Split(*weightList, ",")
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("10.0.0.%d:8081", i+1)
+		weights[i] = 1
+		if *weightList != "" && i < len(parsed) {
+			w, err := strconv.Atoi(strings.TrimSpace(parsed[i]))
+			if err != nil || w < 1 {
+				fmt.Printf("invalid weight %q\n", parsed[i])
+				return
+			}
+			weights[i] = w
+		}
+	}
+	keyList := make([]string, *keys)
+	for i := range keyList {
+		keyList[i] = fmt.Sprintf("33:%06d", i)
+	}
+
+	totalWeight := 0
+	for _, w := range weights {
+		totalWeight += w
+	}
+	fmt.Printf("keys=%d nodes=%d weights=%v\n\n", *keys, *nodeCount, weights)
+	fmt.Printf("%-12s %10s %10s %10s %10s %10s %12s %12s %12s\n",
+		"strategy", "build", "ns/op", "min/ideal", "max/ideal", "stddev", "remove 1", "add 1", "weight x2")
+
+	for _, s := range strings.Split(*strategyList, ",") {
+		strategy := strings.TrimSpace(s)
+		build := func() hash.Router {
+			router, err := hash.NewRouter(strategy, *replicas)
+			if err != nil {
+				panic(err)
+			}
+			for i, node := range nodes {
+				router.AddWeighted(node, weights[i])
+			}
+			return router
+		}
+		if _, err := hash.NewRouter(strategy, *replicas); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		start := time.Now()
+		router := build()
+		buildTime := time.Since(start)
+
+		start = time.Now()
+		owners := make([]string, len(keyList))
+		for i, key := range keyList {
+			owners[i] = router.Get(key)
+		}
+		nsPerOp := float64(time.Since(start).Nanoseconds()) / float64(len(keyList))
+
+		// 各节点实际份额与按权重的理想份额之比
+		counts := make(map[string]int)
+		for _, owner := range owners {
+			counts[owner]++
+		}
+		minRatio, maxRatio, sumSq := math.Inf(1), 0.0, 0.0
+		for i, node := range nodes {
+			ratio := float64(counts[node]) / (float64(len(keyList)) * float64(weights[i]) / float64(totalWeight))
+			minRatio = math.Min(minRatio, ratio)
+			maxRatio = math.Max(maxRatio, ratio)
+			sumSq += (ratio - 1) * (ratio - 1)
+		}
+		stddev := math.Sqrt(sumSq / float64(len(nodes)))
+
+		// 移除最后一个节点、新增一个节点、第一个节点权重翻倍后迁移的Key比例
+		removed := build()
+		removed.Remove(nodes[len(nodes)-1])
+		added := build()
+		added.Add("10.0.0.254:8081")
+		reweighted := build()
+		reweighted.AddWeighted(nodes[0], weights[0]*2)
+
+		fmt.Printf("%-12s %10s %10.1f %10.3f %10.3f %10.3f %11.2f%% %11.2f%% %11.2f%%\n",
+			strategy, buildTime.Round(time.Microsecond), nsPerOp, minRatio, maxRatio, stddev,
+			remap(keyList, owners, removed), remap(keyList, owners, added), remap(keyList, owners, reweighted))
+	}
+
+	fmt.Println()
+	fmt.Printf("ideal remap: remove 1 = %.2f%%, add 1 = %.2f%%, weight x2 = %.2f%%\n",
+		float64(weights[len(weights)-1])/float64(totalWeight)*100,
+		100.0/float64(totalWeight+1),
+		(float64(2*weights[0])/float64(totalWeight+weights[0])-float64(weights[0])/float64(totalWeight))*100)
+}
+
+// remap 成员变化后归属改变的Key比例（百分比）
+func remap(keys, owners []string, router hash.Router) float64 {
+	moved := 0
+	for i, key := range keys {
+		if router.Get(key) != owners[i] {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(keys)) * 100
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	"KamaitachiGo/internal/cache/lru"
+)
+
+// benchValue 固定大小的缓存值
+type benchValue struct {
+	size int
+}
+
+func (v *benchValue) Len() int {
+	return v.size
+}
+
+type cacheFactory struct {
+	name  string
+	build func(maxBytes int64) lru.Store
+}
+
+type workload struct {
+	name      string
+	readRatio float64 // 读操作占比（未命中时回填，同 FinanceService 的用法），其余为覆盖写
+}
+
+func main() {
+	keys := flag.Int("keys", 100000, "Number of distinct keys")
+	valueSize := flag.Int("value", 1024, "Value size in bytes")
+	shardList := flag.String("shards", "8,16,64", "Comma separated shard counts to compare")
+	policyList := flag.String("policies", "lru", "Comma separated eviction policies to compare: lru, lfu, wtinylfu, arc")
+	procs := flag.Int("procs", runtime.GOMAXPROCS(0), "GOMAXPROCS for parallel benchmarks")
+	flag.Parse()
+
+	runtime.GOMAXPROCS(*procs)
+
+	keyList := make([]string, *keys)
+	for i := range keyList {
+		keyList[i] = fmt.Sprintf("33:%08d", i)
+	}
+	// 容量为全部数据的一半，读写过程中持续发生淘汰
+	maxBytes := int64(*keys) * int64(*valueSize+len(keyList[0])+200) / 2
+
+	factories := make([]cacheFactory, 0)
+	for _, p := range strings.Split(*policyList, ",") {
+		policy := strings.TrimSpace(p)
+		if _, err := lru.NewPolicy(policy, 0); err != nil {
+			fmt.Println(err)
+			return
+		}
+		factories = append(factories, cacheFactory{
+			name: fmt.Sprintf("Cache(%s)", policy),
+			build: func(maxBytes int64) lru.Store {
+				store, _ := lru.New(maxBytes, 1, policy, nil)
+				return store
+			},
+		})
+		for _, s := range strings.Split(*shardList, ",") {
+			var n int
+			if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d", &n); err != nil || n <= 1 {
+				continue
+			}
+			shards := n
+			factories = append(factories, cacheFactory{
+				name: fmt.Sprintf("Sharded(%s,%d)", policy, shards),
+				build: func(maxBytes int64) lru.Store {
+					store, _ := lru.New(maxBytes, shards, policy, nil)
+					return store
+				},
+			})
+		}
+	}
+
+	workloads := []workload{
+		{name: "read-100%", readRatio: 1.0},
+		{name: "read-90%", readRatio: 0.9},
+		{name: "read-50%", readRatio: 0.5},
+	}
+
+	fmt.Printf("keys=%d value=%dB maxBytes=%.1fMB GOMAXPROCS=%d\n\n", *keys, *valueSize, float64(maxBytes)/(1024*1024), *procs)
+	fmt.Printf("%-24s %-10s %14s %14s %10s\n", "cache", "workload", "ns/op", "ops/s", "hit rate")
+	for _, w := range workloads {
+		for _, f := range factories {
+			cache := f.build(maxBytes)
+			for _, key := range keyList {
+				cache.Add(key, &benchValue{size: *valueSize})
+			}
+
+			result := testing.Benchmark(func(b *testing.B) {
+				b.RunParallel(func(pb *testing.PB) {
+					r := rand.New(rand.NewSource(rand.Int63()))
+					for pb.Next() {
+						// 热点访问：80%的请求落在20%的key上
+						var key string
+						if r.Float64() < 0.8 {
+							key = keyList[r.Intn(len(keyList)/5)]
+						} else {
+							key = keyList[r.Intn(len(keyList))]
+						}
+						if r.Float64() < w.readRatio {
+							if _, ok := cache.Get(key); !ok {
+								cache.Add(key, &benchValue{size: *valueSize})
+							}
+						} else {
+							cache.Add(key, &benchValue{size: *valueSize})
+						}
+					}
+				})
+			})
+
+			hitRate := cache.Stats().HitRate()
+			nsPerOp := float64(result.T.Nanoseconds()) / float64(result.N)
+			fmt.Printf("%-24s %-10s %14.1f %14.0f %9.2f%%\n", f.name, w.name, nsPerOp, 1e9/nsPerOp, hitRate)
+		}
+		fmt.Println()
+	}
+}